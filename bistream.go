@@ -1,26 +1,48 @@
 package bitstream
 
 import (
+	"context"
 	"encoding/binary"
 	"io"
+	"log/slog"
 	"math"
+	"slices"
 )
 
 type BIStream struct {
-	endian binary.ByteOrder
-	reader io.Reader
-	err    error
+	endian  binary.ByteOrder
+	src     io.Reader
+	reader  io.Reader
+	err     error
+	ctx     context.Context
+	regions []region
+	// pushback is the reader beneath the regions, holding the bytes pushed back by unread
+	pushback *pushback
+	trace    *Trace
+	stats    *Stats
+	logger   *slog.Logger
+	// interceptors are the ones added by WithInterceptor, chain all of them with the built-in ones
+	interceptors []Interceptor
+	chain        []Interceptor
+	label        string
+	depth        int
+	nread        int64
+	// decompressLimit is the limit set by WithDecompressLimit
+	decompressLimit int64
 }
 
 func NewBIStream(endian binary.ByteOrder, reader io.Reader) *BIStream {
+	pushback := &pushback{reader: reader}
 	return &BIStream{
-		endian: endian,
-		reader: reader,
+		endian:   endian,
+		src:      reader,
+		reader:   pushback,
+		pushback: pushback,
 	}
 }
 
 func (b *BIStream) catchError(f func()) *BIStream {
-	if nil == b.err {
+	if nil == b.err && nil == b.checkContext() {
 		f()
 	}
 	return b
@@ -30,10 +52,20 @@ func (b *BIStream) Error() error {
 	return b.err
 }
 
+// Endian returns the byte order of the stream.
+func (b *BIStream) Endian() binary.ByteOrder {
+	return b.endian
+}
+
 // ReadBool read 1 byte in io.Reader. Returns a bool and an error if exists
 func (b *BIStream) ReadBool() (bool, error) {
-	buf, err := b.ReadBytes(1)
-	return buf[0] == 1, err
+	return interceptRead(b, "bool", func() (bool, error) {
+		buf, err := b.ReadBytes(1)
+		if err != nil {
+			return false, err
+		}
+		return buf[0] == 1, nil
+	})
 }
 
 // FetchBool fetch 1 byte in io.Reader.
@@ -45,8 +77,13 @@ func (b *BIStream) FetchBool(value *bool) *BIStream {
 
 // ReadByte read 1 byte in io.Reader. Returns a byte and an error if exists
 func (b *BIStream) ReadByte() (byte, error) {
-	buf, err := b.ReadBytes(1)
-	return buf[0], err
+	return interceptRead(b, "uint8", func() (byte, error) {
+		buf, err := b.ReadBytes(1)
+		if err != nil {
+			return 0, err
+		}
+		return buf[0], nil
+	})
 }
 
 // FetchByte fetch 1 byte in io.Reader.
@@ -56,11 +93,40 @@ func (b *BIStream) FetchByte(value *byte) *BIStream {
 	})
 }
 
-// ReadBytes read n bytes in io.Reader. Returns a byte array and an error if exists
+// maxPrealloc is the largest buffer ReadBytes allocates before the bytes arrive, so that a corrupt length does
+// not exhaust the memory.
+const maxPrealloc = 1 << 16
+
+// ReadBytes read n bytes in io.Reader. Returns a byte array and an error if exists. On a short read, the
+// array holds the bytes read before the error, fewer than n.
 func (b *BIStream) ReadBytes(n uint64) ([]byte, error) {
-	buf := make([]byte, n)
-	_, err := io.ReadFull(b.reader, buf)
-	return buf, err
+	return interceptRead(b, "bytes", func() ([]byte, error) {
+		if n > maxPrealloc {
+			return b.readGrowing(n)
+		}
+		buf := make([]byte, n)
+		read, err := b.readFull(buf)
+		return buf[:read], err
+	})
+}
+
+// readGrowing read n bytes in chunks as large as the bytes already read. Returns the bytes read and an error if
+// exists
+func (b *BIStream) readGrowing(n uint64) ([]byte, error) {
+	var buf []byte
+	for uint64(len(buf)) < n {
+		start := len(buf)
+		chunk := int(min(n-uint64(start), uint64(max(start, maxPrealloc))))
+		buf = slices.Grow(buf, chunk)[:start+chunk]
+		read, err := b.readFull(buf[start:])
+		if err != nil {
+			if err == io.EOF && start > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return buf[:start+read], err
+		}
+	}
+	return buf, nil
 }
 
 // FetchBytes fetch n byte in io.Reader.
@@ -70,6 +136,30 @@ func (b *BIStream) FetchBytes(value *[]byte, n uint64) *BIStream {
 	})
 }
 
+// ReadAll read the rest of io.Reader. Returns a byte array and an error if exists
+func (b *BIStream) ReadAll() ([]byte, error) {
+	return interceptRead(b, "bytes", func() ([]byte, error) {
+		var buf []byte
+		err := b.readContext(func() error {
+			var err error
+			buf, err = io.ReadAll(b.reader)
+			return err
+		})
+		b.nread += int64(len(buf))
+		if b.trace != nil {
+			b.trace.read(buf)
+		}
+		return buf, err
+	})
+}
+
+// FetchAll fetch the rest of io.Reader.
+func (b *BIStream) FetchAll(value *[]byte) *BIStream {
+	return b.catchError(func() {
+		*value, b.err = b.ReadAll()
+	})
+}
+
 // ReadUint8 read 1 byte in io.Reader and covert it to uint8 and an error if exists
 func (b *BIStream) ReadUint8() (uint8, error) {
 	return b.ReadByte()
@@ -82,8 +172,10 @@ func (b *BIStream) FetchUint8(value *byte) *BIStream {
 
 // ReadInt8 read 1 byte in io.Reader and covert it to int8 and an error if exists
 func (b *BIStream) ReadInt8() (int8, error) {
-	u, err := b.ReadUint8()
-	return int8(u), err
+	return interceptRead(b, "int8", func() (int8, error) {
+		u, err := b.ReadUint8()
+		return int8(u), err
+	})
 }
 
 // FetchInt8 fetch 1 byte in io.Reader.
@@ -107,11 +199,13 @@ func (b *BIStream) FetchUShort(value *uint16) *BIStream {
 
 // ReadUint16 read 2 byte in io.Reader and covert it to uint16 and an error if exists
 func (b *BIStream) ReadUint16() (uint16, error) {
-	buf, err := b.ReadBytes(2)
-	if err != nil {
-		return 0, err
-	}
-	return b.endian.Uint16(buf), nil
+	return interceptRead(b, "uint16", func() (uint16, error) {
+		buf, err := b.ReadBytes(2)
+		if err != nil {
+			return 0, err
+		}
+		return b.endian.Uint16(buf), nil
+	})
 }
 
 // FetchUint16 fetch 2 byte in io.Reader.
@@ -135,8 +229,10 @@ func (b *BIStream) FetchShort(value *int16) *BIStream {
 
 // ReadInt16 read 2 byte in io.Reader and covert it to int16 and an error if exists
 func (b *BIStream) ReadInt16() (int16, error) {
-	i, err := b.ReadUint16()
-	return int16(i), err
+	return interceptRead(b, "int16", func() (int16, error) {
+		i, err := b.ReadUint16()
+		return int16(i), err
+	})
 }
 
 // FetchInt16 fetch 2 byte in io.Reader
@@ -148,11 +244,13 @@ func (b *BIStream) FetchInt16(value *int16) *BIStream {
 
 // ReadUint32 read 4 byte in io.Reader and covert it to uint32 and an error if exists
 func (b *BIStream) ReadUint32() (uint32, error) {
-	buf, err := b.ReadBytes(4)
-	if err != nil {
-		return 0, err
-	}
-	return b.endian.Uint32(buf), nil
+	return interceptRead(b, "uint32", func() (uint32, error) {
+		buf, err := b.ReadBytes(4)
+		if err != nil {
+			return 0, err
+		}
+		return b.endian.Uint32(buf), nil
+	})
 }
 
 // FetchUint32 fetch 4 byte in io.Reader
@@ -164,8 +262,10 @@ func (b *BIStream) FetchUint32(value *uint32) *BIStream {
 
 // ReadInt32 read 4 byte in io.Reader and covert it to int32 and an error if exists
 func (b *BIStream) ReadInt32() (int32, error) {
-	i, err := b.ReadUint32()
-	return int32(i), err
+	return interceptRead(b, "int32", func() (int32, error) {
+		i, err := b.ReadUint32()
+		return int32(i), err
+	})
 }
 
 // FetchInt32 fetch 4 byte in io.Reader
@@ -177,11 +277,13 @@ func (b *BIStream) FetchInt32(value *int32) *BIStream {
 
 // ReadUint64 read 8 byte in io.Reader and covert it to uint64 and an error if exists
 func (b *BIStream) ReadUint64() (uint64, error) {
-	buf, err := b.ReadBytes(8)
-	if err != nil {
-		return 0, err
-	}
-	return b.endian.Uint64(buf), nil
+	return interceptRead(b, "uint64", func() (uint64, error) {
+		buf, err := b.ReadBytes(8)
+		if err != nil {
+			return 0, err
+		}
+		return b.endian.Uint64(buf), nil
+	})
 }
 
 // FetchUint64 fetch 8 byte in io.Reader
@@ -193,8 +295,10 @@ func (b *BIStream) FetchUint64(value *uint64) *BIStream {
 
 // ReadInt64 read 8 byte in io.Reader and covert it to int64 and an error if exists
 func (b *BIStream) ReadInt64() (int64, error) {
-	i, err := b.ReadUint64()
-	return int64(i), err
+	return interceptRead(b, "int64", func() (int64, error) {
+		i, err := b.ReadUint64()
+		return int64(i), err
+	})
 }
 
 // FetchInt64 fetch 8 byte in io.Reader
@@ -206,11 +310,13 @@ func (b *BIStream) FetchInt64(value *int64) *BIStream {
 
 // ReadFloat32 read 4 byte in io.Reader and covert it to float32 and an error if exists
 func (b *BIStream) ReadFloat32() (float32, error) {
-	buf, err := b.ReadUint32()
-	if err != nil {
-		return 0, err
-	}
-	return math.Float32frombits(buf), nil
+	return interceptRead(b, "float32", func() (float32, error) {
+		buf, err := b.ReadUint32()
+		if err != nil {
+			return 0, err
+		}
+		return math.Float32frombits(buf), nil
+	})
 }
 
 // FetchFloat32 fetch 4 byte in io.Reader
@@ -222,11 +328,13 @@ func (b *BIStream) FetchFloat32(value *float32) *BIStream {
 
 // ReadFloat64 read 8 byte in io.Reader and covert it to float64 and an error if exists
 func (b *BIStream) ReadFloat64() (float64, error) {
-	buf, err := b.ReadUint64()
-	if err != nil {
-		return 0, err
-	}
-	return math.Float64frombits(buf), nil
+	return interceptRead(b, "float64", func() (float64, error) {
+		buf, err := b.ReadUint64()
+		if err != nil {
+			return 0, err
+		}
+		return math.Float64frombits(buf), nil
+	})
 }
 
 // FetchFloat64 fetch 8 byte in io.Reader
@@ -238,8 +346,10 @@ func (b *BIStream) FetchFloat64(value *float64) *BIStream {
 
 // ReadString read n bytes as string length, then read n bytes, and covert it to string and an error if exists
 func (b *BIStream) ReadString() (string, error) {
-	buf, err := b.ReadBytesWithLengthPrefix()
-	return string(buf), err
+	return interceptRead(b, "string", func() (string, error) {
+		buf, err := b.ReadBytesWithLengthPrefix()
+		return string(buf), err
+	})
 }
 
 // FetchString fetch n bytes as string length in io.Reader
@@ -251,23 +361,32 @@ func (b *BIStream) FetchString(value *string) *BIStream {
 
 // ReadBytesWithLengthPrefix read n bytes as bytes length, then read n bytes, and an error if exists
 func (b *BIStream) ReadBytesWithLengthPrefix() ([]byte, error) {
+	return interceptRead(b, "lpbytes", func() ([]byte, error) {
+		n, err := b.readLengthPrefix()
+		if err != nil {
+			return []byte{}, err
+		}
+		if b.stats != nil {
+			b.stats.allocate(n)
+		}
+		return b.ReadBytes(n)
+	})
+}
+
+// readLengthPrefix read the length written by BOStream.WriteBytesWithLengthPrefix. Returns the length and an
+// error if exists
+func (b *BIStream) readLengthPrefix() (uint64, error) {
 	bLen, err := b.ReadByte()
-	if err != nil {
-		return []byte{}, err
-	}
-	if 0xff > bLen {
-		return b.ReadBytes(uint64(bLen))
+	if err != nil || 0xff > bLen {
+		return uint64(bLen), err
 	}
 	wLen, err := b.ReadUint16()
-	if 0xfffe > wLen {
-		return b.ReadBytes(uint64(wLen))
+	if err != nil || 0xfffe > wLen {
+		return uint64(wLen), err
 	}
-
 	len32, err := b.ReadUint32()
-	if 0xfffffffe > len32 {
-		return b.ReadBytes(uint64(len32))
+	if err != nil || 0xfffffffe > len32 {
+		return uint64(len32), err
 	}
-
-	len64, err := b.ReadUint64()
-	return b.ReadBytes(len64)
+	return b.ReadUint64()
 }
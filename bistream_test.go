@@ -3,6 +3,7 @@ package bitstream
 import (
 	"bytes"
 	"encoding/binary"
+	"io"
 	"math"
 	"reflect"
 	"testing"
@@ -86,6 +87,41 @@ func TestBIStream_ReadBytes(t *testing.T) {
 	}
 }
 
+func TestBIStream_ReadBytes_Large(t *testing.T) {
+	data := bytes.Repeat([]byte{1, 2, 3}, 100000)
+	tests := []struct {
+		name      string
+		input     []byte
+		args      uint64
+		want      []byte
+		wantError error
+	}{
+		{name: "TestBIStream_ReadBytes_Large", args: uint64(len(data)), want: data},
+		{name: "TestBIStream_ReadBytes_LargeTruncated", args: uint64(len(data)) + 1, want: data, wantError: io.ErrUnexpectedEOF},
+		{name: "TestBIStream_ReadBytes_Huge", args: math.MaxUint64, want: data, wantError: io.ErrUnexpectedEOF},
+		{name: "TestBIStream_ReadBytes_ShortBelowThreshold", input: data[:1000], args: maxPrealloc, want: data[:1000], wantError: io.ErrUnexpectedEOF},
+		{name: "TestBIStream_ReadBytes_ShortAboveThreshold", input: data[:1000], args: maxPrealloc + 1, want: data[:1000], wantError: io.ErrUnexpectedEOF},
+		{name: "TestBIStream_ReadBytes_EmptyBelowThreshold", input: []byte{}, args: maxPrealloc, want: []byte{}, wantError: io.EOF},
+		{name: "TestBIStream_ReadBytes_EmptyAboveThreshold", input: []byte{}, args: maxPrealloc + 1, want: []byte{}, wantError: io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := data
+			if tt.input != nil {
+				input = tt.input
+			}
+			p := NewBIStream(binary.BigEndian, bytes.NewReader(input))
+			got, err := p.ReadBytes(tt.args)
+			if err != tt.wantError {
+				t.Errorf("ReadBytes() error = %v, want %v", err, tt.wantError)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("ReadBytes() = %d bytes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
 func TestBIStream_ReadBytesWithLengthPrefix(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -95,6 +131,8 @@ func TestBIStream_ReadBytesWithLengthPrefix(t *testing.T) {
 		wantError error
 	}{
 		{name: "TestBIStream_ReadBytesWithLengthPrefix", endian: binary.LittleEndian, want: []byte{1, 3}, args: []byte{2, 1, 3}},
+		{name: "TestBIStream_ReadBytesWithLengthPrefix_Uint16", endian: binary.LittleEndian, want: []byte{1, 3}, args: []byte{0xff, 2, 0, 1, 3}},
+		{name: "TestBIStream_ReadBytesWithLengthPrefix_Truncated", endian: binary.LittleEndian, want: []byte{}, args: []byte{0xff, 2}, wantError: io.ErrUnexpectedEOF},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,226 @@
+package bitstreamtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"runtime/debug"
+	"strings"
+	"testing"
+	"time"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+// DecodeFunc decodes a value from b. It should return an error for a corrupt input, never panic or hang.
+type DecodeFunc func(b *bitstream.BIStream) error
+
+// Options configure Check, Run, Fuzz and RoundTrip. The zero value is ready to use.
+type Options struct {
+	// Endian is the byte order of the streams, binary.BigEndian when nil.
+	Endian binary.ByteOrder
+	// Mutations is the number of random mutations, 100 when 0 and none when negative.
+	Mutations int
+	// Seed seeds the random mutations.
+	Seed int64
+	// Timeout is the time a decode may take before it is reported as a hang, one second when 0.
+	Timeout time.Duration
+	// Count is the number of values RoundTrip generates, 100 when 0.
+	Count int
+	// Equal compares a value with the one read back by RoundTrip, reflect.DeepEqual when nil. EqualNaN also
+	// takes NaN as equal to NaN.
+	Equal func(a, b any) bool
+}
+
+func (o *Options) endian() binary.ByteOrder {
+	if nil == o || nil == o.Endian {
+		return binary.BigEndian
+	}
+	return o.Endian
+}
+
+func (o *Options) mutations() int {
+	if nil == o || 0 == o.Mutations {
+		return 100
+	}
+	return o.Mutations
+}
+
+func (o *Options) seed() int64 {
+	if nil == o {
+		return 0
+	}
+	return o.Seed
+}
+
+func (o *Options) timeout() time.Duration {
+	if nil == o || 0 == o.Timeout {
+		return time.Second
+	}
+	return o.Timeout
+}
+
+// Failure is a decode that panicked or hung.
+type Failure struct {
+	// Mutation describes how the input was corrupted, such as "truncate 12" or "flip bits 3 40".
+	Mutation string
+	// Input is the corrupted input.
+	Input []byte
+	// Panic is the value the decode panicked with, nil when it hung.
+	Panic any
+	// Stack is the stack of the panic.
+	Stack []byte
+}
+
+func (f *Failure) String() string {
+	if nil == f.Panic {
+		return fmt.Sprintf("%s: decode hung on %x", f.Mutation, f.Input)
+	}
+	return fmt.Sprintf("%s: decode panicked on %x: %v\n%s", f.Mutation, f.Input, f.Panic, f.Stack)
+}
+
+// Check run decode over data truncated at every length, with each length prefix inflated, and over random
+// mutations of data, and reports the runs that panic or hang as errors of t. data must decode without error.
+//
+// A hung decode keeps running in its goroutine until the test binary exits.
+func Check(t testing.TB, data []byte, decode DecodeFunc, opts *Options) {
+	t.Helper()
+	failures, err := Run(data, decode, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range failures {
+		if 10 == i {
+			t.Errorf("bitstreamtest: %d more failures", len(failures)-i)
+			break
+		}
+		t.Error(f)
+	}
+}
+
+// Run is Check returning the failures. Returns an error if data does not decode.
+func Run(data []byte, decode DecodeFunc, opts *Options) ([]*Failure, error) {
+	trace := bitstream.NewTrace()
+	f, err := run(bitstream.NewBIStream(opts.endian(), bytes.NewReader(data)).WithTrace(trace), decode, opts)
+	if f != nil {
+		f.Mutation, f.Input = "original", data
+		return []*Failure{f}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bitstreamtest: decoding the original input: %w", err)
+	}
+	var failures []*Failure
+	try := func(mutation string, r io.Reader) {
+		input, _ := io.ReadAll(r)
+		if f, _ := run(bitstream.NewBIStream(opts.endian(), bytes.NewReader(input)), decode, opts); f != nil {
+			f.Mutation, f.Input = mutation, input
+			failures = append(failures, f)
+		}
+	}
+	for n := 0; n < len(data); n++ {
+		try(fmt.Sprintf("truncate %d", n), Truncate(bytes.NewReader(data), int64(n)))
+	}
+	prefixes := prefixOffsets(trace)
+	for _, offset := range prefixes {
+		try(fmt.Sprintf("inflate prefix %d", offset), InflatePrefix(bytes.NewReader(data), offset))
+	}
+	if 0 == len(data) {
+		return failures, nil
+	}
+	rng := rand.New(rand.NewSource(opts.seed()))
+	for i := 0; i < opts.mutations(); i++ {
+		try(mutate(rng, data, prefixes))
+	}
+	return failures, nil
+}
+
+// Fuzz seed f with each of seeds, truncated by half and with each length prefix inflated, then fuzz decode,
+// failing on the inputs that make it panic or hang.
+//
+//	func FuzzDecode(f *testing.F) {
+//		bitstreamtest.Fuzz(f, decode, nil, sample)
+//	}
+func Fuzz(f *testing.F, decode DecodeFunc, opts *Options, seeds ...[]byte) {
+	for _, seed := range seeds {
+		f.Add(seed)
+		f.Add(seed[:len(seed)/2])
+		trace := bitstream.NewTrace()
+		if failure, err := run(bitstream.NewBIStream(opts.endian(), bytes.NewReader(seed)).WithTrace(trace), decode, opts); nil == failure && nil == err {
+			for _, offset := range prefixOffsets(trace) {
+				input, _ := io.ReadAll(InflatePrefix(bytes.NewReader(seed), offset))
+				f.Add(input)
+			}
+		}
+	}
+	f.Fuzz(func(t *testing.T, input []byte) {
+		if failure, _ := run(bitstream.NewBIStream(opts.endian(), bytes.NewReader(input)), decode, opts); failure != nil {
+			failure.Mutation, failure.Input = "fuzz", input
+			t.Fatal(failure)
+		}
+	})
+}
+
+// run decode b in a goroutine. Returns a Failure if it panics or hangs, or the error it returns.
+func run(b *bitstream.BIStream, decode DecodeFunc, opts *Options) (*Failure, error) {
+	type result struct {
+		err   error
+		panic any
+		stack []byte
+	}
+	done := make(chan result, 1)
+	go func() {
+		var r result
+		defer func() {
+			if v := recover(); v != nil {
+				r = result{panic: v, stack: debug.Stack()}
+			}
+			done <- r
+		}()
+		r.err = decode(b)
+	}()
+	timer := time.NewTimer(opts.timeout())
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		if r.panic != nil {
+			return &Failure{Panic: r.panic, Stack: r.stack}, nil
+		}
+		return nil, r.err
+	case <-timer.C:
+		return &Failure{}, nil
+	}
+}
+
+// prefixOffsets returns the offsets of the length prefixes read in trace.
+func prefixOffsets(trace *bitstream.Trace) []int64 {
+	var offsets []int64
+	for _, op := range trace.Ops {
+		if ("lpbytes" == op.Kind || "string" == op.Kind) && op.Size > 0 {
+			offsets = append(offsets, int64(op.Offset))
+		}
+	}
+	return offsets
+}
+
+// mutate returns a random mutation of data and its description.
+func mutate(rng *rand.Rand, data []byte, prefixes []int64) (string, io.Reader) {
+	r := io.Reader(bytes.NewReader(data))
+	switch k := rng.Intn(4); {
+	case 0 == k && len(prefixes) > 0:
+		offset := prefixes[rng.Intn(len(prefixes))]
+		return fmt.Sprintf("inflate prefix %d", offset), InflatePrefix(r, offset)
+	case 1 == k:
+		offset, c := rng.Int63n(int64(len(data))), byte(rng.Intn(256))
+		return fmt.Sprintf("overwrite %d %02x", offset, c), Overwrite(r, offset, []byte{c})
+	case 2 == k:
+		n, bit := rng.Int63n(int64(len(data))), rng.Int63n(8*int64(len(data)))
+		return fmt.Sprintf("truncate %d flip bits %d", n, bit), Truncate(FlipBits(r, bit), n)
+	}
+	bits := make([]int64, 1+rng.Intn(3))
+	for i := range bits {
+		bits[i] = rng.Int63n(8 * int64(len(data)))
+	}
+	return fmt.Sprintf("flip bits %s", strings.Trim(fmt.Sprint(bits), "[]")), FlipBits(r, bits...)
+}
@@ -0,0 +1,125 @@
+package bitstreamtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	bitstream "github.com/meetleev/go_bitstream"
+	"github.com/meetleev/go_bitstream/schema"
+)
+
+// message writes a sample input of decodeMessage.
+func message() []byte {
+	buf := new(bytes.Buffer)
+	bitstream.NewBOStream(binary.BigEndian, buf).
+		WriteUint16(1).WriteString("bitstream").WriteBytesWithLengthPrefix([]byte{1, 2, 3}).WriteUvarint(300)
+	return buf.Bytes()
+}
+
+// decodeMessage is a decoder returning errors on corrupt inputs.
+func decodeMessage(b *bitstream.BIStream) error {
+	var version uint16
+	var name string
+	b.FetchUint16(&version).FetchString(&name)
+	if err := b.Error(); err != nil {
+		return err
+	}
+	if _, err := b.ReadBytesWithLengthPrefix(); err != nil {
+		return err
+	}
+	_, err := b.ReadUvarint()
+	return err
+}
+
+func TestCheck(t *testing.T) {
+	Check(t, message(), decodeMessage, &Options{Mutations: 500})
+}
+
+func TestCheck_Schema(t *testing.T) {
+	s, err := schema.LoadFile("../schema/testdata/wav.ksy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("../schema/testdata/wav.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Check(t, data, func(b *bitstream.BIStream) error {
+		_, err := s.Decode(b)
+		return err
+	}, &Options{Endian: binary.LittleEndian, Mutations: 500})
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []byte
+		decode       DecodeFunc
+		opts         *Options
+		wantMutation string
+		wantHang     bool
+		wantError    bool
+	}{
+		{
+			name: "TestRun_Panic",
+			args: []byte{2, 1, 2, 3},
+			decode: func(b *bitstream.BIStream) error {
+				n, err := b.ReadUint8()
+				_ = make([]int, 3)[n]
+				return err
+			},
+			opts:         &Options{Seed: 1},
+			wantMutation: "flip bits",
+		},
+		{
+			name: "TestRun_Hang",
+			args: []byte{1, 2, 0},
+			decode: func(b *bitstream.BIStream) error {
+				for {
+					c, err := b.ReadUint8()
+					for ; err != nil; time.Sleep(time.Hour) {
+					}
+					if 0 == c {
+						return nil
+					}
+				}
+			},
+			opts:         &Options{Mutations: -1, Timeout: 20 * time.Millisecond},
+			wantMutation: "truncate 0",
+			wantHang:     true,
+		},
+		{
+			name:      "TestRun_Original",
+			args:      []byte{1},
+			decode:    func(b *bitstream.BIStream) error { return errors.New("unsupported") },
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures, err := Run(tt.args, tt.decode, tt.opts)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Run() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+			if 0 == len(failures) {
+				t.Fatalf("Run() = no failure, want one")
+			}
+			f := failures[0]
+			if !strings.HasPrefix(f.Mutation, tt.wantMutation) || (nil == f.Panic) != tt.wantHang {
+				t.Errorf("Run() = %v, want %s", f, tt.wantMutation)
+			}
+		})
+	}
+}
+
+func FuzzDecodeMessage(f *testing.F) {
+	Fuzz(f, decodeMessage, nil, message())
+}
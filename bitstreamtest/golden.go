@@ -0,0 +1,162 @@
+package bitstreamtest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+var update = flag.Bool("bitstreamtest.update", false, "rewrite the golden files of bitstreamtest.Golden")
+
+// Golden compare the bytes encode writes with the golden file testdata/name.golden, and reports the difference
+// as an error of t: the first differing offset, the field that wrote it, with the labels of its sections, and
+// a hex diff of the lines around it annotated with the fields. With go test -bitstreamtest.update, the golden
+// file is written instead.
+func Golden(t testing.TB, name string, encode func(p *bitstream.BOStream) error, opts *Options) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	trace := bitstream.NewTrace()
+	p := bitstream.NewBOStream(opts.endian(), buf).WithTrace(trace)
+	err := encode(p)
+	if nil == err {
+		err = p.Error()
+	}
+	if err != nil {
+		t.Fatalf("bitstreamtest: encoding %s: %v", name, err)
+	}
+	file := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("bitstreamtest: missing %s, run go test -bitstreamtest.update to write it", file)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("bitstreamtest: %s differs, run go test -bitstreamtest.update if the change is expected\n%s", file, diff(trace, buf.Bytes(), want))
+	}
+}
+
+// diff describes the first difference between got, traced by trace, and want.
+func diff(trace *bitstream.Trace, got, want []byte) string {
+	at := 0
+	for at < min(len(got), len(want)) && got[at] == want[at] {
+		at++
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "first difference at offset %d (0x%x), got %d bytes, want %d", at, at, len(got), len(want))
+	if f := field(trace, at); f != "" {
+		fmt.Fprintf(&sb, ", written by %s", f)
+	}
+	sb.WriteString("\n")
+	for line := at / 16 * 16; line < max(len(got), len(want)) && line < at/16*16+4*16; line += 16 {
+		wantLine, gotLine := row(want, line), row(got, line)
+		if wantLine == gotLine {
+			fmt.Fprintf(&sb, " %s\n", gotLine)
+			continue
+		}
+		if wantLine != "" {
+			fmt.Fprintf(&sb, "-%s\n", wantLine)
+		}
+		if f := fields(trace, line, line+16); gotLine != "" && f != "" {
+			gotLine = fmt.Sprintf("%-58s  %s", gotLine, f)
+		}
+		if gotLine != "" {
+			fmt.Fprintf(&sb, "+%s\n", gotLine)
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// row returns the offset and the hex of the 16 bytes of data from line, or "" past its end.
+func row(data []byte, line int) string {
+	if line >= len(data) {
+		return ""
+	}
+	var sb strings.Builder
+	for i, c := range data[line:min(line+16, len(data))] {
+		if 8 == i {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(" ")
+		sb.WriteString(hex.EncodeToString([]byte{c}))
+	}
+	return fmt.Sprintf("%08x %s", line, sb.String())
+}
+
+// field returns the innermost operation of trace that wrote the byte at offset, named with the labels of its
+// sections, or "" when there is none.
+func field(trace *bitstream.Trace, offset int) string {
+	var found string
+	walk(trace, func(path string, op bitstream.Op) {
+		if op.Offset <= offset && offset < op.Offset+op.Size {
+			found = describe(path, op)
+		}
+	})
+	return found
+}
+
+// fields returns the operations of trace, not sections, starting between from and to.
+func fields(trace *bitstream.Trace, from, to int) string {
+	var list []string
+	walk(trace, func(path string, op bitstream.Op) {
+		if op.Kind != "section" && op.Offset >= from && op.Offset < to {
+			list = append(list, describe(path, op))
+		}
+	})
+	return strings.Join(list, ", ")
+}
+
+// walk call visit for each operation of trace with its label joined to the labels of its sections.
+func walk(trace *bitstream.Trace, visit func(path string, op bitstream.Op)) {
+	var labels []string
+	for _, op := range trace.Ops {
+		labels = append(labels[:min(op.Depth, len(labels))], op.Label)
+		var path []string
+		for _, label := range labels {
+			if label != "" {
+				path = append(path, label)
+			}
+		}
+		visit(strings.Join(path, "."), op)
+	}
+}
+
+func describe(path string, op bitstream.Op) string {
+	s := op.Kind
+	if op.Kind != "section" && op.Value != nil {
+		v := fmt.Sprint(op.Value)
+		switch value := op.Value.(type) {
+		case []byte:
+			v = hex.EncodeToString(value)
+		case string:
+			v = fmt.Sprintf("%q", value)
+		}
+		if len(v) > 32 {
+			v = v[:32] + "..."
+		}
+		s += " " + v
+	}
+	if path != "" {
+		s = path + ": " + s
+	}
+	return s
+}
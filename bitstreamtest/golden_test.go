@@ -0,0 +1,102 @@
+package bitstreamtest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+// encodeHeader returns an encode function of a header with version.
+func encodeHeader(version uint16) func(p *bitstream.BOStream) error {
+	return func(p *bitstream.BOStream) error {
+		p.Label("magic").WriteBytes([]byte("BSTR")).
+			BeginSection("header").
+			Label("version").WriteUint16(version).
+			Label("name").WriteString("bitstream").
+			EndSection().
+			Label("payload").WriteBytesWithLengthPrefix(make([]byte, 20))
+		return p.Error()
+	}
+}
+
+// golden runs Golden in a goroutine, so that its Fatalf stops only it.
+func golden(f *failures, name string, encode func(p *bitstream.BOStream) error, opts *Options) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Golden(f, name, encode, opts)
+	}()
+	<-done
+}
+
+func (f *failures) Fatalf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+	runtime.Goexit()
+}
+
+func TestGolden(t *testing.T) {
+	Golden(t, "header", encodeHeader(1), nil)
+}
+
+func TestGolden_Differ(t *testing.T) {
+	f := &failures{TB: t}
+	golden(f, "header", encodeHeader(2), nil)
+	want := "" +
+		"bitstreamtest: testdata/header.golden differs, run go test -bitstreamtest.update if the change is expected\n" +
+		"first difference at offset 5 (0x5), got 37 bytes, want 37, written by header.version: uint16 2\n" +
+		"-00000000  42 53 54 52 00 01 09 62  69 74 73 74 72 65 61 6d\n" +
+		"+00000000  42 53 54 52 00 02 09 62  69 74 73 74 72 65 61 6d  magic: bytes 42535452, header.version: uint16 2, header.name: string \"bitstream\"\n" +
+		" 00000010  14 00 00 00 00 00 00 00  00 00 00 00 00 00 00 00\n" +
+		" 00000020  00 00 00 00 00\n"
+	if 1 != len(f.errors) || f.errors[0] != strings.TrimSuffix(want, "\n") {
+		t.Errorf("Golden() errors = %q, want %q", f.errors, want)
+	}
+}
+
+func TestGolden_LittleEndian(t *testing.T) {
+	f := &failures{TB: t}
+	golden(f, "header", func(p *bitstream.BOStream) error {
+		return encodeHeader(1)(p.WithTrace(nil)) // the trace is lost, no field is named
+	}, &Options{Endian: binary.LittleEndian})
+	if 1 != len(f.errors) || !strings.Contains(f.errors[0], "first difference at offset 4 (0x4), got 37 bytes, want 37\n") {
+		t.Errorf("Golden() errors = %q", f.errors)
+	}
+}
+
+func TestGolden_Update(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	f := &failures{TB: t}
+	golden(f, "header", encodeHeader(1), nil)
+	if 1 != len(f.errors) || !strings.Contains(f.errors[0], "missing testdata/header.golden") {
+		t.Errorf("Golden() errors = %q, want the golden file missing", f.errors)
+	}
+	*update = true
+	defer func() { *update = false }()
+	Golden(t, "header", encodeHeader(1), nil)
+	got, err := os.ReadFile(filepath.Join(dir, "testdata", "header.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join(wd, "testdata", "header.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Golden() wrote %x, want %x", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+// Package bitstreamtest provides utilities for testing decoders and encoders built on package bitstream.
+//
+// The readers of this package corrupt the bytes of another reader: they truncate them, flip bits or inflate
+// the length prefixes written by BOStream.WriteBytesWithLengthPrefix. Check runs a decoder over every
+// truncation and random mutations of an input, and reports the runs that panic or hang. RoundTrip writes and
+// reads back random values, and Golden compares an encoding with a golden file.
+package bitstreamtest
+
+import (
+	"bytes"
+	"io"
+)
+
+// Truncate returns a reader of the first n bytes of r.
+func Truncate(r io.Reader, n int64) io.Reader {
+	return io.LimitReader(r, n)
+}
+
+// FlipBits returns a reader of r with the bits at the given offsets inverted. Bit 0 is the most significant
+// bit of the first byte.
+func FlipBits(r io.Reader, bits ...int64) io.Reader {
+	return &editReader{r: r, edit: func(buf []byte, offset int64) {
+		for _, bit := range bits {
+			if i := bit/8 - offset; i >= 0 && i < int64(len(buf)) {
+				buf[i] ^= 0x80 >> (bit % 8)
+			}
+		}
+	}}
+}
+
+// Overwrite returns a reader of r with data in place of the bytes at offset. The bytes of data past the end of
+// r are dropped.
+func Overwrite(r io.Reader, offset int64, data []byte) io.Reader {
+	return &editReader{r: r, edit: func(buf []byte, at int64) {
+		for i, c := range data {
+			if k := offset + int64(i) - at; k >= 0 && k < int64(len(buf)) {
+				buf[k] = c
+			}
+		}
+	}}
+}
+
+// InflatePrefix returns a reader of r with the length prefix at offset, as written by
+// BOStream.WriteBytesWithLengthPrefix, replaced by the largest length, 1<<64 - 1.
+func InflatePrefix(r io.Reader, offset int64) io.Reader {
+	return Overwrite(r, offset, bytes.Repeat([]byte{0xff}, 1+2+4+8))
+}
+
+// editReader calls edit on the bytes read from r, with their offset.
+type editReader struct {
+	r      io.Reader
+	offset int64
+	edit   func(buf []byte, offset int64)
+}
+
+func (e *editReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	e.edit(p[:n], e.offset)
+	e.offset += int64(n)
+	return n, err
+}
@@ -0,0 +1,36 @@
+package bitstreamtest
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReaders(t *testing.T) {
+	data := []byte{0x00, 0x01, 0x02, 0x03}
+	tests := []struct {
+		name   string
+		reader func(r io.Reader) io.Reader
+		want   []byte
+	}{
+		{name: "TestTruncate", reader: func(r io.Reader) io.Reader { return Truncate(r, 3) }, want: []byte{0x00, 0x01, 0x02}},
+		{name: "TestTruncate_Past", reader: func(r io.Reader) io.Reader { return Truncate(r, 9) }, want: data},
+		{name: "TestFlipBits", reader: func(r io.Reader) io.Reader { return FlipBits(r, 0, 15, 31, 99) }, want: []byte{0x80, 0x00, 0x02, 0x02}},
+		{name: "TestOverwrite", reader: func(r io.Reader) io.Reader { return Overwrite(r, 2, []byte{0xaa, 0xbb, 0xcc}) }, want: []byte{0x00, 0x01, 0xaa, 0xbb}},
+		{name: "TestInflatePrefix", reader: func(r io.Reader) io.Reader { return InflatePrefix(r, 1) }, want: []byte{0x00, 0xff, 0xff, 0xff}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// one byte reads check that the offsets carry over
+			got, err := io.ReadAll(tt.reader(iotest.OneByteReader(bytes.NewReader(data))))
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadAll() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
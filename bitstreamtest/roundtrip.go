@@ -0,0 +1,164 @@
+package bitstreamtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+// RoundTrip generate random values of T with testing/quick, write each with its MarshalBitstream and read it
+// back with UnmarshalBitstream, and reports as errors of t the values that fail, do not read back equal or
+// leave bytes unread, with the bytes written and a hexdump of the read.
+//
+// T implements quick.Generator to control its values, and must otherwise have only exported fields.
+//
+//	bitstreamtest.RoundTrip[Header](t, &bitstreamtest.Options{Equal: bitstreamtest.EqualNaN})
+func RoundTrip[T any, PT interface {
+	*T
+	bitstream.Marshaler
+	bitstream.Unmarshaler
+}](t testing.TB, opts *Options) {
+	t.Helper()
+	rng := rand.New(rand.NewSource(opts.seed()))
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	failures := 0
+	for i := 0; i < opts.count(); i++ {
+		v, ok := quick.Value(typ, rng)
+		if !ok {
+			t.Fatalf("bitstreamtest: cannot generate values of %v", typ)
+		}
+		want := v.Interface().(T)
+		if err := roundTrip[T, PT](&want, opts); err != nil {
+			if failures++; failures > 10 {
+				t.Errorf("bitstreamtest: more values fail")
+				return
+			}
+			t.Error(err)
+		}
+	}
+}
+
+func (o *Options) count() int {
+	if nil == o || 0 == o.Count {
+		return 100
+	}
+	return o.Count
+}
+
+func (o *Options) equal() func(a, b any) bool {
+	if nil == o || nil == o.Equal {
+		return reflect.DeepEqual
+	}
+	return o.Equal
+}
+
+// roundTrip write want and read it back. Returns an error describing the failure if any.
+func roundTrip[T any, PT interface {
+	*T
+	bitstream.Marshaler
+	bitstream.Unmarshaler
+}](want *T, opts *Options) error {
+	buf := new(bytes.Buffer)
+	p := bitstream.NewBOStream(opts.endian(), buf)
+	err := PT(want).MarshalBitstream(p)
+	if nil == err {
+		err = p.Error()
+	}
+	if err != nil {
+		return fmt.Errorf("bitstreamtest: MarshalBitstream(%+v) error = %w", *want, err)
+	}
+	trace := bitstream.NewTrace()
+	b := bitstream.NewBIStream(opts.endian(), bytes.NewReader(buf.Bytes())).WithTrace(trace)
+	got := new(T)
+	var problem string
+	if err := PT(got).UnmarshalBitstream(b); err != nil {
+		problem = fmt.Sprintf("UnmarshalBitstream() error = %v", err)
+	} else if !opts.equal()(*want, *got) {
+		problem = fmt.Sprintf("read back %+v", *got)
+	} else if eof, _ := b.AtEOF(); !eof {
+		problem = fmt.Sprintf("%d bytes not read", int64(buf.Len())-b.BytesRead())
+	} else {
+		return nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "bitstreamtest: round trip of %+v: %s\nwrote %x\n", *want, problem, buf.Bytes())
+	trace.WriteHexdump(&sb)
+	return errors.New(strings.TrimSuffix(sb.String(), "\n"))
+}
+
+// EqualNaN reports whether a and b are deeply equal like reflect.DeepEqual, except that NaN equals NaN, in
+// floats and complex numbers. Unexported fields are compared too. Cyclic values are not supported.
+func EqualNaN(a, b any) bool {
+	return equalNaN(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func equalNaN(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return floatEqual(a.Float(), b.Float())
+	case reflect.Complex64, reflect.Complex128:
+		x, y := a.Complex(), b.Complex()
+		return floatEqual(real(x), real(y)) && floatEqual(imag(x), imag(y))
+	case reflect.String:
+		return a.String() == b.String()
+	case reflect.Slice, reflect.Map:
+		if a.IsNil() != b.IsNil() || a.Len() != b.Len() {
+			return false
+		}
+		if reflect.Map == a.Kind() {
+			for _, k := range a.MapKeys() {
+				if v := b.MapIndex(k); !v.IsValid() || !equalNaN(a.MapIndex(k), v) {
+					return false
+				}
+			}
+			return true
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if !equalNaN(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !equalNaN(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return equalNaN(a.Elem(), b.Elem())
+	case reflect.Func:
+		return a.IsNil() && b.IsNil()
+	}
+	return a.Pointer() == b.Pointer()
+}
+
+func floatEqual(x, y float64) bool {
+	return x == y || (math.IsNaN(x) && math.IsNaN(y))
+}
@@ -0,0 +1,142 @@
+package bitstreamtest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+type record struct {
+	ID    uint32
+	Name  string
+	Score float64
+	Tags  []uint16
+	Valid bool
+}
+
+func (r *record) MarshalBitstream(p *bitstream.BOStream) error {
+	p.WriteUint32(r.ID).WriteString(r.Name).WriteFloat64(r.Score).WriteUvarint(uint64(len(r.Tags)))
+	for _, tag := range r.Tags {
+		p.WriteUint16(tag)
+	}
+	return p.WriteBool(r.Valid).Error()
+}
+
+func (r *record) UnmarshalBitstream(b *bitstream.BIStream) error {
+	b.FetchUint32(&r.ID).FetchString(&r.Name).FetchFloat64(&r.Score)
+	n, err := b.ReadUvarint()
+	if err != nil {
+		return err
+	}
+	r.Tags = make([]uint16, n)
+	for i := range r.Tags {
+		b.FetchUint16(&r.Tags[i])
+	}
+	return b.FetchBool(&r.Valid).Error()
+}
+
+// nanRecord generates NaN scores.
+type nanRecord struct {
+	record
+}
+
+func (nanRecord) Generate(rng *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(nanRecord{record{ID: rng.Uint32(), Score: math.NaN(), Tags: []uint16{}}})
+}
+
+// lossyRecord writes the id on 8 bits only.
+type lossyRecord struct {
+	ID uint16
+}
+
+func (r *lossyRecord) MarshalBitstream(p *bitstream.BOStream) error {
+	return p.WriteUint8(uint8(r.ID)).Error()
+}
+
+func (r *lossyRecord) UnmarshalBitstream(b *bitstream.BIStream) error {
+	n, err := b.ReadUint8()
+	r.ID = uint16(n)
+	return err
+}
+
+// failures collects the errors of a test.
+type failures struct {
+	testing.TB
+	errors []string
+}
+
+func (f *failures) Error(args ...any) {
+	f.errors = append(f.errors, fmt.Sprint(args...))
+}
+
+func (f *failures) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRoundTrip(t *testing.T) {
+	RoundTrip[record](t, nil)
+	RoundTrip[nanRecord](t, &Options{Equal: EqualNaN, Count: 10})
+}
+
+func TestRoundTrip_Failures(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t testing.TB)
+		want string
+	}{
+		{
+			name: "TestRoundTrip_NaN",
+			run:  func(t testing.TB) { RoundTrip[nanRecord](t, &Options{Count: 1}) },
+			want: "Score:NaN",
+		},
+		{
+			name: "TestRoundTrip_Lossy",
+			run:  func(t testing.TB) { RoundTrip[lossyRecord](t, &Options{Seed: 2}) },
+			want: "read back {ID:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &failures{TB: t}
+			tt.run(f)
+			if 0 == len(f.errors) {
+				t.Fatalf("RoundTrip() reported no error")
+			}
+			if !strings.Contains(f.errors[0], tt.want) || !strings.Contains(f.errors[0], "\nwrote ") || !strings.Contains(f.errors[0], "00000000  ") {
+				t.Errorf("RoundTrip() error = %s, want %q with the bytes and a hexdump", f.errors[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestEqualNaN(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name string
+		a, b any
+		want bool
+	}{
+		{name: "TestEqualNaN_Float", a: nan, b: nan, want: true},
+		{name: "TestEqualNaN_Float32", a: float32(nan), b: float32(1), want: false},
+		{name: "TestEqualNaN_Complex", a: complex(nan, 1), b: complex(nan, 1), want: true},
+		{name: "TestEqualNaN_Struct", a: record{Score: nan, Tags: []uint16{1}}, b: record{Score: nan, Tags: []uint16{1}}, want: true},
+		{name: "TestEqualNaN_Slice", a: []float64{1, nan}, b: []float64{1, 2}, want: false},
+		{name: "TestEqualNaN_NilSlice", a: []byte(nil), b: []byte{}, want: false},
+		{name: "TestEqualNaN_Map", a: map[string]any{"a": nan}, b: map[string]any{"a": nan}, want: true},
+		{name: "TestEqualNaN_Pointer", a: &nan, b: new(float64), want: false},
+		{name: "TestEqualNaN_Types", a: 1, b: int64(1), want: false},
+		{name: "TestEqualNaN_Nil", a: nil, b: nil, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EqualNaN(tt.a, tt.b); got != tt.want {
+				t.Errorf("EqualNaN() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package bitstream
+
+import (
+	"crypto/hmac"
+	"errors"
+	"hash"
+	"io"
+)
+
+// ErrChecksumMismatch is reported when a checksum read by EndChecksum does not match the data.
+var ErrChecksumMismatch = errors.New("bitstream: checksum mismatch")
+
+// BeginChecksum start a checksum region. Every byte written until the matching EndChecksum is fed to h,
+// which can be a hash/crc32, hash/adler32, NewCRC16, NewCRC8 or any other hash.Hash.
+func (p *BOStream) BeginChecksum(h hash.Hash) *BOStream {
+	return p.catchError(func() {
+		h.Reset()
+		p.pushRegion(region{kind: regionChecksum, hash: h}, io.MultiWriter(p.writer, h))
+	})
+}
+
+// EndChecksum end the checksum region and write the checksum. Hash8, Hash16, hash.Hash32 and
+// hash.Hash64 checksums are written as integers in the stream byte order, others as h.Sum bytes.
+func (p *BOStream) EndChecksum() *BOStream {
+	r, ok := p.popRegion(regionChecksum)
+	if !ok {
+		return p
+	}
+	return p.catchError(func() {
+		switch h := r.hash.(type) {
+		case Hash8:
+			p.WriteUint8(h.Sum8())
+		case Hash16:
+			p.WriteUint16(h.Sum16())
+		case hash.Hash32:
+			p.WriteUint32(h.Sum32())
+		case hash.Hash64:
+			p.WriteUint64(h.Sum64())
+		default:
+			p.WriteBytes(h.Sum(nil))
+		}
+	})
+}
+
+// BeginChecksum start a checksum region. Every byte read until the matching EndChecksum is fed to h.
+func (b *BIStream) BeginChecksum(h hash.Hash) *BIStream {
+	return b.catchError(func() {
+		h.Reset()
+		b.pushRegion(region{kind: regionChecksum, hash: h}, io.TeeReader(b.reader, h))
+	})
+}
+
+// EndChecksum end the checksum region, then read the checksum written by BOStream.EndChecksum and verify it.
+// A mismatch is reported as ErrChecksumMismatch.
+func (b *BIStream) EndChecksum() *BIStream {
+	r, ok := b.popRegion(regionChecksum)
+	if !ok {
+		return b
+	}
+	return b.catchError(func() {
+		var match bool
+		switch h := r.hash.(type) {
+		case Hash8:
+			var sum uint8
+			b.FetchUint8(&sum)
+			match = sum == h.Sum8()
+		case Hash16:
+			var sum uint16
+			b.FetchUint16(&sum)
+			match = sum == h.Sum16()
+		case hash.Hash32:
+			var sum uint32
+			b.FetchUint32(&sum)
+			match = sum == h.Sum32()
+		case hash.Hash64:
+			var sum uint64
+			b.FetchUint64(&sum)
+			match = sum == h.Sum64()
+		default:
+			var sum []byte
+			b.FetchBytes(&sum, uint64(h.Size()))
+			match = hmac.Equal(sum, h.Sum(nil))
+		}
+		if nil == b.err && !match {
+			b.err = ErrChecksumMismatch
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package bitstream
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"reflect"
+	"testing"
+)
+
+func TestBOStream_EndChecksum(t *testing.T) {
+	tests := []struct {
+		name      string
+		endian    binary.ByteOrder
+		hash      hash.Hash
+		args      []byte
+		want      []byte
+		wantError error
+	}{
+		{name: "TestBOStream_EndChecksum_CRC32", endian: binary.BigEndian, hash: crc32.NewIEEE(), args: []byte("123456789"), want: append([]byte("123456789"), 0xcb, 0xf4, 0x39, 0x26)},
+		{name: "TestBOStream_EndChecksum_Adler32", endian: binary.LittleEndian, hash: adler32.New(), args: []byte("123456789"), want: append([]byte("123456789"), 0xde, 0x01, 0x1e, 0x09)},
+		{name: "TestBOStream_EndChecksum_Modbus", endian: binary.LittleEndian, hash: NewCRC16(CRC16Modbus), args: []byte("123456789"), want: append([]byte("123456789"), 0x37, 0x4b)},
+		{name: "TestBOStream_EndChecksum_CRC8", endian: binary.BigEndian, hash: NewCRC8(CRC8), args: []byte("123456789"), want: append([]byte("123456789"), 0xf4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			p := NewBOStream(tt.endian, buf)
+			p.BeginChecksum(tt.hash).WriteBytes(tt.args).EndChecksum()
+			if p.Error() != tt.wantError {
+				t.Errorf("EndChecksum() has error %v", p.Error())
+			}
+			if !reflect.DeepEqual(buf.Bytes(), tt.want) {
+				t.Errorf("EndChecksum() = %v, want %v", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBIStream_EndChecksum(t *testing.T) {
+	tests := []struct {
+		name      string
+		endian    binary.ByteOrder
+		hash      hash.Hash
+		args      []byte
+		want      []byte
+		wantError error
+	}{
+		{name: "TestBIStream_EndChecksum_CRC32", endian: binary.BigEndian, hash: crc32.NewIEEE(), args: append([]byte("123456789"), 0xcb, 0xf4, 0x39, 0x26), want: []byte("123456789")},
+		{name: "TestBIStream_EndChecksum_CCITT", endian: binary.BigEndian, hash: NewCRC16(CRC16CCITT), args: append([]byte("123456789"), 0x29, 0xb1), want: []byte("123456789")},
+		{name: "TestBIStream_EndChecksum_CRC8", endian: binary.BigEndian, hash: NewCRC8(CRC8), args: append([]byte("123456789"), 0xf4), want: []byte("123456789")},
+		{name: "TestBIStream_EndChecksum_Mismatch", endian: binary.BigEndian, hash: crc32.NewIEEE(), args: append([]byte("123456780"), 0xcb, 0xf4, 0x39, 0x26), want: []byte("123456780"), wantError: ErrChecksumMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data []byte
+			p := NewBIStream(tt.endian, bytes.NewBuffer(tt.args))
+			p.BeginChecksum(tt.hash).FetchBytes(&data, 9).EndChecksum()
+			if p.Error() != tt.wantError {
+				t.Errorf("EndChecksum() error = %v, want %v", p.Error(), tt.wantError)
+			}
+			if !reflect.DeepEqual(data, tt.want) {
+				t.Errorf("EndChecksum() = %v, want %v", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecksum_Nested(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := NewBOStream(binary.BigEndian, buf)
+	w.BeginChecksum(sha256.New()).
+		WriteUint16(1).
+		BeginChecksum(NewCRC16(CRC16IBM)).WriteString("inner").EndChecksum().
+		WriteUint32(2).
+		EndChecksum()
+	if w.Error() != nil {
+		t.Fatalf("EndChecksum() has error %v", w.Error())
+	}
+
+	var (
+		u16 uint16
+		s   string
+		u32 uint32
+	)
+	r := NewBIStream(binary.BigEndian, bytes.NewReader(buf.Bytes()))
+	r.BeginChecksum(sha256.New()).
+		FetchUint16(&u16).
+		BeginChecksum(NewCRC16(CRC16IBM)).FetchString(&s).EndChecksum().
+		FetchUint32(&u32).
+		EndChecksum()
+	if r.Error() != nil {
+		t.Fatalf("EndChecksum() has error %v", r.Error())
+	}
+	if u16 != 1 || s != "inner" || u32 != 2 {
+		t.Errorf("EndChecksum() = %v %v %v, want 1 inner 2", u16, s, u32)
+	}
+}
+
+func TestChecksum_RegionMismatch(t *testing.T) {
+	w := NewBOStream(binary.BigEndian, new(bytes.Buffer))
+	w.EndChecksum()
+	if w.Error() != ErrRegionMismatch {
+		t.Errorf("EndChecksum() error = %v, want %v", w.Error(), ErrRegionMismatch)
+	}
+}
@@ -0,0 +1,319 @@
+// Command bitstream inspects, dumps and builds binary files described by a schema.
+//
+// Usage:
+//
+//	bitstream dump -schema fmt.yaml [-json] file.bin
+//	bitstream encode -schema fmt.yaml [-o out.bin] value.json
+//	bitstream diff -schema fmt.yaml a.bin b.bin
+//	bitstream hexdump [-schema fmt.yaml] file.bin
+//
+// dump prints the annotated tree of the fields with their offsets and sizes, or the decoded value as JSON.
+// encode writes the value in the JSON file, as printed by dump -json, to out.bin or the standard output.
+// diff prints the fields whose values differ and exits with status 1 when there is any. hexdump prints the
+// bytes with their offsets, annotated with the fields when a schema is given.
+//
+// Every command takes -endian be or le, the byte order of fields whose endian the schema does not set.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	bitstream "github.com/meetleev/go_bitstream"
+	"github.com/meetleev/go_bitstream/schema"
+)
+
+const usage = `usage:
+  bitstream dump -schema fmt.yaml [-json] file.bin
+  bitstream encode -schema fmt.yaml [-o out.bin] value.json
+  bitstream diff -schema fmt.yaml a.bin b.bin
+  bitstream hexdump [-schema fmt.yaml] file.bin
+`
+
+// errDiffer is returned by diff when the files differ.
+var errDiffer = errors.New("files differ")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run the command in args, and returns the exit status: 0 on success, 1 when diff finds differences and 2 on
+// errors.
+func run(args []string, stdout, stderr io.Writer) int {
+	if 0 == len(args) {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	var cmd func(c *command, args []string) error
+	switch args[0] {
+	case "dump":
+		cmd = dump
+	case "encode":
+		cmd = encode
+	case "diff":
+		cmd = diff
+	case "hexdump":
+		cmd = hexdump
+	default:
+		fmt.Fprintf(stderr, "bitstream: unknown command %q\n%s", args[0], usage)
+		return 2
+	}
+	c := &command{flags: flag.NewFlagSet(args[0], flag.ContinueOnError), stdout: stdout}
+	c.flags.SetOutput(stderr)
+	c.flags.StringVar(&c.schemaFile, "schema", "", "schema `file`, YAML, JSON or .ksy")
+	c.flags.StringVar(&c.endianName, "endian", "be", "default byte order, be or le")
+	err := cmd(c, args[1:])
+	switch {
+	case nil == err:
+		return 0
+	case errors.Is(err, errDiffer):
+		return 1
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	}
+	fmt.Fprintf(stderr, "bitstream %s: %v\n", args[0], err)
+	return 2
+}
+
+// command holds the flags shared by the commands.
+type command struct {
+	flags      *flag.FlagSet
+	schemaFile string
+	endianName string
+	stdout     io.Writer
+}
+
+// parse parse args, and returns the n positional arguments.
+func (c *command) parse(args []string, n int) ([]string, error) {
+	if err := c.flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if c.flags.NArg() != n {
+		return nil, fmt.Errorf("want %d file arguments, got %d", n, c.flags.NArg())
+	}
+	return c.flags.Args(), nil
+}
+
+func (c *command) endian() (binary.ByteOrder, error) {
+	switch c.endianName {
+	case "be":
+		return binary.BigEndian, nil
+	case "le":
+		return binary.LittleEndian, nil
+	}
+	return nil, fmt.Errorf("unknown endian %q", c.endianName)
+}
+
+func (c *command) schema() (*schema.Schema, error) {
+	if "" == c.schemaFile {
+		return nil, errors.New("missing -schema")
+	}
+	return schema.LoadFile(c.schemaFile)
+}
+
+// decode read name with the schema, tracing the fields.
+func (c *command) decode(s *schema.Schema, name string) (*schema.Struct, *bitstream.Trace, error) {
+	trace := bitstream.NewTrace()
+	endian, err := c.endian()
+	if err != nil {
+		return nil, trace, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, trace, err
+	}
+	defer f.Close()
+	v, err := s.Decode(bitstream.NewBIStream(endian, bufio.NewReader(f)).WithTrace(trace))
+	return v, trace, err
+}
+
+func dump(c *command, args []string) error {
+	asJSON := c.flags.Bool("json", false, "print the decoded value as JSON")
+	files, err := c.parse(args, 1)
+	if err != nil {
+		return err
+	}
+	s, err := c.schema()
+	if err != nil {
+		return err
+	}
+	v, trace, err := c.decode(s, files[0])
+	if err != nil {
+		// the fields read before the error help finding it
+		trace.WriteTree(c.stdout)
+		return err
+	}
+	if *asJSON {
+		js, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.stdout, "%s\n", js)
+		return err
+	}
+	return trace.WriteTree(c.stdout)
+}
+
+func encode(c *command, args []string) error {
+	out := c.flags.String("o", "", "output `file`, the standard output by default")
+	files, err := c.parse(args, 1)
+	if err != nil {
+		return err
+	}
+	s, err := c.schema()
+	if err != nil {
+		return err
+	}
+	endian, err := c.endian()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	var v map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err := s.Encode(bitstream.NewBOStream(endian, buf), v); err != nil {
+		return err
+	}
+	if "" == *out {
+		_, err = c.stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(*out, buf.Bytes(), 0o644)
+}
+
+func diff(c *command, args []string) error {
+	files, err := c.parse(args, 2)
+	if err != nil {
+		return err
+	}
+	s, err := c.schema()
+	if err != nil {
+		return err
+	}
+	a, _, err := c.decode(s, files[0])
+	if err != nil {
+		return fmt.Errorf("%s: %w", files[0], err)
+	}
+	b, _, err := c.decode(s, files[1])
+	if err != nil {
+		return fmt.Errorf("%s: %w", files[1], err)
+	}
+	n := 0
+	compare("", a, b, func(path string, x, y any) {
+		fmt.Fprintf(c.stdout, "%s: %s -> %s\n", path, format(x), format(y))
+		n++
+	})
+	if n > 0 {
+		return errDiffer
+	}
+	return nil
+}
+
+// compare call report for each field whose value differs in a and b. A missing field is nil.
+func compare(path string, a, b any, report func(path string, a, b any)) {
+	switch x := a.(type) {
+	case *schema.Struct:
+		y, ok := b.(*schema.Struct)
+		if !ok {
+			break
+		}
+		for _, k := range x.Keys() {
+			xv, _ := x.Get(k)
+			yv, _ := y.Get(k)
+			compare(join(path, k), xv, yv, report)
+		}
+		for _, k := range y.Keys() {
+			if _, ok := x.Get(k); !ok {
+				yv, _ := y.Get(k)
+				report(join(path, k), nil, yv)
+			}
+		}
+		return
+	case []any:
+		y, ok := b.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < max(len(x), len(y)); i++ {
+			var xv, yv any
+			if i < len(x) {
+				xv = x[i]
+			}
+			if i < len(y) {
+				yv = y[i]
+			}
+			compare(fmt.Sprintf("%s[%d]", path, i), xv, yv, report)
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		report(path, a, b)
+	}
+}
+
+func join(path, id string) string {
+	if "" == path {
+		return id
+	}
+	return path + "." + id
+}
+
+// format print a field value for diff.
+func format(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "(none)"
+	case []byte:
+		return hex.EncodeToString(v)
+	case string:
+		return fmt.Sprintf("%q", v)
+	case *schema.Struct, []any:
+		js, _ := json.Marshal(v)
+		return string(js)
+	}
+	return fmt.Sprint(v)
+}
+
+func hexdump(c *command, args []string) error {
+	files, err := c.parse(args, 1)
+	if err != nil {
+		return err
+	}
+	if "" == c.schemaFile {
+		data, err := os.ReadFile(files[0])
+		if err != nil {
+			return err
+		}
+		w := hex.Dumper(c.stdout)
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		return w.Close()
+	}
+	s, err := c.schema()
+	if err != nil {
+		return err
+	}
+	_, trace, err := c.decode(s, files[0])
+	if werr := trace.WriteHexdump(c.stdout); nil == err {
+		err = werr
+	}
+	return err
+}
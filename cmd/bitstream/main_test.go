@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	wavSchema = "../../schema/testdata/wav.ksy"
+	wavFile   = "../../schema/testdata/wav.bin"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		want       int
+		wantPrefix string
+		wantStderr string
+	}{
+		{
+			name: "TestRun_Dump",
+			args: []string{"dump", "-schema", wavSchema, wavFile},
+			wantPrefix: "" +
+				"00000000      4  riff_id: bytes 52494646\n" +
+				"00000004      4  file_size: u4 48\n" +
+				"00000008      4  wave_id: bytes 57415645\n" +
+				"0000000c     24  chunks[0]\n" +
+				"0000000c      4    id: str \"fmt \"\n",
+		},
+		{
+			name:       "TestRun_DumpJSON",
+			args:       []string{"dump", "--schema", wavSchema, "-json", wavFile},
+			wantPrefix: "{\n  \"riff_id\": \"UklGRg==\",\n  \"file_size\": 48,",
+		},
+		{
+			name:       "TestRun_Hexdump",
+			args:       []string{"hexdump", wavFile},
+			wantPrefix: "00000000  52 49 46 46 30 00 00 00  57 41 56 45 66 6d 74 20  |RIFF0...WAVEfmt |\n",
+		},
+		{
+			name:       "TestRun_HexdumpSchema",
+			args:       []string{"hexdump", "-schema", wavSchema, wavFile},
+			wantPrefix: "00000000  52 49 46 46                                      riff_id: bytes 52494646\n",
+		},
+		{name: "TestRun_Same", args: []string{"diff", "-schema", wavSchema, wavFile, wavFile}},
+		{name: "TestRun_NoCommand", args: nil, want: 2, wantStderr: "usage:"},
+		{name: "TestRun_UnknownCommand", args: []string{"cat"}, want: 2, wantStderr: "bitstream: unknown command"},
+		{name: "TestRun_MissingSchema", args: []string{"dump", wavFile}, want: 2, wantStderr: "bitstream dump: missing -schema"},
+		{name: "TestRun_Endian", args: []string{"dump", "-schema", wavSchema, "-endian", "middle", wavFile}, want: 2, wantStderr: "bitstream dump: unknown endian"},
+		{name: "TestRun_Arguments", args: []string{"diff", "-schema", wavSchema, wavFile}, want: 2, wantStderr: "bitstream diff: want 2 file arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := run(tt.args, &stdout, &stderr); got != tt.want {
+				t.Fatalf("run() = %v, want %v, stderr %s", got, tt.want, stderr.String())
+			}
+			if !strings.HasPrefix(stdout.String(), tt.wantPrefix) {
+				t.Errorf("run() stdout = %q, want prefix %q", stdout.String(), tt.wantPrefix)
+			}
+			if !strings.HasPrefix(stderr.String(), tt.wantStderr) {
+				t.Errorf("run() stderr = %q, want prefix %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestRun_EncodeDiff(t *testing.T) {
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"dump", "-schema", wavSchema, "-json", wavFile}, &stdout, &stderr); got != 0 {
+		t.Fatalf("dump = %v, stderr %s", got, stderr.String())
+	}
+	value := strings.Replace(stdout.String(), `"channels": 1`, `"channels": 2`, 1)
+	jsonFile := filepath.Join(dir, "wav.json")
+	if err := os.WriteFile(jsonFile, []byte(value), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	binFile := filepath.Join(dir, "wav.bin")
+	if got := run([]string{"encode", "-schema", wavSchema, "-o", binFile, jsonFile}, &stdout, &stderr); got != 0 {
+		t.Fatalf("encode = %v, stderr %s", got, stderr.String())
+	}
+	want, _ := os.ReadFile(wavFile)
+	want[0x16] = 2
+	if got, _ := os.ReadFile(binFile); !bytes.Equal(got, want) {
+		t.Errorf("encode wrote %x, want %x", got, want)
+	}
+
+	stdout.Reset()
+	if got := run([]string{"diff", "-schema", wavSchema, wavFile, binFile}, &stdout, &stderr); got != 1 {
+		t.Fatalf("diff = %v, want 1, stderr %s", got, stderr.String())
+	}
+	if want := "chunks[0].body.channels: 1 -> 2\n"; stdout.String() != want {
+		t.Errorf("diff = %q, want %q", stdout.String(), want)
+	}
+}
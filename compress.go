@@ -0,0 +1,218 @@
+package bitstream
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/lzw"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// ErrDecompressedTooLarge is reported when a compressed section decompresses to more bytes than the limit set
+// by WithDecompressLimit.
+var ErrDecompressedTooLarge = errors.New("bitstream: decompressed section too large")
+
+// Codec compresses and decompresses the sections written by BeginCompressed and read by ReadCompressed.
+type Codec interface {
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+type flateCodec struct{}
+
+func (flateCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(w, flate.DefaultCompression)
+}
+
+func (flateCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(r), nil
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type zlibCodec struct{}
+
+func (zlibCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriter(w), nil
+}
+
+func (zlibCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
+
+type lzwCodec struct {
+	order    lzw.Order
+	litWidth int
+}
+
+func (c lzwCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return lzw.NewWriter(w, c.order, c.litWidth), nil
+}
+
+func (c lzwCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return lzw.NewReader(r, c.order, c.litWidth), nil
+}
+
+var (
+	// Flate is the raw DEFLATE codec of compress/flate.
+	Flate Codec = flateCodec{}
+	// Gzip is the codec of compress/gzip.
+	Gzip Codec = gzipCodec{}
+	// Zlib is the codec of compress/zlib.
+	Zlib Codec = zlibCodec{}
+	// LZW is the codec of compress/lzw, MSB first with 8 bit literals.
+	LZW Codec = lzwCodec{order: lzw.MSB, litWidth: 8}
+)
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{
+		"flate": Flate,
+		"gzip":  Gzip,
+		"zlib":  Zlib,
+		"lzw":   LZW,
+	}
+)
+
+// RegisterCodec make a codec available by name. The built-in codecs are "flate", "gzip", "zlib" and "lzw".
+func RegisterCodec(name string, codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[name] = codec
+}
+
+// LookupCodec returns the codec registered under name.
+func LookupCodec(name string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	codec, ok := codecs[name]
+	return codec, ok
+}
+
+// BeginCompressed start a compressed section. The bytes written until the matching EndCompressed are compressed by codec.
+func (p *BOStream) BeginCompressed(codec Codec) *BOStream {
+	return p.catchError(func() {
+		buf := new(bytes.Buffer)
+		zw, err := codec.NewWriter(buf)
+		if err != nil {
+			p.err = err
+			return
+		}
+		p.pushRegion(region{kind: regionCompressed, buf: buf, closer: zw}, zw)
+	})
+}
+
+// EndCompressed end the compressed section and write the compressed bytes with a length prefix.
+func (p *BOStream) EndCompressed() *BOStream {
+	r, ok := p.popRegion(regionCompressed)
+	if !ok {
+		return p
+	}
+	return p.catchError(func() {
+		if p.err = r.closer.Close(); p.err != nil {
+			return
+		}
+		p.WriteBytesWithLengthPrefix(r.buf.Bytes())
+	})
+}
+
+// WithDecompressLimit limit to n bytes the decompressed size of the sections read by ReadCompressed, and of
+// the sections nested in them, so that a small section can not expand without bound. 0 means no limit.
+func (b *BIStream) WithDecompressLimit(n int64) *BIStream {
+	b.decompressLimit = n
+	return b
+}
+
+// ReadCompressed read a section written by BOStream.EndCompressed. Returns a BIStream over the decompressed
+// bytes of the section and an error if exists. Reading past the limit set by WithDecompressLimit is reported as
+// ErrDecompressedTooLarge. The decompressor is closed at the end of the section, or by Close.
+func (b *BIStream) ReadCompressed(codec Codec) (*BIStream, error) {
+	data, err := b.ReadBytesWithLengthPrefix()
+	if err != nil {
+		return nil, err
+	}
+	return b.Decompress(data, codec)
+}
+
+// Decompress returns a BIStream over data decompressed by codec, with the byte order and the limit set by
+// WithDecompressLimit of the stream, like ReadCompressed, and an error if exists
+func (b *BIStream) Decompress(data []byte, codec Codec) (*BIStream, error) {
+	zr, err := codec.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return NewBIStream(b.endian, &decompressor{zr: zr, limit: b.decompressLimit}).WithDecompressLimit(b.decompressLimit), nil
+}
+
+// DecompressLimit returns the limit set by WithDecompressLimit, 0 for none.
+func (b *BIStream) DecompressLimit() int64 {
+	return b.decompressLimit
+}
+
+// Close close the decompressor of a stream returned by ReadCompressed. It does not close the io.Reader of
+// other streams.
+func (b *BIStream) Close() error {
+	if d, ok := b.src.(*decompressor); ok {
+		return d.close()
+	}
+	return nil
+}
+
+// decompressor is the io.Reader of a compressed section. It fails past limit bytes when limit is not 0, and
+// closes zr once it returns an error or io.EOF.
+type decompressor struct {
+	zr    io.ReadCloser
+	limit int64
+	n     int64
+	err   error
+}
+
+func (d *decompressor) Read(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	if d.limit > 0 && int64(len(p)) > d.limit-d.n+1 {
+		// one byte past the limit tells a section of exactly limit bytes from a larger one
+		p = p[:d.limit-d.n+1]
+	}
+	n, err := d.zr.Read(p)
+	d.n += int64(n)
+	if d.limit > 0 && d.n > d.limit {
+		n -= int(d.n - d.limit)
+		d.n, err = d.limit, ErrDecompressedTooLarge
+	}
+	if err != nil {
+		d.err = err
+		if closeErr := d.zr.Close(); closeErr != nil && err == io.EOF {
+			d.err = closeErr
+		}
+	}
+	return n, d.err
+}
+
+func (d *decompressor) close() error {
+	if d.err != nil {
+		return nil
+	}
+	d.err = fs.ErrClosed
+	return d.zr.Close()
+}
+
+// FetchCompressed fetch a compressed section into value, see ReadCompressed.
+func (b *BIStream) FetchCompressed(value **BIStream, codec Codec) *BIStream {
+	return b.catchError(func() {
+		*value, b.err = b.ReadCompressed(codec)
+	})
+}
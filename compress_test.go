@@ -0,0 +1,146 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestBOStream_BeginCompressed(t *testing.T) {
+	tests := []struct {
+		name   string
+		endian binary.ByteOrder
+		codec  Codec
+		want   string
+	}{
+		{name: "TestBOStream_BeginCompressed_Flate", endian: binary.BigEndian, codec: Flate, want: strings.Repeat("golang", 100)},
+		{name: "TestBOStream_BeginCompressed_Gzip", endian: binary.LittleEndian, codec: Gzip, want: strings.Repeat("golang", 100)},
+		{name: "TestBOStream_BeginCompressed_Zlib", endian: binary.BigEndian, codec: Zlib, want: strings.Repeat("golang", 100)},
+		{name: "TestBOStream_BeginCompressed_LZW", endian: binary.LittleEndian, codec: LZW, want: strings.Repeat("golang", 100)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			w := NewBOStream(tt.endian, buf)
+			w.WriteUint16(1).BeginCompressed(tt.codec).WriteString(tt.want).WriteUint32(2).EndCompressed().WriteUint16(3)
+			if w.Error() != nil {
+				t.Fatalf("EndCompressed() has error %v", w.Error())
+			}
+			if buf.Len() >= len(tt.want) {
+				t.Errorf("EndCompressed() wrote %d bytes, want less than %d", buf.Len(), len(tt.want))
+			}
+
+			var (
+				head, tail uint16
+				section    *BIStream
+			)
+			r := NewBIStream(tt.endian, buf)
+			r.FetchUint16(&head).FetchCompressed(&section, tt.codec).FetchUint16(&tail)
+			if r.Error() != nil {
+				t.Fatalf("ReadCompressed() has error %v", r.Error())
+			}
+			var (
+				s   string
+				u32 uint32
+			)
+			section.FetchString(&s).FetchUint32(&u32)
+			if section.Error() != nil {
+				t.Fatalf("ReadCompressed() section has error %v", section.Error())
+			}
+			if head != 1 || s != tt.want || u32 != 2 || tail != 3 {
+				t.Errorf("ReadCompressed() = %v %v %v, want 1 %v 2 3", head, u32, tail, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupCodec(t *testing.T) {
+	for _, name := range []string{"flate", "gzip", "zlib", "lzw"} {
+		if _, ok := LookupCodec(name); !ok {
+			t.Errorf("LookupCodec(%q) not found", name)
+		}
+	}
+	RegisterCodec("test", Flate)
+	if codec, ok := LookupCodec("test"); !ok || codec != Flate {
+		t.Errorf("LookupCodec(%q) = %v, want %v", "test", codec, Flate)
+	}
+}
+
+// closeCounter is a Codec counting the readers it made that were closed.
+type closeCounter struct {
+	Codec
+	closed int
+}
+
+func (c *closeCounter) NewReader(r io.Reader) (io.ReadCloser, error) {
+	zr, err := c.Codec.NewReader(r)
+	return &countedCloser{zr, c}, err
+}
+
+type countedCloser struct {
+	io.ReadCloser
+	c *closeCounter
+}
+
+func (r *countedCloser) Close() error {
+	r.c.closed++
+	return r.ReadCloser.Close()
+}
+
+func TestBIStream_WithDecompressLimit(t *testing.T) {
+	text := strings.Repeat("golang", 100)
+	buf := new(bytes.Buffer)
+	NewBOStream(binary.BigEndian, buf).BeginCompressed(Flate).WriteBytes([]byte(text)).EndCompressed()
+	tests := []struct {
+		name      string
+		limit     int64
+		wantError error
+	}{
+		{name: "TestBIStream_WithDecompressLimit_None", limit: 0},
+		{name: "TestBIStream_WithDecompressLimit_Exact", limit: int64(len(text))},
+		{name: "TestBIStream_WithDecompressLimit_TooLarge", limit: int64(len(text)) - 1, wantError: ErrDecompressedTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec := &closeCounter{Codec: Flate}
+			section, err := NewBIStream(binary.BigEndian, bytes.NewReader(buf.Bytes())).WithDecompressLimit(tt.limit).ReadCompressed(codec)
+			if err != nil {
+				t.Fatalf("ReadCompressed() error = %v", err)
+			}
+			got, err := section.ReadAll()
+			if err != tt.wantError {
+				t.Errorf("ReadAll() error = %v, want %v", err, tt.wantError)
+			}
+			if nil == tt.wantError && string(got) != text {
+				t.Errorf("ReadAll() = %q, want %q", got, text)
+			}
+			if codec.closed != 1 {
+				t.Errorf("decompressor closed %d times, want 1", codec.closed)
+			}
+		})
+	}
+}
+
+func TestBIStream_Close(t *testing.T) {
+	buf := new(bytes.Buffer)
+	NewBOStream(binary.BigEndian, buf).BeginCompressed(Flate).WriteUint32(1).WriteUint32(2).EndCompressed()
+	codec := &closeCounter{Codec: Flate}
+	section, err := NewBIStream(binary.BigEndian, buf).ReadCompressed(codec)
+	if err != nil {
+		t.Fatalf("ReadCompressed() error = %v", err)
+	}
+	section.ReadUint32()
+	if err := section.Close(); err != nil || codec.closed != 1 {
+		t.Errorf("Close() = %v, closed %d times, want nil, 1", err, codec.closed)
+	}
+	if _, err := section.ReadUint32(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("ReadUint32() after Close() error = %v, want %v", err, fs.ErrClosed)
+	}
+	if err := section.Close(); err != nil || codec.closed != 1 {
+		t.Errorf("Close() again = %v, closed %d times, want nil, 1", err, codec.closed)
+	}
+}
@@ -0,0 +1,57 @@
+package bitstream
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Validate run check once the previous fields of the chain are read. A non nil error becomes the stream error
+// and stops the chain. check typically returns one of Min, Max, Range, OneOf or MaxLen:
+//
+//	reader.FetchUint8(&version).Validate(func() error { return bitstream.Max("version", version, 3) })
+func (b *BIStream) Validate(check func() error) *BIStream {
+	return b.catchError(func() {
+		b.err = check()
+	})
+}
+
+// Min returns a *ValidationError if value is less than min.
+func Min[T cmp.Ordered](field string, value, min T) error {
+	if value < min {
+		return &ValidationError{Field: field, Expected: min, Actual: value, Message: fmt.Sprintf("%v is less than the minimum %v", value, min)}
+	}
+	return nil
+}
+
+// Max returns a *ValidationError if value is greater than max.
+func Max[T cmp.Ordered](field string, value, max T) error {
+	if value > max {
+		return &ValidationError{Field: field, Expected: max, Actual: value, Message: fmt.Sprintf("%v is greater than the maximum %v", value, max)}
+	}
+	return nil
+}
+
+// Range returns a *ValidationError if value is not between min and max, both included.
+func Range[T cmp.Ordered](field string, value, min, max T) error {
+	if value < min || value > max {
+		return &ValidationError{Field: field, Expected: [2]T{min, max}, Actual: value, Message: fmt.Sprintf("%v is out of range [%v, %v]", value, min, max)}
+	}
+	return nil
+}
+
+// OneOf returns a *ValidationError if value is not one of allowed.
+func OneOf[T comparable](field string, value T, allowed ...T) error {
+	if !slices.Contains(allowed, value) {
+		return &ValidationError{Field: field, Expected: allowed, Actual: value, Message: fmt.Sprintf("%v is not one of %v", value, allowed)}
+	}
+	return nil
+}
+
+// MaxLen returns a *ValidationError if value is longer than max.
+func MaxLen[T ~string | ~[]byte](field string, value T, max int) error {
+	if len(value) > max {
+		return &ValidationError{Field: field, Expected: max, Actual: len(value), Message: fmt.Sprintf("length %d is greater than the maximum %d", len(value), max)}
+	}
+	return nil
+}
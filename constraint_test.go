@@ -0,0 +1,57 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestBIStream_Validate(t *testing.T) {
+	type args struct {
+		Version uint8
+		Count   uint16
+		Kind    uint8
+		Name    string
+	}
+	tests := []struct {
+		name      string
+		args      []byte
+		want      args
+		wantError string
+	}{
+		{name: "TestBIStream_Validate", args: []byte{3, 0, 10, 2, 4, 'n', 'a', 'm', 'e'}, want: args{Version: 3, Count: 10, Kind: 2, Name: "name"}},
+		{name: "TestBIStream_Validate_Max", args: []byte{4, 0, 10, 2, 4, 'n', 'a', 'm', 'e'}, want: args{Version: 4}, wantError: "bitstream: invalid version: 4 is greater than the maximum 3"},
+		{name: "TestBIStream_Validate_Range", args: []byte{3, 4, 1, 2, 4, 'n', 'a', 'm', 'e'}, want: args{Version: 3, Count: 1025}, wantError: "bitstream: invalid count: 1025 is out of range [1, 1024]"},
+		{name: "TestBIStream_Validate_OneOf", args: []byte{3, 0, 10, 3, 4, 'n', 'a', 'm', 'e'}, want: args{Version: 3, Count: 10, Kind: 3}, wantError: "bitstream: invalid kind: 3 is not one of [1 2]"},
+		{name: "TestBIStream_Validate_MaxLen", args: []byte{3, 0, 10, 2, 9, 'n', 'a', 'm', 'e', 'n', 'a', 'm', 'e', 's'}, want: args{Version: 3, Count: 10, Kind: 2, Name: "namenames"}, wantError: "bitstream: invalid name: length 9 is greater than the maximum 8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := args{}
+			p := NewBIStream(binary.BigEndian, bytes.NewReader(tt.args))
+			p.FetchUint8(&got.Version).Validate(func() error { return Max("version", got.Version, 3) }).
+				FetchUint16(&got.Count).Validate(func() error { return Range("count", got.Count, 1, 1024) }).
+				FetchUint8(&got.Kind).Validate(func() error { return OneOf("kind", got.Kind, 1, 2) }).
+				FetchString(&got.Name).Validate(func() error { return MaxLen("name", got.Name, 8) })
+			gotError := ""
+			if p.Error() != nil {
+				gotError = p.Error().Error()
+			}
+			if gotError != tt.wantError {
+				t.Errorf("Validate() error = %v, want %v", gotError, tt.wantError)
+			}
+			if got != tt.want {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMin(t *testing.T) {
+	if err := Min("size", 4, 8); nil == err || err.Error() != "bitstream: invalid size: 4 is less than the minimum 8" {
+		t.Errorf("Min() = %v", err)
+	}
+	if err := Min("size", 8, 8); err != nil {
+		t.Errorf("Min() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,164 @@
+package bitstream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// ContextError is reported by Error() when a stream was stopped by its context.
+type ContextError struct {
+	Err error
+}
+
+func (e *ContextError) Error() string {
+	return "bitstream: " + e.Err.Error()
+}
+
+func (e *ContextError) Unwrap() error {
+	return e.Err
+}
+
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// aLongTimeAgo is a deadline in the past, used to unblock a pending read or write.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// WithContext bind ctx to the stream. Cancellation is checked between fields, and when the
+// io.Reader supports SetReadDeadline (as net.Conn does) the ctx deadline is applied to every read, then cleared.
+// A deadline set on the io.Reader is kept while ctx has no deadline and is not done.
+func (b *BIStream) WithContext(ctx context.Context) *BIStream {
+	b.ctx = ctx
+	return b
+}
+
+func (b *BIStream) checkContext() error {
+	if nil == b.ctx {
+		return nil
+	}
+	if err := b.ctx.Err(); err != nil {
+		b.err = &ContextError{Err: err}
+	}
+	return b.err
+}
+
+func (b *BIStream) readReader(r io.Reader, buf []byte) (int, error) {
+	var n int
+	err := b.readContext(func() error {
+		var err error
+		n, err = io.ReadFull(r, buf)
+		return err
+	})
+	return n, err
+}
+
+// readContext run read, interrupted when the context of the stream is done.
+func (b *BIStream) readContext(read func() error) error {
+	if nil == b.ctx {
+		return read()
+	}
+	if err := b.checkContext(); err != nil {
+		return err
+	}
+	if d, ok := b.src.(readDeadliner); ok {
+		defer applyDeadline(b.ctx, d.SetReadDeadline)()
+	}
+	err := read()
+	if err != nil {
+		if ctxErr := contextError(b.ctx, err); ctxErr != nil {
+			b.err = ctxErr
+			return ctxErr
+		}
+	}
+	return err
+}
+
+// WithContext bind ctx to the stream. Cancellation is checked between fields, and when the
+// io.Writer supports SetWriteDeadline (as net.Conn does) the ctx deadline is applied to every write, then
+// cleared. A deadline set on the io.Writer is kept while ctx has no deadline and is not done.
+func (p *BOStream) WithContext(ctx context.Context) *BOStream {
+	p.ctx = ctx
+	return p
+}
+
+func (p *BOStream) checkContext() error {
+	if nil == p.ctx {
+		return nil
+	}
+	if err := p.ctx.Err(); err != nil {
+		p.err = &ContextError{Err: err}
+	}
+	return p.err
+}
+
+func (p *BOStream) write(buf []byte) error {
+	n, err := p.writeContext(buf)
+	if !p.buffered() {
+		p.written += int64(n)
+		if p.trace != nil {
+			p.trace.read(buf[:n])
+		}
+	}
+	return err
+}
+
+// writeContext write buf to io.Writer, interrupted when the context of the stream is done. Returns the number
+// of bytes written and an error if exists
+func (p *BOStream) writeContext(buf []byte) (int, error) {
+	if nil == p.ctx {
+		return p.writer.Write(buf)
+	}
+	if d, ok := p.sink().(writeDeadliner); ok {
+		defer applyDeadline(p.ctx, d.SetWriteDeadline)()
+	}
+	n, err := p.writer.Write(buf)
+	if err != nil {
+		if ctxErr := contextError(p.ctx, err); ctxErr != nil {
+			return n, ctxErr
+		}
+	}
+	return n, err
+}
+
+// applyDeadline set the deadline of ctx, if it has one, and a deadline in the past when ctx is done, to
+// interrupt an operation. Returns a function clearing the deadline once the operation returns, if it was set,
+// so that a deadline set by the caller is kept while ctx has none and is done.
+func applyDeadline(ctx context.Context, set func(t time.Time) error) (restore func()) {
+	deadline, ok := ctx.Deadline()
+	if ok {
+		_ = set(deadline)
+	}
+	done := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		_ = set(aLongTimeAgo)
+		close(done)
+	})
+	return func() {
+		if !stop() {
+			<-done
+			ok = true
+		}
+		if ok {
+			_ = set(time.Time{})
+		}
+	}
+}
+
+// contextError translate an io error caused by ctx into a *ContextError.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return &ContextError{Err: ctxErr}
+	}
+	if deadline, ok := ctx.Deadline(); ok && errors.Is(err, os.ErrDeadlineExceeded) && !time.Now().Before(deadline) {
+		return &ContextError{Err: context.DeadlineExceeded}
+	}
+	return nil
+}
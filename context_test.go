@@ -0,0 +1,188 @@
+package bitstream
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBIStream_WithContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		args      []byte
+		want      uint16
+		wantError error
+	}{
+		{name: "TestBIStream_WithContext_Background", ctx: context.Background(), args: []byte{0, 1}, want: 1},
+		{name: "TestBIStream_WithContext_Canceled", ctx: canceled, args: []byte{0, 1}, wantError: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data uint16
+			p := NewBIStream(binary.BigEndian, bytes.NewBuffer(tt.args)).WithContext(tt.ctx)
+			p.FetchUint16(&data)
+			if !errors.Is(p.Error(), tt.wantError) {
+				t.Errorf("FetchUint16() error = %v, want %v", p.Error(), tt.wantError)
+			}
+			if data != tt.want {
+				t.Errorf("FetchUint16() = %v, want %v", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestBIStream_WithContext_Deadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var data uint32
+	p := NewBIStream(binary.BigEndian, client).WithContext(ctx)
+	p.FetchUint32(&data)
+	var ctxErr *ContextError
+	if !errors.As(p.Error(), &ctxErr) || !errors.Is(p.Error(), context.DeadlineExceeded) {
+		t.Errorf("FetchUint32() error = %v, want %v", p.Error(), context.DeadlineExceeded)
+	}
+}
+
+func TestBIStream_WithContext_Cancel(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := NewBIStream(binary.BigEndian, client).WithContext(ctx).ReadUint64()
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadUint64() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestBOStream_WithContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		want      []byte
+		wantError error
+	}{
+		{name: "TestBOStream_WithContext_Background", ctx: context.Background(), want: []byte{0, 1}},
+		{name: "TestBOStream_WithContext_Canceled", ctx: canceled, want: nil, wantError: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			p := NewBOStream(binary.BigEndian, buf).WithContext(tt.ctx)
+			p.WriteUint16(1)
+			if !errors.Is(p.Error(), tt.wantError) {
+				t.Errorf("WriteUint16() error = %v, want %v", p.Error(), tt.wantError)
+			}
+			if !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("WriteUint16() = %v, want %v", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBOStream_WithContext_Deadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	p := NewBOStream(binary.BigEndian, client).WithContext(ctx)
+	p.WriteUint32(1)
+	if !errors.Is(p.Error(), context.DeadlineExceeded) {
+		t.Errorf("WriteUint32() error = %v, want %v", p.Error(), context.DeadlineExceeded)
+	}
+}
+
+// deadlines is an io.ReadWriter recording the deadlines set on it.
+type deadlines struct {
+	bytes.Buffer
+	set []time.Time
+}
+
+func (d *deadlines) SetReadDeadline(t time.Time) error {
+	d.set = append(d.set, t)
+	return nil
+}
+
+func (d *deadlines) SetWriteDeadline(t time.Time) error {
+	d.set = append(d.set, t)
+	return nil
+}
+
+func TestWithContext_Deadlines(t *testing.T) {
+	at := time.Now().Add(time.Hour)
+	withDeadline, cancel := context.WithDeadline(context.Background(), at)
+	defer cancel()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want []time.Time
+	}{
+		{name: "TestWithContext_Deadlines_None", ctx: context.Background(), want: nil},
+		{name: "TestWithContext_Deadlines_Set", ctx: withDeadline, want: []time.Time{at, {}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := new(deadlines)
+			d.Write([]byte{0, 1, 2})
+			b := NewBIStream(binary.BigEndian, d).WithContext(tt.ctx)
+			b.ReadUint16()
+			if !reflect.DeepEqual(d.set, tt.want) {
+				t.Errorf("ReadUint16() deadlines = %v, want %v", d.set, tt.want)
+			}
+			d.set = nil
+			if rest, err := b.ReadAll(); err != nil || !bytes.Equal(rest, []byte{2}) || !reflect.DeepEqual(d.set, tt.want) {
+				t.Errorf("ReadAll() = %v, %v, deadlines = %v, want [2], nil, %v", rest, err, d.set, tt.want)
+			}
+			d.set = nil
+			NewBOStream(binary.BigEndian, d).WithContext(tt.ctx).WriteUint16(1)
+			if !reflect.DeepEqual(d.set, tt.want) {
+				t.Errorf("WriteUint16() deadlines = %v, want %v", d.set, tt.want)
+			}
+		})
+	}
+}
+
+func TestBIStream_WithContext_ReadAllDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := NewBIStream(binary.BigEndian, client).WithContext(ctx).ReadAll(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ReadAll() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestBIStream_WithContext_CancelRestore(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := NewBIStream(binary.BigEndian, client).WithContext(ctx).ReadUint8(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ReadUint8() error = %v, want %v", err, context.Canceled)
+	}
+	go server.Write([]byte{7})
+	if v, err := NewBIStream(binary.BigEndian, client).ReadUint8(); err != nil || v != 7 {
+		t.Errorf("ReadUint8() after cancel = %v, %v, want 7, nil", v, err)
+	}
+}
@@ -0,0 +1,192 @@
+package bitstream
+
+import (
+	"hash"
+	"math/bits"
+)
+
+// Hash16 is the common interface implemented by all 16-bit hash functions.
+type Hash16 interface {
+	hash.Hash
+	Sum16() uint16
+}
+
+// Hash8 is the common interface implemented by all 8-bit hash functions.
+type Hash8 interface {
+	hash.Hash
+	Sum8() uint8
+}
+
+// CRC16Params describe a CRC-16 variant.
+type CRC16Params struct {
+	Poly   uint16
+	Init   uint16
+	RefIn  bool
+	RefOut bool
+	XorOut uint16
+}
+
+// CRC8Params describe a CRC-8 variant.
+type CRC8Params struct {
+	Poly   uint8
+	Init   uint8
+	RefIn  bool
+	RefOut bool
+	XorOut uint8
+}
+
+var (
+	// CRC16CCITT is CRC-16/CCITT-FALSE.
+	CRC16CCITT = CRC16Params{Poly: 0x1021, Init: 0xffff}
+	// CRC16Modbus is CRC-16/MODBUS.
+	CRC16Modbus = CRC16Params{Poly: 0x8005, Init: 0xffff, RefIn: true, RefOut: true}
+	// CRC16IBM is CRC-16/ARC, also known as CRC-16/IBM.
+	CRC16IBM = CRC16Params{Poly: 0x8005, RefIn: true, RefOut: true}
+
+	// CRC8 is CRC-8/SMBUS.
+	CRC8 = CRC8Params{Poly: 0x07}
+	// CRC8Maxim is CRC-8/MAXIM used by 1-Wire devices.
+	CRC8Maxim = CRC8Params{Poly: 0x31, RefIn: true, RefOut: true}
+)
+
+type crc16 struct {
+	params CRC16Params
+	table  [256]uint16
+	crc    uint16
+}
+
+// NewCRC16 creates a new Hash16 computing the CRC-16 checksum described by params.
+func NewCRC16(params CRC16Params) Hash16 {
+	c := &crc16{params: params}
+	for i := range c.table {
+		if params.RefIn {
+			crc := uint16(i)
+			poly := bits.Reverse16(params.Poly)
+			for j := 0; j < 8; j++ {
+				if crc&1 == 1 {
+					crc = crc>>1 ^ poly
+				} else {
+					crc >>= 1
+				}
+			}
+			c.table[i] = crc
+		} else {
+			crc := uint16(i) << 8
+			for j := 0; j < 8; j++ {
+				if crc&0x8000 != 0 {
+					crc = crc<<1 ^ params.Poly
+				} else {
+					crc <<= 1
+				}
+			}
+			c.table[i] = crc
+		}
+	}
+	c.Reset()
+	return c
+}
+
+func (c *crc16) Reset() {
+	c.crc = c.params.Init
+	if c.params.RefIn {
+		c.crc = bits.Reverse16(c.crc)
+	}
+}
+
+func (c *crc16) Size() int { return 2 }
+
+func (c *crc16) BlockSize() int { return 1 }
+
+func (c *crc16) Write(p []byte) (int, error) {
+	crc := c.crc
+	for _, b := range p {
+		if c.params.RefIn {
+			crc = crc>>8 ^ c.table[byte(crc)^b]
+		} else {
+			crc = crc<<8 ^ c.table[byte(crc>>8)^b]
+		}
+	}
+	c.crc = crc
+	return len(p), nil
+}
+
+func (c *crc16) Sum16() uint16 {
+	crc := c.crc
+	if c.params.RefIn != c.params.RefOut {
+		crc = bits.Reverse16(crc)
+	}
+	return crc ^ c.params.XorOut
+}
+
+func (c *crc16) Sum(in []byte) []byte {
+	s := c.Sum16()
+	return append(in, byte(s>>8), byte(s))
+}
+
+type crc8 struct {
+	params CRC8Params
+	table  [256]uint8
+	crc    uint8
+}
+
+// NewCRC8 creates a new Hash8 computing the CRC-8 checksum described by params.
+func NewCRC8(params CRC8Params) Hash8 {
+	c := &crc8{params: params}
+	poly := params.Poly
+	if params.RefIn {
+		poly = bits.Reverse8(poly)
+	}
+	for i := range c.table {
+		crc := uint8(i)
+		for j := 0; j < 8; j++ {
+			if params.RefIn {
+				if crc&1 == 1 {
+					crc = crc>>1 ^ poly
+				} else {
+					crc >>= 1
+				}
+			} else {
+				if crc&0x80 != 0 {
+					crc = crc<<1 ^ poly
+				} else {
+					crc <<= 1
+				}
+			}
+		}
+		c.table[i] = crc
+	}
+	c.Reset()
+	return c
+}
+
+func (c *crc8) Reset() {
+	c.crc = c.params.Init
+	if c.params.RefIn {
+		c.crc = bits.Reverse8(c.crc)
+	}
+}
+
+func (c *crc8) Size() int { return 1 }
+
+func (c *crc8) BlockSize() int { return 1 }
+
+func (c *crc8) Write(p []byte) (int, error) {
+	crc := c.crc
+	for _, b := range p {
+		crc = c.table[crc^b]
+	}
+	c.crc = crc
+	return len(p), nil
+}
+
+func (c *crc8) Sum8() uint8 {
+	crc := c.crc
+	if c.params.RefIn != c.params.RefOut {
+		crc = bits.Reverse8(crc)
+	}
+	return crc ^ c.params.XorOut
+}
+
+func (c *crc8) Sum(in []byte) []byte {
+	return append(in, c.Sum8())
+}
@@ -0,0 +1,54 @@
+package bitstream
+
+import (
+	"testing"
+)
+
+func TestNewCRC16(t *testing.T) {
+	tests := []struct {
+		name   string
+		params CRC16Params
+		args   []byte
+		want   uint16
+	}{
+		{name: "TestNewCRC16_CCITT", params: CRC16CCITT, args: []byte("123456789"), want: 0x29b1},
+		{name: "TestNewCRC16_Modbus", params: CRC16Modbus, args: []byte("123456789"), want: 0x4b37},
+		{name: "TestNewCRC16_IBM", params: CRC16IBM, args: []byte("123456789"), want: 0xbb3d},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewCRC16(tt.params)
+			h.Write(tt.args)
+			if h.Sum16() != tt.want {
+				t.Errorf("Sum16() = %#x, want %#x", h.Sum16(), tt.want)
+			}
+			h.Reset()
+			h.Write(tt.args[:4])
+			h.Write(tt.args[4:])
+			if h.Sum16() != tt.want {
+				t.Errorf("Sum16() after Reset() = %#x, want %#x", h.Sum16(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCRC8(t *testing.T) {
+	tests := []struct {
+		name   string
+		params CRC8Params
+		args   []byte
+		want   uint8
+	}{
+		{name: "TestNewCRC8_SMBus", params: CRC8, args: []byte("123456789"), want: 0xf4},
+		{name: "TestNewCRC8_Maxim", params: CRC8Maxim, args: []byte("123456789"), want: 0xa1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewCRC8(tt.params)
+			h.Write(tt.args)
+			if h.Sum8() != tt.want {
+				t.Errorf("Sum8() = %#x, want %#x", h.Sum8(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package bitstream
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+)
+
+// ValidationError is reported when a value read from the stream is not the expected one.
+type ValidationError struct {
+	Field    string
+	Expected any
+	Actual   any
+	Message  string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("bitstream: invalid %s: %s", e.Field, e.Message)
+	}
+	if _, ok := e.Actual.([]byte); ok {
+		return fmt.Sprintf("bitstream: invalid %s: expected % x, got % x", e.Field, e.Expected, e.Actual)
+	}
+	return fmt.Sprintf("bitstream: invalid %s: expected %v, got %v", e.Field, e.Expected, e.Actual)
+}
+
+// ExpectBytes read len(magic) bytes and check they equal magic.
+func (b *BIStream) ExpectBytes(magic []byte) *BIStream {
+	return b.catchError(func() {
+		var buf []byte
+		if buf, b.err = b.ReadBytes(uint64(len(magic))); nil == b.err && !bytes.Equal(buf, magic) {
+			b.err = &ValidationError{Field: "magic", Expected: magic, Actual: buf}
+		}
+	})
+}
+
+// ExpectUint8 read 1 byte and check it equals value.
+func (b *BIStream) ExpectUint8(value uint8) *BIStream {
+	return b.catchError(func() {
+		var v uint8
+		if v, b.err = b.ReadUint8(); nil == b.err && v != value {
+			b.err = &ValidationError{Field: "uint8", Expected: value, Actual: v}
+		}
+	})
+}
+
+// ExpectUint16 read 2 byte and check they equal value.
+func (b *BIStream) ExpectUint16(value uint16) *BIStream {
+	return b.catchError(func() {
+		var v uint16
+		if v, b.err = b.ReadUint16(); nil == b.err && v != value {
+			b.err = &ValidationError{Field: "uint16", Expected: value, Actual: v}
+		}
+	})
+}
+
+// ExpectUint32 read 4 byte and check they equal value.
+func (b *BIStream) ExpectUint32(value uint32) *BIStream {
+	return b.catchError(func() {
+		var v uint32
+		if v, b.err = b.ReadUint32(); nil == b.err && v != value {
+			b.err = &ValidationError{Field: "uint32", Expected: value, Actual: v}
+		}
+	})
+}
+
+// ExpectUint64 read 8 byte and check they equal value.
+func (b *BIStream) ExpectUint64(value uint64) *BIStream {
+	return b.catchError(func() {
+		var v uint64
+		if v, b.err = b.ReadUint64(); nil == b.err && v != value {
+			b.err = &ValidationError{Field: "uint64", Expected: value, Actual: v}
+		}
+	})
+}
+
+// ExpectZero read n reserved bytes and check they are all zero.
+func (b *BIStream) ExpectZero(n uint64) *BIStream {
+	return b.catchError(func() {
+		var buf []byte
+		if buf, b.err = b.ReadBytes(n); nil == b.err && slices.ContainsFunc(buf, func(c byte) bool { return c != 0 }) {
+			b.err = &ValidationError{Field: "reserved", Expected: make([]byte, n), Actual: buf}
+		}
+	})
+}
+
+// ReadEnum read 1 byte and check it is one of allowed. Returns the byte and an error if exists
+func (b *BIStream) ReadEnum(allowed ...uint8) (uint8, error) {
+	v, err := b.ReadUint8()
+	if nil == err && !slices.Contains(allowed, v) {
+		err = &ValidationError{Field: "enum", Expected: allowed, Actual: v}
+	}
+	return v, err
+}
+
+// FetchEnum fetch 1 byte that must be one of allowed.
+func (b *BIStream) FetchEnum(value *uint8, allowed ...uint8) *BIStream {
+	return b.catchError(func() {
+		*value, b.err = b.ReadEnum(allowed...)
+	})
+}
+
+// WriteMagic write the magic bytes in io.Writer.
+func (p *BOStream) WriteMagic(magic []byte) *BOStream {
+	return p.WriteBytes(magic)
+}
+
+// WriteReserved write n zero bytes in io.Writer.
+func (p *BOStream) WriteReserved(n uint64) *BOStream {
+	return p.WriteBytes(make([]byte, n))
+}
@@ -0,0 +1,99 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBIStream_Expect(t *testing.T) {
+	tests := []struct {
+		name      string
+		endian    binary.ByteOrder
+		args      []byte
+		expect    func(p *BIStream) *BIStream
+		wantError error
+	}{
+		{name: "TestBIStream_ExpectBytes", endian: binary.BigEndian, args: []byte("RIFF"), expect: func(p *BIStream) *BIStream { return p.ExpectBytes([]byte("RIFF")) }},
+		{name: "TestBIStream_ExpectBytes_Mismatch", endian: binary.BigEndian, args: []byte("RIFX"), expect: func(p *BIStream) *BIStream { return p.ExpectBytes([]byte("RIFF")) },
+			wantError: &ValidationError{Field: "magic", Expected: []byte("RIFF"), Actual: []byte("RIFX")}},
+		{name: "TestBIStream_ExpectUint8", endian: binary.BigEndian, args: []byte{7}, expect: func(p *BIStream) *BIStream { return p.ExpectUint8(7) }},
+		{name: "TestBIStream_ExpectUint16_LittleEndian", endian: binary.LittleEndian, args: []byte{0x34, 0x12}, expect: func(p *BIStream) *BIStream { return p.ExpectUint16(0x1234) }},
+		{name: "TestBIStream_ExpectUint16_Mismatch", endian: binary.BigEndian, args: []byte{0x34, 0x12}, expect: func(p *BIStream) *BIStream { return p.ExpectUint16(0x1234) },
+			wantError: &ValidationError{Field: "uint16", Expected: uint16(0x1234), Actual: uint16(0x3412)}},
+		{name: "TestBIStream_ExpectUint32", endian: binary.BigEndian, args: []byte{0xca, 0xfe, 0xba, 0xbe}, expect: func(p *BIStream) *BIStream { return p.ExpectUint32(0xcafebabe) }},
+		{name: "TestBIStream_ExpectUint64", endian: binary.BigEndian, args: []byte{0, 0, 0, 0, 0, 0, 0, 1}, expect: func(p *BIStream) *BIStream { return p.ExpectUint64(1) }},
+		{name: "TestBIStream_ExpectZero", endian: binary.BigEndian, args: []byte{0, 0, 0}, expect: func(p *BIStream) *BIStream { return p.ExpectZero(3) }},
+		{name: "TestBIStream_ExpectZero_Mismatch", endian: binary.BigEndian, args: []byte{0, 1, 0}, expect: func(p *BIStream) *BIStream { return p.ExpectZero(3) },
+			wantError: &ValidationError{Field: "reserved", Expected: []byte{0, 0, 0}, Actual: []byte{0, 1, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewBIStream(tt.endian, bytes.NewReader(tt.args))
+			err := tt.expect(p).Error()
+			if !reflect.DeepEqual(err, tt.wantError) {
+				t.Errorf("Expect() error = %v, want %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestBIStream_ReadEnum(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []byte
+		allowed   []uint8
+		want      uint8
+		wantError error
+	}{
+		{name: "TestBIStream_ReadEnum", args: []byte{2}, allowed: []uint8{1, 2, 4}, want: 2},
+		{name: "TestBIStream_ReadEnum_Invalid", args: []byte{3}, allowed: []uint8{1, 2, 4}, want: 3, wantError: &ValidationError{Field: "enum", Expected: []uint8{1, 2, 4}, Actual: uint8(3)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v uint8
+			p := NewBIStream(binary.BigEndian, bytes.NewReader(tt.args))
+			p.FetchEnum(&v, tt.allowed...)
+			if !reflect.DeepEqual(p.Error(), tt.wantError) {
+				t.Errorf("ReadEnum() error = %v, want %v", p.Error(), tt.wantError)
+			}
+			if v != tt.want {
+				t.Errorf("ReadEnum() = %v, want %v", v, tt.want)
+			}
+			var vErr *ValidationError
+			if tt.wantError != nil && !errors.As(p.Error(), &vErr) {
+				t.Errorf("ReadEnum() error is not a *ValidationError")
+			}
+		})
+	}
+}
+
+func TestValidationError_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *ValidationError
+		want string
+	}{
+		{name: "TestValidationError_Error_Bytes", err: &ValidationError{Field: "magic", Expected: []byte("RIFF"), Actual: []byte("RIFX")}, want: "bitstream: invalid magic: expected 52 49 46 46, got 52 49 46 58"},
+		{name: "TestValidationError_Error_Uint16", err: &ValidationError{Field: "uint16", Expected: uint16(1), Actual: uint16(2)}, want: "bitstream: invalid uint16: expected 1, got 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Error() != tt.want {
+				t.Errorf("Error() = %v, want %v", tt.err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBOStream_WriteMagic(t *testing.T) {
+	buf := new(bytes.Buffer)
+	p := NewBOStream(binary.BigEndian, buf)
+	p.WriteMagic([]byte("RIFF")).WriteReserved(3).WriteByte(1)
+	want := []byte{'R', 'I', 'F', 'F', 0, 0, 0, 1}
+	if p.Error() != nil || !reflect.DeepEqual(buf.Bytes(), want) {
+		t.Errorf("WriteMagic() = %v, want %v", buf.Bytes(), want)
+	}
+}
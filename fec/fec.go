@@ -0,0 +1,193 @@
+// Package fec adds Reed-Solomon forward error correction under a BOStream or BIStream.
+//
+// The Writer groups the stream into blocks of Depth codewords. Each codeword carries DataSize data bytes and
+// ParitySize parity bytes, and the codewords of a block are interleaved byte by byte, so a burst of up to
+// Depth*ParitySize/2 corrupted bytes is still corrected. The first two data bytes of every block hold the
+// big-endian count of stream bytes in the block.
+//
+//	w := fec.NewWriter(conn, fec.Config{DataSize: 223, ParitySize: 32, Depth: 4})
+//	bitstream.NewBOStream(binary.BigEndian, w).WriteUint32(1)
+//	w.Close()
+//
+//	r := fec.NewReader(conn, fec.Config{DataSize: 223, ParitySize: 32, Depth: 4})
+//	val, err := bitstream.NewBIStream(binary.BigEndian, r).ReadUint32()
+//	corrected := r.Corrected()
+package fec
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrConfig is returned for a Config that can not describe a block.
+var ErrConfig = errors.New("fec: invalid config")
+
+// ErrBlockLength is returned when a block claims more bytes than it can carry.
+var ErrBlockLength = errors.New("fec: invalid block length")
+
+// Config describe the codewords and the interleaving of a stream. Both ends must use the same Config.
+type Config struct {
+	// DataSize is the number of data bytes per codeword.
+	DataSize int
+	// ParitySize is the number of parity bytes per codeword. Up to ParitySize/2 bad bytes per codeword are corrected.
+	ParitySize int
+	// Depth is the number of interleaved codewords per block. 0 means 1.
+	Depth int
+}
+
+func (c Config) validate() (Config, error) {
+	if 0 == c.Depth {
+		c.Depth = 1
+	}
+	if c.DataSize <= 0 || c.ParitySize <= 0 || c.Depth < 0 || c.DataSize+c.ParitySize > 255 {
+		return c, ErrConfig
+	}
+	if c.capacity() <= 0 || c.capacity() > 0xffff {
+		return c, ErrConfig
+	}
+	return c, nil
+}
+
+// capacity returns the number of stream bytes per block.
+func (c Config) capacity() int {
+	return c.DataSize*c.Depth - 2
+}
+
+// Writer encodes the bytes written to it into interleaved Reed-Solomon codewords.
+type Writer struct {
+	w      io.Writer
+	config Config
+	rs     *rsCodec
+	data   []byte
+	n      int
+	block  []byte
+	parity []byte
+	err    error
+}
+
+// NewWriter returns a Writer encoding to w. A partial block is only written by Flush or Close.
+func NewWriter(w io.Writer, config Config) *Writer {
+	config, err := config.validate()
+	fw := &Writer{w: w, config: config, err: err}
+	if nil == err {
+		fw.rs = newRSCodec(config.ParitySize)
+		fw.data = make([]byte, config.DataSize*config.Depth)
+		fw.block = make([]byte, (config.DataSize+config.ParitySize)*config.Depth)
+		fw.parity = make([]byte, config.ParitySize)
+	}
+	return fw
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 && nil == w.err {
+		k := copy(w.data[2+w.n:], p)
+		w.n += k
+		n += k
+		p = p[k:]
+		if w.n == w.config.capacity() {
+			w.err = w.writeBlock()
+		}
+	}
+	return n, w.err
+}
+
+// Flush write the pending bytes as a partial block.
+func (w *Writer) Flush() error {
+	if nil == w.err && w.n > 0 {
+		w.err = w.writeBlock()
+	}
+	return w.err
+}
+
+// Close flush the pending bytes. It does not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	return w.Flush()
+}
+
+func (w *Writer) writeBlock() error {
+	c := w.config
+	binary.BigEndian.PutUint16(w.data, uint16(w.n))
+	clear(w.data[2+w.n:])
+	for j := 0; j < c.Depth; j++ {
+		msg := w.data[j*c.DataSize : (j+1)*c.DataSize]
+		w.rs.encode(msg, w.parity)
+		for i, v := range msg {
+			w.block[i*c.Depth+j] = v
+		}
+		for i, v := range w.parity {
+			w.block[(c.DataSize+i)*c.Depth+j] = v
+		}
+	}
+	w.n = 0
+	_, err := w.w.Write(w.block)
+	return err
+}
+
+// Reader decodes the blocks written by a Writer, correcting errors up to the parity limit.
+type Reader struct {
+	r         io.Reader
+	config    Config
+	rs        *rsCodec
+	block     []byte
+	cw        []byte
+	data      []byte
+	buf       []byte
+	corrected int
+	err       error
+}
+
+// NewReader returns a Reader decoding from r.
+func NewReader(r io.Reader, config Config) *Reader {
+	config, err := config.validate()
+	fr := &Reader{r: r, config: config, err: err}
+	if nil == err {
+		fr.rs = newRSCodec(config.ParitySize)
+		fr.block = make([]byte, (config.DataSize+config.ParitySize)*config.Depth)
+		fr.cw = make([]byte, config.DataSize+config.ParitySize)
+		fr.data = make([]byte, config.DataSize*config.Depth)
+	}
+	return fr
+}
+
+// Corrected returns the number of symbols corrected so far.
+func (r *Reader) Corrected() int {
+	return r.corrected
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	for 0 == len(r.buf) {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.readBlock()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *Reader) readBlock() error {
+	if _, err := io.ReadFull(r.r, r.block); err != nil {
+		return err
+	}
+	c := r.config
+	for j := 0; j < c.Depth; j++ {
+		for i := range r.cw {
+			r.cw[i] = r.block[i*c.Depth+j]
+		}
+		n, err := r.rs.decode(r.cw)
+		if err != nil {
+			return err
+		}
+		r.corrected += n
+		copy(r.data[j*c.DataSize:], r.cw[:c.DataSize])
+	}
+	n := int(binary.BigEndian.Uint16(r.data))
+	if n > c.capacity() {
+		return ErrBlockLength
+	}
+	r.buf = r.data[2 : 2+n]
+	return nil
+}
@@ -0,0 +1,69 @@
+package fec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+func TestWriter_Reader(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        Config
+		corrupt       []int
+		wantCorrected int
+		wantError     error
+	}{
+		{name: "TestWriter_Reader_Clean", config: Config{DataSize: 16, ParitySize: 4, Depth: 1}},
+		{name: "TestWriter_Reader_Corrected", config: Config{DataSize: 16, ParitySize: 4, Depth: 1}, corrupt: []int{0, 5, 20, 39}, wantCorrected: 4},
+		{name: "TestWriter_Reader_Burst", config: Config{DataSize: 16, ParitySize: 4, Depth: 4}, corrupt: []int{10, 11, 12, 13, 14, 15, 16, 17}, wantCorrected: 8},
+		{name: "TestWriter_Reader_Uncorrectable", config: Config{DataSize: 16, ParitySize: 4, Depth: 1}, corrupt: []int{0, 1, 2}, wantError: ErrUncorrectable},
+		{name: "TestWriter_Reader_Config", config: Config{DataSize: 250, ParitySize: 10}, wantError: ErrConfig},
+	}
+	type args struct {
+		U16 uint16
+		S   string
+		F64 float64
+	}
+	want := args{U16: 0xbeef, S: "telemetry over a lossy radio link", F64: 1.5}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			fw := NewWriter(buf, tt.config)
+			w := bitstream.NewBOStream(binary.BigEndian, fw)
+			w.WriteUint16(want.U16).WriteString(want.S).WriteFloat64(want.F64)
+			fw.Close()
+			if w.Error() != nil && w.Error() != tt.wantError {
+				t.Fatalf("Write() has error %v", w.Error())
+			}
+			data := buf.Bytes()
+			for _, i := range tt.corrupt {
+				data[i] ^= 0xa5
+			}
+
+			fr := NewReader(bytes.NewReader(data), tt.config)
+			r := bitstream.NewBIStream(binary.BigEndian, fr)
+			got := args{}
+			r.FetchUint16(&got.U16).FetchString(&got.S).FetchFloat64(&got.F64)
+			if r.Error() != tt.wantError {
+				t.Fatalf("Read() error = %v, want %v", r.Error(), tt.wantError)
+			}
+			if tt.wantError != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Read() = %v, want %v", got, want)
+			}
+			if fr.Corrected() != tt.wantCorrected {
+				t.Errorf("Corrected() = %v, want %v", fr.Corrected(), tt.wantCorrected)
+			}
+			if _, err := r.ReadByte(); err != io.EOF {
+				t.Errorf("ReadByte() at end error = %v, want %v", err, io.EOF)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package fec
+
+// Arithmetic in GF(2^8) with the primitive polynomial x^8 + x^4 + x^3 + x^2 + 1 (0x11d) and generator 2.
+// Polynomials are byte slices with the highest degree coefficient first.
+
+var (
+	gfExp [512]byte
+	gfLog [256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < 512; i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMul(x, y byte) byte {
+	if 0 == x || 0 == y {
+		return 0
+	}
+	return gfExp[int(gfLog[x])+int(gfLog[y])]
+}
+
+func gfDiv(x, y byte) byte {
+	if 0 == x {
+		return 0
+	}
+	return gfExp[(int(gfLog[x])+255-int(gfLog[y]))%255]
+}
+
+func gfPow(x byte, power int) byte {
+	return gfExp[((int(gfLog[x])*power)%255+255)%255]
+}
+
+func gfInverse(x byte) byte {
+	return gfExp[255-int(gfLog[x])]
+}
+
+func gfPolyScale(p []byte, x byte) []byte {
+	r := make([]byte, len(p))
+	for i, c := range p {
+		r[i] = gfMul(c, x)
+	}
+	return r
+}
+
+func gfPolyAdd(p, q []byte) []byte {
+	r := make([]byte, max(len(p), len(q)))
+	copy(r[len(r)-len(p):], p)
+	for i, c := range q {
+		r[i+len(r)-len(q)] ^= c
+	}
+	return r
+}
+
+func gfPolyMul(p, q []byte) []byte {
+	r := make([]byte, len(p)+len(q)-1)
+	for j, qc := range q {
+		for i, pc := range p {
+			r[i+j] ^= gfMul(pc, qc)
+		}
+	}
+	return r
+}
+
+func gfPolyEval(p []byte, x byte) byte {
+	y := p[0]
+	for _, c := range p[1:] {
+		y = gfMul(y, x) ^ c
+	}
+	return y
+}
+
+// gfPolyDiv divide by a monic divisor and return the quotient and the remainder.
+func gfPolyDiv(dividend, divisor []byte) ([]byte, []byte) {
+	out := append([]byte(nil), dividend...)
+	for i := 0; i < len(dividend)-(len(divisor)-1); i++ {
+		coef := out[i]
+		if coef != 0 {
+			for j := 1; j < len(divisor); j++ {
+				if divisor[j] != 0 {
+					out[i+j] ^= gfMul(divisor[j], coef)
+				}
+			}
+		}
+	}
+	sep := len(out) - (len(divisor) - 1)
+	return out[:sep], out[sep:]
+}
+
+func reversed(p []byte) []byte {
+	r := make([]byte, len(p))
+	for i, c := range p {
+		r[len(p)-1-i] = c
+	}
+	return r
+}
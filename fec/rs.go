@@ -0,0 +1,141 @@
+package fec
+
+import "errors"
+
+// ErrUncorrectable is returned when a codeword has more errors than its parity can correct.
+var ErrUncorrectable = errors.New("fec: too many errors to correct")
+
+// rsCodec is a systematic Reed-Solomon code over GF(2^8) with nsym parity symbols per codeword.
+type rsCodec struct {
+	nsym int
+	gen  []byte
+}
+
+func newRSCodec(nsym int) *rsCodec {
+	gen := []byte{1}
+	for i := 0; i < nsym; i++ {
+		gen = gfPolyMul(gen, []byte{1, gfPow(2, i)})
+	}
+	return &rsCodec{nsym: nsym, gen: gen}
+}
+
+// encode write the parity of msg into parity, which must be nsym bytes long.
+func (c *rsCodec) encode(msg, parity []byte) {
+	out := make([]byte, len(msg)+c.nsym)
+	copy(out, msg)
+	for i := range msg {
+		coef := out[i]
+		if coef != 0 {
+			for j := 1; j < len(c.gen); j++ {
+				out[i+j] ^= gfMul(c.gen[j], coef)
+			}
+		}
+	}
+	copy(parity, out[len(msg):])
+}
+
+// syndromes returns the syndromes of cw, with a leading zero, and whether any of them is non zero.
+func (c *rsCodec) syndromes(cw []byte) ([]byte, bool) {
+	synd := make([]byte, c.nsym+1)
+	bad := false
+	for i := 0; i < c.nsym; i++ {
+		synd[i+1] = gfPolyEval(cw, gfPow(2, i))
+		bad = bad || synd[i+1] != 0
+	}
+	return synd, bad
+}
+
+// decode correct cw in place and returns the number of corrected symbols.
+func (c *rsCodec) decode(cw []byte) (int, error) {
+	synd, bad := c.syndromes(cw)
+	if !bad {
+		return 0, nil
+	}
+	errLoc, err := c.errorLocator(synd)
+	if err != nil {
+		return 0, err
+	}
+	errPos, err := findErrors(reversed(errLoc), len(cw))
+	if err != nil {
+		return 0, err
+	}
+	correctErrata(cw, synd, errPos)
+	if _, bad = c.syndromes(cw); bad {
+		return 0, ErrUncorrectable
+	}
+	return len(errPos), nil
+}
+
+// errorLocator run Berlekamp-Massey over the syndromes.
+func (c *rsCodec) errorLocator(synd []byte) ([]byte, error) {
+	errLoc := []byte{1}
+	oldLoc := []byte{1}
+	shift := len(synd) - c.nsym
+	for i := 0; i < c.nsym; i++ {
+		k := i + shift
+		delta := synd[k]
+		for j := 1; j < len(errLoc); j++ {
+			delta ^= gfMul(errLoc[len(errLoc)-1-j], synd[k-j])
+		}
+		oldLoc = append(oldLoc, 0)
+		if delta != 0 {
+			if len(oldLoc) > len(errLoc) {
+				newLoc := gfPolyScale(oldLoc, delta)
+				oldLoc = gfPolyScale(errLoc, gfInverse(delta))
+				errLoc = newLoc
+			}
+			errLoc = gfPolyAdd(errLoc, gfPolyScale(oldLoc, delta))
+		}
+	}
+	for len(errLoc) > 0 && 0 == errLoc[0] {
+		errLoc = errLoc[1:]
+	}
+	if 2*(len(errLoc)-1) > c.nsym {
+		return nil, ErrUncorrectable
+	}
+	return errLoc, nil
+}
+
+// findErrors run a Chien search for the roots of the error locator.
+func findErrors(errLoc []byte, n int) ([]int, error) {
+	var pos []int
+	for i := 0; i < n; i++ {
+		if 0 == gfPolyEval(errLoc, gfPow(2, i)) {
+			pos = append(pos, n-1-i)
+		}
+	}
+	if len(pos) != len(errLoc)-1 {
+		return nil, ErrUncorrectable
+	}
+	return pos, nil
+}
+
+// correctErrata compute the error magnitudes with the Forney algorithm and fix cw.
+func correctErrata(cw []byte, synd []byte, errPos []int) {
+	coefPos := make([]int, len(errPos))
+	for i, p := range errPos {
+		coefPos[i] = len(cw) - 1 - p
+	}
+	errLoc := []byte{1}
+	for _, p := range coefPos {
+		errLoc = gfPolyMul(errLoc, gfPolyAdd([]byte{1}, []byte{gfPow(2, p), 0}))
+	}
+	_, rem := gfPolyDiv(gfPolyMul(reversed(synd), errLoc), append([]byte{1}, make([]byte, len(errLoc))...))
+	errEval := rem
+
+	x := make([]byte, len(coefPos))
+	for i, p := range coefPos {
+		x[i] = gfPow(2, p)
+	}
+	for i, xi := range x {
+		xiInv := gfInverse(xi)
+		prime := byte(1)
+		for j, xj := range x {
+			if j != i {
+				prime = gfMul(prime, 1^gfMul(xiInv, xj))
+			}
+		}
+		y := gfMul(xi, gfPolyEval(errEval, xiInv))
+		cw[errPos[i]] ^= gfDiv(y, prime)
+	}
+}
@@ -0,0 +1,56 @@
+package fec
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestRSCodec_Decode(t *testing.T) {
+	tests := []struct {
+		name      string
+		k, nsym   int
+		errors    int
+		wantError error
+	}{
+		{name: "TestRSCodec_Decode_NoError", k: 32, nsym: 8, errors: 0},
+		{name: "TestRSCodec_Decode_OneError", k: 32, nsym: 8, errors: 1},
+		{name: "TestRSCodec_Decode_MaxErrors", k: 32, nsym: 8, errors: 4},
+		{name: "TestRSCodec_Decode_MaxLength", k: 223, nsym: 32, errors: 16},
+		{name: "TestRSCodec_Decode_TooManyErrors", k: 32, nsym: 8, errors: 6, wantError: ErrUncorrectable},
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRSCodec(tt.nsym)
+			for round := 0; round < 50; round++ {
+				msg := make([]byte, tt.k)
+				rnd.Read(msg)
+				cw := make([]byte, tt.k+tt.nsym)
+				copy(cw, msg)
+				c.encode(msg, cw[tt.k:])
+				want := append([]byte(nil), cw...)
+				for _, pos := range rnd.Perm(len(cw))[:tt.errors] {
+					cw[pos] ^= byte(rnd.Intn(255) + 1)
+				}
+				n, err := c.decode(cw)
+				if tt.wantError != nil {
+					// beyond the parity limit a codeword may also decode to a different valid codeword
+					if nil == err && bytes.Equal(cw, want) {
+						t.Fatalf("decode() corrected %d errors with %d parity symbols", tt.errors, tt.nsym)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("decode() has error %v", err)
+				}
+				if n != tt.errors {
+					t.Errorf("decode() = %v, want %v", n, tt.errors)
+				}
+				if !bytes.Equal(cw, want) {
+					t.Fatalf("decode() = %v, want %v", cw, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package fixture
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	bitstream "github.com/meetleev/go_bitstream"
+	"github.com/meetleev/go_bitstream/schema"
+)
+
+// directives maps the kinds of operations to the directives rendering them.
+var directives = map[string]string{
+	"uint8": "u8", "int8": "s8", "uint16": "u16", "int16": "s16", "uint32": "u32", "int32": "s32",
+	"uint64": "u64", "int64": "s64", "float32": "f32", "float64": "f64",
+}
+
+// Disassemble write the operations recorded in t as text that assembles back to the traced bytes. Labels and
+// sections are kept. An operation no directive reproduces exactly, and bytes no operation covers, are
+// written as bytes with a comment. A number reading the same in both byte orders is written in the byte order
+// of the numbers around it.
+func Disassemble(w io.Writer, t *bitstream.Trace) error {
+	d := &disassembler{data: t.Bytes()}
+	d.order = d.firstOrder(t.Ops)
+	for _, op := range t.Ops {
+		d.closeSections(op.Depth)
+		if op.Offset > d.at && op.Offset <= len(d.data) {
+			d.gap(op.Offset, op.Depth)
+		}
+		if "section" == op.Kind {
+			d.write(op.Depth, section(op.Label))
+			d.open = append(d.open, op)
+			continue
+		}
+		if op.Error != "" {
+			d.write(op.Depth, "# error: "+op.Error)
+		}
+		end := min(op.Offset+op.Size, len(d.data))
+		if op.Offset >= end {
+			continue
+		}
+		d.op(op, d.data[op.Offset:end])
+		d.at = max(d.at, end)
+	}
+	d.closeSections(0)
+	if d.at < len(d.data) {
+		d.gap(len(d.data), 0)
+	}
+	_, err := io.WriteString(w, d.sb.String())
+	return err
+}
+
+type disassembler struct {
+	sb   strings.Builder
+	data []byte
+	at   int
+	open []bitstream.Op
+	// order is the suffix, be or le, of the last number whose byte order was not ambiguous
+	order string
+}
+
+func (d *disassembler) write(depth int, line string) {
+	d.sb.WriteString(strings.Repeat("  ", depth))
+	d.sb.WriteString(line)
+	d.sb.WriteString("\n")
+}
+
+// closeSections close the sections at depth and deeper, with the bytes they hold after their operations.
+func (d *disassembler) closeSections(depth int) {
+	for n := len(d.open); n > 0 && d.open[n-1].Depth >= depth; n = len(d.open) {
+		s := d.open[n-1]
+		if end := min(s.Offset+s.Size, len(d.data)); end > d.at {
+			d.gap(end, s.Depth+1)
+		}
+		d.write(s.Depth, "}")
+		d.open = d.open[:n-1]
+	}
+}
+
+// gap write the bytes up to end that no operation covers.
+func (d *disassembler) gap(end, depth int) {
+	d.bytes(depth, "", d.data[d.at:end], "# not read")
+	d.at = end
+}
+
+// bytes write data as bytes directives of 16 bytes, the first one labeled and commented.
+func (d *disassembler) bytes(depth int, name string, data []byte, comment string) {
+	for i := 0; i < len(data); i += 16 {
+		line := "bytes " + hex.EncodeToString(data[i:min(i+16, len(data))])
+		if 0 == i {
+			line = label(name, line)
+			if comment != "" {
+				line += "  " + comment
+			}
+		}
+		d.write(depth, line)
+	}
+}
+
+func (d *disassembler) op(op bitstream.Op, data []byte) {
+	if c, comment := d.match(op, data); c != "" {
+		if comment != "" {
+			c += "  # " + comment
+		}
+		d.write(op.Depth, label(op.Label, c))
+		return
+	}
+	comment := "# " + op.Kind
+	if op.Value != nil {
+		comment += " " + strings.ReplaceAll(fmt.Sprint(op.Value), "\n", " ")
+	}
+	d.bytes(op.Depth, op.Label, data, comment)
+}
+
+// match returns the first directive reproducing data for op, trying the byte order of d first, or "", and a
+// comment for it. The byte order of d follows a directive only matching in the other one.
+func (d *disassembler) match(op bitstream.Op, data []byte) (string, string) {
+	list, comment := candidates(op)
+	slices.SortStableFunc(list, func(a, b string) int {
+		return cmp.Compare(d.rank(a), d.rank(b))
+	})
+	for _, c := range list {
+		if got, err := Assemble([]byte(c), binary.BigEndian); nil == err && bytes.Equal(got, data) {
+			if name, _, _ := strings.Cut(c, " "); d.rank(c) > 0 {
+				d.order = name[len(name)-2:]
+			}
+			return c, comment
+		}
+	}
+	return "", ""
+}
+
+// firstOrder returns the byte order of the first number of ops reading in only one of them, be when there is
+// none.
+func (d *disassembler) firstOrder(ops []bitstream.Op) string {
+	for _, op := range ops {
+		if "section" == op.Kind || op.Offset+op.Size > len(d.data) {
+			continue
+		}
+		for _, order := range []string{"be", "le"} {
+			d.order = order
+			if d.match(op, d.data[op.Offset:op.Offset+op.Size]); d.order != order {
+				return d.order
+			}
+		}
+	}
+	return "be"
+}
+
+// rank returns 1 when the directive c is a number in the byte order other than the one of d, 0 otherwise.
+func (d *disassembler) rank(c string) int {
+	name, _, _ := strings.Cut(c, " ")
+	if (strings.HasSuffix(name, "be") || strings.HasSuffix(name, "le")) && !strings.HasSuffix(name, d.order) {
+		return 1
+	}
+	return 0
+}
+
+// candidates returns the directives that may reproduce op, to be checked against its bytes, and a comment
+// for them.
+func candidates(op bitstream.Op) ([]string, string) {
+	switch v := op.Value.(type) {
+	case bool:
+		return []string{"bool " + strconv.FormatBool(v)}, ""
+	case string:
+		switch op.Kind {
+		case "string":
+			return []string{"lpstr " + strconv.Quote(v)}, ""
+		case "str", "strz":
+			return []string{"str " + strconv.Quote(v)}, ""
+		}
+	case []byte:
+		switch op.Kind {
+		case "bytes":
+			return []string{"bytes " + hex.EncodeToString(v)}, ""
+		case "lpbytes":
+			return []string{"lpbytes " + hex.EncodeToString(v)}, ""
+		}
+	case []any:
+		if "pack" == op.Kind {
+			return packed(v), ""
+		}
+	case schema.EnumValue:
+		return numbers(op.Kind, v.Value), v.Name
+	case uint64:
+		if "uvarint" == op.Kind {
+			return []string{fmt.Sprintf("uvarint %d", v)}, ""
+		}
+	case int64:
+		if "varint" == op.Kind {
+			return []string{fmt.Sprintf("varint %d", v)}, ""
+		}
+	}
+	return numbers(op.Kind, op.Value), ""
+}
+
+// schemaTypes maps the primitive types of package schema to directives.
+var schemaTypes = map[string]string{
+	"u1": "u8", "s1": "s8", "u2": "u16", "s2": "s16", "u4": "u32", "s4": "s32", "u8": "u64", "s8": "s64",
+	"f4": "f32", "f8": "f64",
+}
+
+// numbers returns the directives writing the number v as an operation of kind.
+func numbers(kind string, v any) []string {
+	var s string
+	switch v := v.(type) {
+	case float32:
+		if math.IsNaN(float64(v)) {
+			return nil
+		}
+		s = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		if math.IsNaN(v) {
+			return nil
+		}
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	case uint8, int8, uint16, int16, uint32, int32, uint64, int64:
+		s = fmt.Sprint(v)
+	default:
+		return nil
+	}
+	name, orders := directives[kind], []string{"be", "le"}
+	if "" == name {
+		base := strings.TrimSuffix(strings.TrimSuffix(kind, "le"), "be")
+		if name = schemaTypes[base]; base != kind {
+			orders = []string{kind[len(base):]}
+		}
+	}
+	switch name {
+	case "":
+		return nil
+	case "u8", "s8":
+		return []string{name + " " + s}
+	}
+	list := make([]string, len(orders))
+	for i, order := range orders {
+		list[i] = name + order + " " + s
+	}
+	return list
+}
+
+// packed returns the integer or float directives that may have written values with BOStream.Pack.
+func packed(values []any) []string {
+	names := []string{"u8", "s8", "u16", "s16", "u32", "s32", "u64", "s64"}
+	args := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case int64, uint64:
+			args[i] = fmt.Sprint(v)
+		case float64:
+			names = []string{"f32", "f64"}
+			args[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		default:
+			return nil
+		}
+	}
+	if 0 == len(args) {
+		return nil
+	}
+	joined := strings.Join(args, " ")
+	var list []string
+	for _, name := range names {
+		if "u8" == name || "s8" == name {
+			list = append(list, name+" "+joined)
+		} else {
+			list = append(list, name+"be "+joined, name+"le "+joined)
+		}
+	}
+	return list
+}
+
+// section returns the line opening a section named name.
+func section(name string) string {
+	line := label(name, "{")
+	if name != "" && strings.HasPrefix(line, name+": ") {
+		return name + " {"
+	}
+	return line
+}
+
+// label prefix line with name, or with a comment holding name when it is not a valid label.
+func label(name, line string) string {
+	switch {
+	case "" == name:
+		return line
+	case strings.ContainsAny(name, " \t\r\n#\"`:{}"):
+		return line + "  # " + strconv.Quote(name)
+	}
+	return name + ": " + line
+}
@@ -0,0 +1,131 @@
+package fixture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"strings"
+	"testing"
+
+	bitstream "github.com/meetleev/go_bitstream"
+	"github.com/meetleev/go_bitstream/schema"
+)
+
+func TestDisassemble(t *testing.T) {
+	trace := bitstream.NewTrace()
+	data := []byte{0xff, 'B', 'S', 0, 2, 2, 'g', 'o', 0xac, 0x02, 0x40, 0x49, 0x0f, 0xdb, 1, 2, 3}
+	b := bitstream.NewBIStream(binary.BigEndian, bytes.NewReader(data)).WithTrace(trace)
+	b.ReadUint8()
+	b.Label("magic").ExpectBytes([]byte("BS")).
+		BeginSection("header").
+		Label("version").ExpectUint16(2)
+	b.ReadString()
+	b.EndSection()
+	b.ReadUvarint()
+	b.Label("not a label").ReadFloat32()
+	b.ReadUint8()
+	if err := b.Error(); err != nil {
+		t.Fatalf("Error() = %v", err)
+	}
+	var out strings.Builder
+	if err := Disassemble(&out, trace); err != nil {
+		t.Fatalf("Disassemble() error = %v", err)
+	}
+	want := "" +
+		"u8 255\n" +
+		"magic: bytes 4253\n" +
+		"header {\n" +
+		"  version: u16be 2\n" +
+		"  lpstr \"go\"\n" +
+		"}\n" +
+		"uvarint 300\n" +
+		"f32be 3.1415927  # \"not a label\"\n" +
+		"u8 1\n"
+	if out.String() != want {
+		t.Errorf("Disassemble() = \n%s, want \n%s", out.String(), want)
+	}
+	got, err := Assemble([]byte(out.String()), binary.BigEndian)
+	if err != nil {
+		t.Fatalf("Assemble() error = %v", err)
+	}
+	if !bytes.Equal(got, data[:15]) {
+		t.Errorf("Assemble() = %x, want %x", got, data[:15])
+	}
+}
+
+func TestDisassemble_Gaps(t *testing.T) {
+	trace := bitstream.NewTrace()
+	b := bitstream.NewBIStream(binary.BigEndian, bytes.NewReader([]byte{9, 9, 'B', 'S', 7, 8})).WithTrace(trace)
+	b.ScanFor([]byte("BS"))
+	b.ExpectBytes([]byte("BS"))
+	b.ScanFor([]byte{8})
+	b.ReadUint8()
+	var out strings.Builder
+	if err := Disassemble(&out, trace); err != nil {
+		t.Fatalf("Disassemble() error = %v", err)
+	}
+	want := "bytes 0909  # not read\nbytes 4253\nbytes 07  # not read\nu8 8\n"
+	if out.String() != want {
+		t.Errorf("Disassemble() = \n%s, want \n%s", out.String(), want)
+	}
+}
+
+func TestDisassemble_Schema(t *testing.T) {
+	s, err := schema.LoadFile("../schema/testdata/wav.ksy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("../schema/testdata/wav.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := bitstream.NewTrace()
+	if _, err := s.Decode(bitstream.NewBIStream(binary.LittleEndian, bytes.NewReader(data)).WithTrace(trace)); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	var out strings.Builder
+	if err := Disassemble(&out, trace); err != nil {
+		t.Fatalf("Disassemble() error = %v", err)
+	}
+	want, err := os.ReadFile("testdata/wav.fixture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != string(want) {
+		t.Errorf("Disassemble() = \n%s, want \n%s", out.String(), want)
+	}
+}
+
+func TestDisassemble_Order(t *testing.T) {
+	tests := []struct {
+		name   string
+		endian binary.ByteOrder
+		args   []uint16
+		want   string
+	}{
+		{name: "TestDisassemble_Order_LittleEndian", endian: binary.LittleEndian, args: []uint16{0, 1, 0x0101}, want: "u16le 0\nu16le 1\nu16le 257\n"},
+		{name: "TestDisassemble_Order_BigEndian", endian: binary.BigEndian, args: []uint16{0, 1, 0x0101}, want: "u16be 0\nu16be 1\nu16be 257\n"},
+		{name: "TestDisassemble_Order_None", endian: binary.LittleEndian, args: []uint16{0, 0x0101}, want: "u16be 0\nu16be 257\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			p := bitstream.NewBOStream(tt.endian, buf)
+			for _, v := range tt.args {
+				p.WriteUint16(v)
+			}
+			trace := bitstream.NewTrace()
+			b := bitstream.NewBIStream(tt.endian, buf).WithTrace(trace)
+			for range tt.args {
+				b.ReadUint16()
+			}
+			var out strings.Builder
+			if err := Disassemble(&out, trace); err != nil {
+				t.Fatalf("Disassemble() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("Disassemble() = \n%s, want \n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
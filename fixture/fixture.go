@@ -0,0 +1,335 @@
+// Package fixture translates a small text format into bytes and back, so that binary test fixtures can be
+// reviewed and diffed as text.
+//
+// Each line holds one directive, optionally preceded by a label, and # starts a comment:
+//
+//	# a chunk of a RIFF file
+//	magic: str "RIFF"
+//	size: u32le 36
+//	header {
+//	  u16be 0x1234 0x5678
+//	  crc32 begin
+//	  uvarint 300
+//	  bits 3 0b101 5 0
+//	  crc32 end
+//	}
+//
+// The directives are:
+//
+//	u8 s8 u16 s16 u32 s32 u64 s64   integers, with a be or le suffix or the endian of the stream
+//	f32 f64                         floats, with a be or le suffix or the endian of the stream
+//	bool true|false                 a byte 1 or 0
+//	bytes 01ff                      bytes in hex, spaces are allowed
+//	zero n                          n zero bytes
+//	str "abc"                       the bytes of Go quoted strings
+//	lpstr "abc", lpbytes 01ff       length prefixed, as BOStream.WriteString and WriteBytesWithLengthPrefix
+//	uvarint 300, varint -5          varints in the format of encoding/binary
+//	bits 3 0b101                    pairs of a width and a value, most significant bit first
+//	crc32 begin, crc32 end          a CRC-32 (IEEE) of the bytes between, in the endian of the stream
+//
+// Integer directives take several values. A label names the bytes of its directive in a bitstream.Trace, and
+// name { ... } is a section. bits must end on a byte boundary, a label before bits names the first byte the
+// line completes.
+package fixture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+// LineError is returned for an invalid line of a fixture.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("fixture: line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Assemble translate src into bytes. endian is the byte order of the directives without be or le suffix and
+// of checksums.
+func Assemble(src []byte, endian binary.ByteOrder) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := AssembleTo(bitstream.NewBOStream(endian, buf), src); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// AssembleTo write the bytes of src to p, labeling them and opening sections when p has a trace.
+func AssembleTo(p *bitstream.BOStream, src []byte) error {
+	a := &assembler{p: p}
+	for i, line := range strings.Split(string(src), "\n") {
+		if err := a.line(line); err != nil {
+			return &LineError{Line: i + 1, Err: err}
+		}
+		if err := p.Error(); err != nil {
+			return &LineError{Line: i + 1, Err: err}
+		}
+	}
+	switch {
+	case a.nbits > 0:
+		return errors.New("fixture: bits do not end on a byte boundary")
+	case a.sections > 0:
+		return errors.New("fixture: missing }")
+	case a.checksums > 0:
+		return errors.New("fixture: missing crc32 end")
+	}
+	return nil
+}
+
+type assembler struct {
+	p         *bitstream.BOStream
+	label     string
+	bits      byte
+	nbits     int
+	sections  int
+	checksums int
+}
+
+func (a *assembler) line(line string) error {
+	tokens, err := tokenize(line)
+	if err != nil || 0 == len(tokens) {
+		return err
+	}
+	if t := tokens[0]; len(t) > 1 && strings.HasSuffix(t, ":") {
+		a.label, tokens = strings.TrimSuffix(t, ":"), tokens[1:]
+		if 0 == len(tokens) {
+			return nil
+		}
+	}
+	if "bits" == tokens[0] {
+		return a.writeBits(tokens[1:])
+	}
+	if a.nbits > 0 {
+		return errors.New("bits do not end on a byte boundary")
+	}
+	switch {
+	case "}" == tokens[0] && 1 == len(tokens):
+		if 0 == a.sections {
+			return errors.New("} without section")
+		}
+		a.sections--
+		a.p.EndSection()
+		return nil
+	case "{" == tokens[len(tokens)-1] && len(tokens) <= 2:
+		name := a.label
+		if 2 == len(tokens) {
+			name = tokens[0]
+		}
+		a.label = ""
+		a.sections++
+		a.p.BeginSection(name)
+		return nil
+	}
+	if a.label != "" {
+		a.p.Label(a.label)
+		a.label = ""
+	}
+	return a.directive(tokens[0], tokens[1:])
+}
+
+// tokenize split line on spaces, keeping quoted strings whole and dropping the comment.
+func tokenize(line string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case ' ' == c || '\t' == c || '\r' == c:
+			i++
+		case '#' == c:
+			return tokens, nil
+		case '"' == c || '`' == c:
+			j := i + 1
+			for ; j < len(line) && line[j] != c; j++ {
+				if '\\' == line[j] && '"' == c {
+					j++
+				}
+			}
+			if j >= len(line) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, line[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for j < len(line) && !strings.ContainsRune(" \t\r#\"`", rune(line[j])) {
+				j++
+			}
+			tokens = append(tokens, line[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// packCodes maps the integer and float directives to the codes of BOStream.Pack.
+var packCodes = map[string]byte{
+	"u8": 'B', "s8": 'b', "u16": 'H', "s16": 'h', "u32": 'I', "s32": 'i', "u64": 'Q', "s64": 'q',
+	"f32": 'f', "f64": 'd',
+}
+
+func (a *assembler) directive(name string, args []string) error {
+	base, order := name, ""
+	if strings.HasSuffix(name, "be") {
+		base, order = strings.TrimSuffix(name, "be"), ">"
+	} else if strings.HasSuffix(name, "le") {
+		base, order = strings.TrimSuffix(name, "le"), "<"
+	}
+	if code, ok := packCodes[base]; ok && !(order != "" && len(base) < 3) {
+		return a.writeNumbers(order, code, args)
+	}
+	switch name {
+	case "bool":
+		for _, arg := range args {
+			v, err := strconv.ParseBool(arg)
+			if err != nil {
+				return err
+			}
+			a.p.WriteBool(v)
+		}
+	case "bytes", "lpbytes":
+		data, err := hex.DecodeString(strings.Join(args, ""))
+		if err != nil {
+			return err
+		}
+		if "bytes" == name {
+			a.p.WriteBytes(data)
+		} else {
+			a.p.WriteBytesWithLengthPrefix(data)
+		}
+	case "zero":
+		if len(args) != 1 {
+			return errors.New("zero takes a count")
+		}
+		n, err := strconv.ParseUint(args[0], 0, 32)
+		if err != nil {
+			return err
+		}
+		a.p.WriteReserved(n)
+	case "str", "lpstr":
+		if "lpstr" == name && len(args) != 1 {
+			return errors.New("lpstr takes one string")
+		}
+		for _, arg := range args {
+			s, err := strconv.Unquote(arg)
+			if err != nil {
+				return fmt.Errorf("invalid string %s", arg)
+			}
+			if "str" == name {
+				a.p.WriteBytes([]byte(s))
+			} else {
+				a.p.WriteString(s)
+			}
+		}
+	case "uvarint":
+		for _, arg := range args {
+			v, err := strconv.ParseUint(arg, 0, 64)
+			if err != nil {
+				return err
+			}
+			a.p.WriteUvarint(v)
+		}
+	case "varint":
+		for _, arg := range args {
+			v, err := strconv.ParseInt(arg, 0, 64)
+			if err != nil {
+				return err
+			}
+			a.p.WriteVarint(v)
+		}
+	case "crc32":
+		return a.checksum(args)
+	default:
+		return fmt.Errorf("unknown directive %q", name)
+	}
+	return nil
+}
+
+func (a *assembler) writeNumbers(order string, code byte, args []string) error {
+	if 0 == len(args) {
+		return errors.New("missing value")
+	}
+	values := make([]any, len(args))
+	for i, arg := range args {
+		var err error
+		switch code {
+		case 'f', 'd':
+			values[i], err = strconv.ParseFloat(arg, 64)
+		case 'b', 'h', 'i', 'q':
+			values[i], err = strconv.ParseInt(arg, 0, 64)
+		default:
+			values[i], err = strconv.ParseUint(arg, 0, 64)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	a.p.Pack(fmt.Sprintf("%s%d%c", order, len(values), code), values...)
+	return nil
+}
+
+func (a *assembler) writeBits(args []string) error {
+	if 0 == len(args) || len(args)%2 != 0 {
+		return errors.New("bits takes pairs of a width and a value")
+	}
+	label := a.label
+	a.label = ""
+	for i := 0; i < len(args); i += 2 {
+		width, err := strconv.ParseUint(args[i], 10, 8)
+		if err != nil || width < 1 || width > 64 {
+			return fmt.Errorf("invalid width %s", args[i])
+		}
+		v, err := strconv.ParseUint(args[i+1], 0, 64)
+		if err != nil {
+			return err
+		}
+		if width < 64 && v>>width != 0 {
+			return fmt.Errorf("%s does not fit in %d bits", args[i+1], width)
+		}
+		for k := int(width) - 1; k >= 0; k-- {
+			a.bits = a.bits<<1 | byte(v>>k&1)
+			if a.nbits++; 8 == a.nbits {
+				if label != "" {
+					a.p.Label(label)
+					label = ""
+				}
+				a.p.WriteByte(a.bits)
+				a.bits, a.nbits = 0, 0
+			}
+		}
+	}
+	return nil
+}
+
+func (a *assembler) checksum(args []string) error {
+	if len(args) != 1 {
+		return errors.New("crc32 takes begin or end")
+	}
+	switch args[0] {
+	case "begin":
+		a.checksums++
+		a.p.BeginChecksum(crc32.NewIEEE())
+	case "end":
+		if 0 == a.checksums {
+			return errors.New("crc32 end without begin")
+		}
+		a.checksums--
+		a.p.EndChecksum()
+	default:
+		return fmt.Errorf("crc32 takes begin or end, not %s", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package fixture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+func TestAssemble(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      string
+		endian    binary.ByteOrder
+		want      string
+		wantLine  int
+		wantError bool
+	}{
+		{name: "TestAssemble_Integers", args: "u8 1 0xff\ns8 -1\nu16 0x102\nu16le 0x102\ns32be -2", endian: binary.BigEndian, want: "01ffff01020201fffffffe"},
+		{name: "TestAssemble_Endian", args: "u32 1\nu64be 2", endian: binary.LittleEndian, want: "010000000000000000000002"},
+		{name: "TestAssemble_Floats", args: "f32be 1.5\nf64le -2", endian: binary.BigEndian, want: "3fc00000" + "00000000000000c0"},
+		{name: "TestAssemble_Bytes", args: "bytes 01 ff\nbool true false\nzero 2\nlpbytes beef", endian: binary.BigEndian, want: "01ff0100000002beef"},
+		{name: "TestAssemble_Strings", args: "str \"a#\\n\" `b`\nlpstr \"go\"", endian: binary.BigEndian, want: "61230a6202676f"},
+		{name: "TestAssemble_Varints", args: "uvarint 300\nvarint -5", endian: binary.BigEndian, want: "ac0209"},
+		{name: "TestAssemble_Bits", args: "bits 3 0b101 5 0\nbits 4 0xa 12 0x123", endian: binary.BigEndian, want: "a0a123"},
+		{name: "TestAssemble_Checksum", args: "crc32 begin\nstr \"abc\"\ncrc32 end", endian: binary.BigEndian, want: "616263352441c2"},
+		{
+			name:   "TestAssemble_Labels",
+			args:   "# comment\nmagic: str \"RIFF\"  # tail\n\nheader {\n  size:\n  u16 4\n}\nbody: {\n}",
+			endian: binary.BigEndian,
+			want:   "524946460004",
+		},
+		{name: "TestAssemble_UnknownDirective", args: "u8 1\nu24 1", endian: binary.BigEndian, wantLine: 2, wantError: true},
+		{name: "TestAssemble_OutOfRange", args: "u8 256", endian: binary.BigEndian, wantLine: 1, wantError: true},
+		{name: "TestAssemble_SuffixedByte", args: "u8le 1", endian: binary.BigEndian, wantLine: 1, wantError: true},
+		{name: "TestAssemble_UnalignedBits", args: "bits 3 1\nu8 1", endian: binary.BigEndian, wantLine: 2, wantError: true},
+		{name: "TestAssemble_BitsTooWide", args: "bits 2 4 6 0", endian: binary.BigEndian, wantLine: 1, wantError: true},
+		{name: "TestAssemble_UnterminatedString", args: "str \"abc", endian: binary.BigEndian, wantLine: 1, wantError: true},
+		{name: "TestAssemble_UnmatchedBrace", args: "}", endian: binary.BigEndian, wantLine: 1, wantError: true},
+		{name: "TestAssemble_MissingBrace", args: "header {\nu8 1", endian: binary.BigEndian, wantError: true},
+		{name: "TestAssemble_MissingChecksumEnd", args: "crc32 begin\nu8 1", endian: binary.BigEndian, wantError: true},
+		{name: "TestAssemble_TrailingBits", args: "bits 4 1", endian: binary.BigEndian, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Assemble([]byte(tt.args), tt.endian)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Assemble() error = %v, wantError %v", err, tt.wantError)
+			}
+			var lineErr *LineError
+			if errors.As(err, &lineErr) != (tt.wantLine > 0) || (tt.wantLine > 0 && lineErr.Line != tt.wantLine) {
+				t.Errorf("Assemble() error = %v, want line %v", err, tt.wantLine)
+			}
+			if !tt.wantError && hex.EncodeToString(got) != tt.want {
+				t.Errorf("Assemble() = %x, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssemble_Testdata(t *testing.T) {
+	src, err := os.ReadFile("testdata/wav.fixture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("../schema/testdata/wav.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Assemble(src, binary.LittleEndian)
+	if err != nil {
+		t.Fatalf("Assemble() error = %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Assemble() = %x, want %x", got, want)
+	}
+}
+
+func TestAssembleTo_BitsLabel(t *testing.T) {
+	trace := bitstream.NewTrace()
+	p := bitstream.NewBOStream(binary.BigEndian, new(bytes.Buffer)).WithTrace(trace)
+	if err := AssembleTo(p, []byte("flags: bits 4 1 12 2\nu8 3\nodd: bits 3 1\nbits 5 0\nu8 4")); err != nil {
+		t.Fatalf("AssembleTo() error = %v", err)
+	}
+	var labels []string
+	for _, op := range trace.Ops {
+		labels = append(labels, op.Label)
+	}
+	want := []string{"flags", "", "", "", ""}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels = %q, want %q", labels, want)
+	}
+}
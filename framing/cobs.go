@@ -0,0 +1,54 @@
+package framing
+
+import "io"
+
+// NewCOBSWriter returns a Writer encoding frames with Consistent Overhead Byte Stuffing, each followed by a 0x00 delimiter.
+func NewCOBSWriter(w io.Writer) *Writer {
+	return newWriter(w, cobs{})
+}
+
+// NewCOBSReader returns a Reader decoding COBS frames delimited by 0x00.
+func NewCOBSReader(r io.Reader) *Reader {
+	return newReader(r, cobs{})
+}
+
+type cobs struct{}
+
+func (cobs) delimiter() byte {
+	return 0
+}
+
+func (cobs) encode(dst, frame []byte) []byte {
+	code := len(dst)
+	dst = append(dst, 1)
+	for _, b := range frame {
+		if 0 == b {
+			code = len(dst)
+			dst = append(dst, 1)
+			continue
+		}
+		dst = append(dst, b)
+		dst[code]++
+		if 0xff == dst[code] {
+			code = len(dst)
+			dst = append(dst, 1)
+		}
+	}
+	return append(dst, 0)
+}
+
+func (cobs) decode(raw []byte) ([]byte, error) {
+	frame := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); {
+		code := int(raw[i])
+		if 0 == code || i+code > len(raw) {
+			return nil, ErrBadFrame
+		}
+		frame = append(frame, raw[i+1:i+code]...)
+		i += code
+		if code < 0xff && i < len(raw) {
+			frame = append(frame, 0)
+		}
+	}
+	return frame, nil
+}
@@ -0,0 +1,134 @@
+// Package framing delimits frames on byte streams with COBS, SLIP or HDLC byte stuffing.
+//
+// A Writer collects the bytes of one frame, typically from a BOStream, and EndFrame encodes and writes it:
+//
+//	fw := framing.NewCOBSWriter(port)
+//	bitstream.NewBOStream(binary.BigEndian, fw).WriteUint16(1).WriteString("ping")
+//	err := fw.EndFrame()
+//
+// A Reader yields each decoded frame as its own BIStream. A corrupted or truncated frame is reported once,
+// and the next call to Next starts from the following delimiter:
+//
+//	fr := framing.NewCOBSReader(port)
+//	frame, err := fr.Next(binary.BigEndian)
+package framing
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+var (
+	// ErrBadFrame is returned for a frame whose byte stuffing is invalid.
+	ErrBadFrame = errors.New("framing: bad frame")
+	// ErrFrameTooLong is returned for a frame longer than the reader's MaxFrameSize.
+	ErrFrameTooLong = errors.New("framing: frame too long")
+)
+
+// DefaultMaxFrameSize is the MaxFrameSize of new readers.
+const DefaultMaxFrameSize = 1 << 20
+
+type codec interface {
+	delimiter() byte
+	// encode append the encoded frame, including delimiters, to dst.
+	encode(dst, frame []byte) []byte
+	// decode the bytes found between two delimiters.
+	decode(raw []byte) ([]byte, error)
+}
+
+// Writer buffers the bytes of a frame until EndFrame.
+type Writer struct {
+	w     io.Writer
+	codec codec
+	frame bytes.Buffer
+	out   []byte
+}
+
+func newWriter(w io.Writer, c codec) *Writer {
+	return &Writer{w: w, codec: c}
+}
+
+// Write append p to the current frame.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.frame.Write(p)
+}
+
+// EndFrame encode the current frame and write it to the underlying io.Writer.
+func (w *Writer) EndFrame() error {
+	w.out = w.codec.encode(w.out[:0], w.frame.Bytes())
+	w.frame.Reset()
+	_, err := w.w.Write(w.out)
+	return err
+}
+
+// Reader splits the underlying io.Reader into decoded frames.
+type Reader struct {
+	// MaxFrameSize is the longest encoded frame accepted, in bytes.
+	MaxFrameSize int
+
+	r     *bufio.Reader
+	codec codec
+	raw   []byte
+}
+
+func newReader(r io.Reader, c codec) *Reader {
+	return &Reader{MaxFrameSize: DefaultMaxFrameSize, r: bufio.NewReader(r), codec: c}
+}
+
+// ReadFrame read and decode the next non-empty frame. Returns io.EOF at a frame boundary,
+// io.ErrUnexpectedEOF for a truncated last frame, and ErrBadFrame or ErrFrameTooLong for a frame
+// that was skipped; reading can continue after those.
+func (r *Reader) ReadFrame() ([]byte, error) {
+	for {
+		raw, err := r.readRaw()
+		if err != nil {
+			return nil, err
+		}
+		if 0 == len(raw) {
+			continue
+		}
+		return r.codec.decode(raw)
+	}
+}
+
+// Next read the next frame and returns a BIStream bounded to it.
+func (r *Reader) Next(endian binary.ByteOrder) (*bitstream.BIStream, error) {
+	frame, err := r.ReadFrame()
+	if err != nil {
+		return nil, err
+	}
+	return bitstream.NewBIStream(endian, bytes.NewReader(frame)), nil
+}
+
+// readRaw returns the bytes up to the next delimiter, excluding it.
+func (r *Reader) readRaw() ([]byte, error) {
+	r.raw = r.raw[:0]
+	tooLong := false
+	for {
+		chunk, err := r.r.ReadSlice(r.codec.delimiter())
+		if nil == err {
+			chunk = chunk[:len(chunk)-1]
+		}
+		if !tooLong {
+			r.raw = append(r.raw, chunk...)
+			tooLong = len(r.raw) > r.MaxFrameSize
+		}
+		switch {
+		case nil == err && tooLong:
+			return nil, ErrFrameTooLong
+		case nil == err:
+			return r.raw, nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case err == io.EOF && len(r.raw) > 0:
+			return nil, io.ErrUnexpectedEOF
+		default:
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package framing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+func TestWriter_EndFrame(t *testing.T) {
+	tests := []struct {
+		name      string
+		newWriter func(io.Writer) *Writer
+		args      []byte
+		want      []byte
+	}{
+		{name: "TestWriter_EndFrame_COBS", newWriter: NewCOBSWriter, args: []byte{0x11, 0x22, 0x00, 0x33}, want: []byte{0x03, 0x11, 0x22, 0x02, 0x33, 0x00}},
+		{name: "TestWriter_EndFrame_COBS_Zero", newWriter: NewCOBSWriter, args: []byte{0x00}, want: []byte{0x01, 0x01, 0x00}},
+		{name: "TestWriter_EndFrame_COBS_Long", newWriter: NewCOBSWriter, args: bytes.Repeat([]byte{1}, 254), want: append(append([]byte{0xff}, bytes.Repeat([]byte{1}, 254)...), 0x01, 0x00)},
+		{name: "TestWriter_EndFrame_SLIP", newWriter: NewSLIPWriter, args: []byte{0x01, 0xc0, 0xdb, 0x02}, want: []byte{0xc0, 0x01, 0xdb, 0xdc, 0xdb, 0xdd, 0x02, 0xc0}},
+		{name: "TestWriter_EndFrame_HDLC", newWriter: NewHDLCWriter, args: []byte{0x01, 0x7e, 0x7d, 0x02}, want: []byte{0x7e, 0x01, 0x7d, 0x5e, 0x7d, 0x5d, 0x02, 0x7e}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			w := tt.newWriter(buf)
+			w.Write(tt.args)
+			if err := w.EndFrame(); err != nil {
+				t.Errorf("EndFrame() has error %v", err)
+			}
+			if !reflect.DeepEqual(buf.Bytes(), tt.want) {
+				t.Errorf("EndFrame() = %x, want %x", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestReader_Next(t *testing.T) {
+	tests := []struct {
+		name      string
+		newWriter func(io.Writer) *Writer
+		newReader func(io.Reader) *Reader
+	}{
+		{name: "TestReader_Next_COBS", newWriter: NewCOBSWriter, newReader: NewCOBSReader},
+		{name: "TestReader_Next_SLIP", newWriter: NewSLIPWriter, newReader: NewSLIPReader},
+		{name: "TestReader_Next_HDLC", newWriter: NewHDLCWriter, newReader: NewHDLCReader},
+	}
+	want := []string{"ping", string([]byte{0x00, 0xc0, 0xdb, 0x7e, 0x7d}), "pong"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			fw := tt.newWriter(buf)
+			w := bitstream.NewBOStream(binary.BigEndian, fw)
+			for i, s := range want {
+				w.WriteUint16(uint16(i)).WriteString(s)
+				fw.EndFrame()
+			}
+
+			fr := tt.newReader(buf)
+			for i, s := range want {
+				frame, err := fr.Next(binary.BigEndian)
+				if err != nil {
+					t.Fatalf("Next() has error %v", err)
+				}
+				var (
+					n   uint16
+					got string
+				)
+				frame.FetchUint16(&n).FetchString(&got)
+				if frame.Error() != nil || int(n) != i || got != s {
+					t.Errorf("Next() = %v %q, want %v %q", n, got, i, s)
+				}
+				if _, err := frame.ReadByte(); err != io.EOF {
+					t.Errorf("ReadByte() past frame error = %v, want %v", err, io.EOF)
+				}
+			}
+			if _, err := fr.Next(binary.BigEndian); err != io.EOF {
+				t.Errorf("Next() at end error = %v, want %v", err, io.EOF)
+			}
+		})
+	}
+}
+
+func TestReader_Resync(t *testing.T) {
+	tests := []struct {
+		name      string
+		newReader func(io.Reader) *Reader
+		args      []byte
+		want      []error
+	}{
+		{name: "TestReader_Resync_COBS", newReader: NewCOBSReader, args: []byte{0x09, 0x01, 0x00, 0x03, 'o', 'k', 0x00, 0x05, 'l', 'o'}, want: []error{ErrBadFrame, nil, io.ErrUnexpectedEOF}},
+		{name: "TestReader_Resync_SLIP", newReader: NewSLIPReader, args: []byte{0x01, 0xdb, 0x01, 0xc0, 0xc0, 'o', 'k', 0xc0, 'l', 'o'}, want: []error{ErrBadFrame, nil, io.ErrUnexpectedEOF}},
+		{name: "TestReader_Resync_HDLC", newReader: NewHDLCReader, args: []byte{0x7e, 0x01, 0x7d, 0x7e, 'o', 'k', 0x7e, 'l', 'o'}, want: []error{ErrBadFrame, nil, io.ErrUnexpectedEOF}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.newReader(bytes.NewReader(tt.args))
+			for _, want := range tt.want {
+				frame, err := r.ReadFrame()
+				if !errors.Is(err, want) {
+					t.Fatalf("ReadFrame() error = %v, want %v", err, want)
+				}
+				if nil == err && string(frame) != "ok" {
+					t.Errorf("ReadFrame() = %q, want %q", frame, "ok")
+				}
+			}
+		})
+	}
+}
+
+func TestReader_MaxFrameSize(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := NewSLIPWriter(buf)
+	w.Write(make([]byte, 10000))
+	w.EndFrame()
+	w.Write([]byte("ok"))
+	w.EndFrame()
+
+	r := NewSLIPReader(buf)
+	r.MaxFrameSize = 100
+	if _, err := r.ReadFrame(); err != ErrFrameTooLong {
+		t.Errorf("ReadFrame() error = %v, want %v", err, ErrFrameTooLong)
+	}
+	if frame, err := r.ReadFrame(); err != nil || string(frame) != "ok" {
+		t.Errorf("ReadFrame() = %q, %v, want %q", frame, err, "ok")
+	}
+}
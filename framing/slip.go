@@ -0,0 +1,116 @@
+package framing
+
+import "io"
+
+const (
+	slipEnd    = 0xc0
+	slipEsc    = 0xdb
+	slipEscEnd = 0xdc
+	slipEscEsc = 0xdd
+
+	hdlcFlag = 0x7e
+	hdlcEsc  = 0x7d
+	hdlcXor  = 0x20
+)
+
+// NewSLIPWriter returns a Writer encoding frames with SLIP (RFC 1055). Each frame starts and ends with END,
+// which flushes any line noise on the receiving side.
+func NewSLIPWriter(w io.Writer) *Writer {
+	return newWriter(w, slip{})
+}
+
+// NewSLIPReader returns a Reader decoding SLIP frames.
+func NewSLIPReader(r io.Reader) *Reader {
+	return newReader(r, slip{})
+}
+
+// NewHDLCWriter returns a Writer encoding frames with HDLC-like byte stuffing (RFC 1662): frames are enclosed
+// in 0x7e flags, and 0x7e and 0x7d are escaped by 0x7d followed by the byte xor 0x20.
+// The FCS is not added; use a CRC16CCITT checksum region if the format needs one.
+func NewHDLCWriter(w io.Writer) *Writer {
+	return newWriter(w, hdlc{})
+}
+
+// NewHDLCReader returns a Reader decoding HDLC-like frames.
+func NewHDLCReader(r io.Reader) *Reader {
+	return newReader(r, hdlc{})
+}
+
+type slip struct{}
+
+func (slip) delimiter() byte {
+	return slipEnd
+}
+
+func (slip) encode(dst, frame []byte) []byte {
+	dst = append(dst, slipEnd)
+	for _, b := range frame {
+		switch b {
+		case slipEnd:
+			dst = append(dst, slipEsc, slipEscEnd)
+		case slipEsc:
+			dst = append(dst, slipEsc, slipEscEsc)
+		default:
+			dst = append(dst, b)
+		}
+	}
+	return append(dst, slipEnd)
+}
+
+func (slip) decode(raw []byte) ([]byte, error) {
+	frame := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != slipEsc {
+			frame = append(frame, raw[i])
+			continue
+		}
+		i++
+		if i == len(raw) {
+			return nil, ErrBadFrame
+		}
+		switch raw[i] {
+		case slipEscEnd:
+			frame = append(frame, slipEnd)
+		case slipEscEsc:
+			frame = append(frame, slipEsc)
+		default:
+			return nil, ErrBadFrame
+		}
+	}
+	return frame, nil
+}
+
+type hdlc struct{}
+
+func (hdlc) delimiter() byte {
+	return hdlcFlag
+}
+
+func (hdlc) encode(dst, frame []byte) []byte {
+	dst = append(dst, hdlcFlag)
+	for _, b := range frame {
+		if hdlcFlag == b || hdlcEsc == b {
+			dst = append(dst, hdlcEsc, b^hdlcXor)
+		} else {
+			dst = append(dst, b)
+		}
+	}
+	return append(dst, hdlcFlag)
+}
+
+func (hdlc) decode(raw []byte) ([]byte, error) {
+	frame := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != hdlcEsc {
+			frame = append(frame, raw[i])
+			continue
+		}
+		// an escape right before the flag aborts the frame
+		i++
+		if i == len(raw) {
+			return nil, ErrBadFrame
+		}
+		frame = append(frame, raw[i]^hdlcXor)
+	}
+	return frame, nil
+}
@@ -0,0 +1,99 @@
+package bitstream
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"errors"
+	"hash"
+	"io"
+)
+
+// ErrMACMismatch is reported by ReadVerified when the trailing MAC does not match the data.
+var ErrMACMismatch = errors.New("bitstream: message authentication failed")
+
+// BeginHash start a hash region. Every byte written until the matching EndHash is fed to h.
+func (p *BOStream) BeginHash(h hash.Hash) *BOStream {
+	return p.catchError(func() {
+		h.Reset()
+		p.pushRegion(region{kind: regionHash, hash: h}, io.MultiWriter(p.writer, h))
+	})
+}
+
+// EndHash end the hash region and store the digest of the written bytes in digest. Nothing is written.
+func (p *BOStream) EndHash(digest *[]byte) *BOStream {
+	r, ok := p.popRegion(regionHash)
+	if !ok {
+		return p
+	}
+	return p.catchError(func() {
+		*digest = r.hash.Sum(nil)
+	})
+}
+
+// BeginVerified start a verified region. The bytes written until the matching EndVerified are buffered.
+func (p *BOStream) BeginVerified(mac hash.Hash) *BOStream {
+	return p.catchError(func() {
+		buf := new(bytes.Buffer)
+		p.pushRegion(region{kind: regionVerified, hash: mac, buf: buf}, buf)
+	})
+}
+
+// EndVerified end the verified region, then write the buffered bytes with a length prefix followed by
+// their MAC, for example hmac.New(sha256.New, key). Use BIStream.ReadVerified to read it back.
+func (p *BOStream) EndVerified() *BOStream {
+	r, ok := p.popRegion(regionVerified)
+	if !ok {
+		return p
+	}
+	return p.catchError(func() {
+		r.hash.Reset()
+		r.hash.Write(r.buf.Bytes())
+		p.WriteBytesWithLengthPrefix(r.buf.Bytes()).WriteBytes(r.hash.Sum(nil))
+	})
+}
+
+// BeginHash start a hash region. Every byte read until the matching EndHash is fed to h.
+func (b *BIStream) BeginHash(h hash.Hash) *BIStream {
+	return b.catchError(func() {
+		h.Reset()
+		b.pushRegion(region{kind: regionHash, hash: h}, io.TeeReader(b.reader, h))
+	})
+}
+
+// EndHash end the hash region and store the digest of the read bytes in digest.
+func (b *BIStream) EndHash(digest *[]byte) *BIStream {
+	r, ok := b.popRegion(regionHash)
+	if !ok {
+		return b
+	}
+	return b.catchError(func() {
+		*digest = r.hash.Sum(nil)
+	})
+}
+
+// ReadVerified read a region written by BOStream.EndVerified and compare its trailing MAC in constant time.
+// Returns a BIStream over the region only if the MAC matches, so no value is decoded from unauthenticated
+// bytes, and an error if exists.
+func (b *BIStream) ReadVerified(mac hash.Hash) (*BIStream, error) {
+	data, err := b.ReadBytesWithLengthPrefix()
+	if err != nil {
+		return nil, err
+	}
+	tag, err := b.ReadBytes(uint64(mac.Size()))
+	if err != nil {
+		return nil, err
+	}
+	mac.Reset()
+	mac.Write(data)
+	if !hmac.Equal(tag, mac.Sum(nil)) {
+		return nil, ErrMACMismatch
+	}
+	return NewBIStream(b.endian, bytes.NewReader(data)), nil
+}
+
+// FetchVerified read a verified region into value, see ReadVerified.
+func (b *BIStream) FetchVerified(value **BIStream, mac hash.Hash) *BIStream {
+	return b.catchError(func() {
+		*value, b.err = b.ReadVerified(mac)
+	})
+}
@@ -0,0 +1,85 @@
+package bitstream
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestBOStream_EndHash(t *testing.T) {
+	buf := new(bytes.Buffer)
+	var digest []byte
+	p := NewBOStream(binary.BigEndian, buf)
+	p.WriteByte(0).BeginHash(sha256.New()).WriteString("golang").WriteUint32(1).EndHash(&digest).WriteByte(0)
+	if p.Error() != nil {
+		t.Fatalf("EndHash() has error %v", p.Error())
+	}
+	want := sha256.Sum256(buf.Bytes()[1 : buf.Len()-1])
+	if !reflect.DeepEqual(digest, want[:]) {
+		t.Errorf("EndHash() = %x, want %x", digest, want)
+	}
+}
+
+func TestBIStream_EndHash(t *testing.T) {
+	args := []byte{6, 'g', 'o', 'l', 'a', 'n', 'g', 0, 0, 0, 1}
+	var (
+		digest []byte
+		s      string
+		u32    uint32
+	)
+	p := NewBIStream(binary.BigEndian, bytes.NewReader(args))
+	p.BeginHash(sha256.New()).FetchString(&s).FetchUint32(&u32).EndHash(&digest)
+	if p.Error() != nil {
+		t.Fatalf("EndHash() has error %v", p.Error())
+	}
+	want := sha256.Sum256(args)
+	if !reflect.DeepEqual(digest, want[:]) {
+		t.Errorf("EndHash() = %x, want %x", digest, want)
+	}
+}
+
+func TestBIStream_ReadVerified(t *testing.T) {
+	key := []byte("secret")
+	tests := []struct {
+		name      string
+		key       []byte
+		tamper    int
+		want      string
+		wantError error
+	}{
+		{name: "TestBIStream_ReadVerified", key: key, tamper: -1, want: "firmware"},
+		{name: "TestBIStream_ReadVerified_TamperedData", key: key, tamper: 3, wantError: ErrMACMismatch},
+		{name: "TestBIStream_ReadVerified_TamperedTag", key: key, tamper: 20, wantError: ErrMACMismatch},
+		{name: "TestBIStream_ReadVerified_WrongKey", key: []byte("other"), tamper: -1, wantError: ErrMACMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			w := NewBOStream(binary.BigEndian, buf)
+			w.BeginVerified(hmac.New(sha256.New, key)).WriteString("firmware").WriteUint16(3).EndVerified()
+			if w.Error() != nil {
+				t.Fatalf("EndVerified() has error %v", w.Error())
+			}
+			data := buf.Bytes()
+			if tt.tamper >= 0 {
+				data[tt.tamper] ^= 0x01
+			}
+			var body *BIStream
+			r := NewBIStream(binary.BigEndian, bytes.NewReader(data))
+			r.FetchVerified(&body, hmac.New(sha256.New, tt.key))
+			if r.Error() != tt.wantError {
+				t.Fatalf("ReadVerified() error = %v, want %v", r.Error(), tt.wantError)
+			}
+			if tt.wantError != nil {
+				return
+			}
+			s, err := body.ReadString()
+			if err != nil || s != tt.want {
+				t.Errorf("ReadVerified() = %v, want %v", s, tt.want)
+			}
+		})
+	}
+}
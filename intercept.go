@@ -0,0 +1,130 @@
+package bitstream
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Call is a primitive operation of a stream, as seen by an Interceptor. When a Read* or Write* is built on
+// others, like WriteString, only the outermost one is a Call.
+type Call struct {
+	// Kind is the type of the value, such as uint16, bytes, lpbytes, string or pack, as the Kind of an Op.
+	Kind  string
+	Write bool
+	// Label is the name given by Label to the operation.
+	Label string
+	// Offset is the number of bytes the stream read or wrote before the operation, as BytesRead or
+	// BytesWritten.
+	Offset int64
+	// Size is the number of bytes the operation read or wrote, set when it returns.
+	Size int64
+	// Value is the value to write, or the value read, set when the operation returns. Changing it changes
+	// what the interceptors added before see, not the bytes, so that an interceptor can redact values.
+	Value any
+	// Context is the context of the stream, or context.Background.
+	Context context.Context
+}
+
+// Interceptor wraps the primitive operations of a stream. Intercept calls next to run the operation, through
+// the interceptors added after it, and returns its error, or returns an error without calling next to fail
+// the operation.
+type Interceptor interface {
+	Intercept(c *Call, next func() error) error
+}
+
+// InterceptorFunc is an Interceptor calling itself.
+type InterceptorFunc func(c *Call, next func() error) error
+
+func (f InterceptorFunc) Intercept(c *Call, next func() error) error {
+	return f(c, next)
+}
+
+// WithInterceptor add interceptors to the stream, wrapping its operations in the order they are added. The
+// trace, stats and logger of the stream are interceptors wrapping all the others.
+func (b *BIStream) WithInterceptor(interceptors ...Interceptor) *BIStream {
+	b.interceptors = append(b.interceptors, interceptors...)
+	b.chain = chain(b.trace, nil, b.stats, b.logger, b.interceptors)
+	return b
+}
+
+// WithInterceptor add interceptors to the stream, wrapping its operations in the order they are added. The
+// trace, stats and logger of the stream are interceptors wrapping all the others.
+func (p *BOStream) WithInterceptor(interceptors ...Interceptor) *BOStream {
+	p.interceptors = append(p.interceptors, interceptors...)
+	p.chain = chain(p.trace, p.buffered, p.stats, p.logger, p.interceptors)
+	return p
+}
+
+// chain returns the interceptors of a stream, outermost first. paused reports whether the trace misses the
+// bytes written.
+func chain(t *Trace, paused func() bool, s *Stats, l *slog.Logger, interceptors []Interceptor) []Interceptor {
+	var list []Interceptor
+	if l != nil {
+		list = append(list, logInterceptor{l})
+	}
+	if s != nil {
+		list = append(list, statsInterceptor{s})
+	}
+	if t != nil {
+		list = append(list, traceInterceptor{t, paused})
+	}
+	return append(list, interceptors...)
+}
+
+// intercept run op through the interceptors in list.
+func intercept(list []Interceptor, c *Call, op func() error) error {
+	if 0 == len(list) {
+		return op()
+	}
+	return list[0].Intercept(c, func() error {
+		return intercept(list[1:], c, op)
+	})
+}
+
+func contextOrBackground(ctx context.Context) context.Context {
+	if nil == ctx {
+		return context.Background()
+	}
+	return ctx
+}
+
+// interceptRead run read as an operation of kind.
+func interceptRead[T any](b *BIStream, kind string, read func() (T, error)) (T, error) {
+	label := b.label
+	b.label = ""
+	if 0 == len(b.chain) || b.depth > 0 {
+		return read()
+	}
+	var v T
+	c := &Call{Kind: kind, Label: label, Offset: b.nread, Context: contextOrBackground(b.ctx)}
+	b.depth++
+	err := intercept(b.chain, c, func() error {
+		var err error
+		v, err = read()
+		c.Size, c.Value = b.nread-c.Offset, v
+		return err
+	})
+	b.depth--
+	return v, err
+}
+
+// interceptWrite run write as an operation of kind writing v.
+func interceptWrite[T any](p *BOStream, kind string, v T, write func() *BOStream) *BOStream {
+	label := p.label
+	p.label = ""
+	if 0 == len(p.chain) || p.depth > 0 || p.err != nil {
+		return write()
+	}
+	c := &Call{Kind: kind, Write: true, Label: label, Offset: p.written, Value: v, Context: contextOrBackground(p.ctx)}
+	p.depth++
+	err := intercept(p.chain, c, func() error {
+		write()
+		c.Size = p.written - c.Offset
+		return p.err
+	})
+	p.depth--
+	if err != nil && nil == p.err {
+		p.err = err
+	}
+	return p
+}
@@ -0,0 +1,120 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// recorder is an Interceptor keeping the calls it sees.
+type recorder struct {
+	name  string
+	calls *[]string
+	seen  []Call
+}
+
+func (r *recorder) Intercept(c *Call, next func() error) error {
+	*r.calls = append(*r.calls, r.name+" "+c.Kind)
+	err := next()
+	r.seen = append(r.seen, Call{Kind: c.Kind, Write: c.Write, Label: c.Label, Offset: c.Offset, Size: c.Size, Value: c.Value})
+	*r.calls = append(*r.calls, "/"+r.name)
+	return err
+}
+
+func TestBIStream_WithInterceptor(t *testing.T) {
+	var calls []string
+	outer, inner := &recorder{name: "outer", calls: &calls}, &recorder{name: "inner", calls: &calls}
+	b := NewBIStream(binary.BigEndian, bytes.NewReader([]byte{0, 2, 2, 'g', 'o'})).WithInterceptor(outer).WithInterceptor(inner)
+	b.Label("version").ReadUint16()
+	b.ReadString()
+	wantCalls := []string{"outer uint16", "inner uint16", "/inner", "/outer", "outer string", "inner string", "/inner", "/outer"}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Errorf("calls = %v, want %v", calls, wantCalls)
+	}
+	want := []Call{
+		{Kind: "uint16", Label: "version", Offset: 0, Size: 2, Value: uint16(2)},
+		{Kind: "string", Offset: 2, Size: 3, Value: "go"},
+	}
+	if !reflect.DeepEqual(inner.seen, want) {
+		t.Errorf("Calls = %+v, want %+v", inner.seen, want)
+	}
+}
+
+func TestBIStream_WithInterceptor_Unpack(t *testing.T) {
+	var calls []string
+	r := &recorder{name: "r", calls: &calls}
+	var (
+		v    uint16
+		name string
+	)
+	NewBIStream(binary.BigEndian, bytes.NewReader([]byte{0, 2, 'g', 'o'})).WithInterceptor(r).Unpack(">H2s", &v, &name)
+	want := []Call{{Kind: "pack", Offset: 0, Size: 4, Value: []any{uint16(2), "go"}}}
+	if !reflect.DeepEqual(r.seen, want) {
+		t.Errorf("Calls = %+v, want %+v", r.seen, want)
+	}
+}
+
+func TestBOStream_WithInterceptor(t *testing.T) {
+	var calls []string
+	r := &recorder{name: "r", calls: &calls}
+	buf := new(bytes.Buffer)
+	NewBOStream(binary.BigEndian, buf).WithInterceptor(r).WriteBool(true).Label("name").WriteString("go")
+	want := []Call{
+		{Kind: "bool", Write: true, Offset: 0, Size: 1, Value: true},
+		{Kind: "string", Write: true, Label: "name", Offset: 1, Size: 3, Value: "go"},
+	}
+	if !reflect.DeepEqual(r.seen, want) {
+		t.Errorf("Calls = %+v, want %+v", r.seen, want)
+	}
+}
+
+func TestInterceptorFunc_Fault(t *testing.T) {
+	errInjected := errors.New("injected")
+	fault := InterceptorFunc(func(c *Call, next func() error) error {
+		if "uint32" == c.Kind {
+			return errInjected
+		}
+		return next()
+	})
+	trace := NewTrace()
+	b := NewBIStream(binary.BigEndian, bytes.NewReader([]byte{1, 0, 0, 0, 2})).WithTrace(trace).WithInterceptor(fault)
+	var n uint8
+	var m uint32
+	b.FetchUint8(&n).FetchUint32(&m)
+	if !errors.Is(b.Error(), errInjected) {
+		t.Errorf("Error() = %v, want %v", b.Error(), errInjected)
+	}
+	if b.BytesRead() != 1 {
+		t.Errorf("BytesRead() = %v, want 1", b.BytesRead())
+	}
+	if 2 != len(trace.Ops) || trace.Ops[1].Error != errInjected.Error() {
+		t.Errorf("Ops = %+v, want the injected error", trace.Ops)
+	}
+
+	buf := new(bytes.Buffer)
+	p := NewBOStream(binary.BigEndian, buf).WithInterceptor(fault).WriteUint8(1).WriteUint32(2).WriteUint8(3)
+	if !errors.Is(p.Error(), errInjected) || buf.Len() != 1 {
+		t.Errorf("Error() = %v, wrote %x, want %v after 1 byte", p.Error(), buf.Bytes(), errInjected)
+	}
+}
+
+func TestInterceptorFunc_Redact(t *testing.T) {
+	redact := InterceptorFunc(func(c *Call, next func() error) error {
+		err := next()
+		if "password" == c.Label {
+			c.Value = "***"
+		}
+		return err
+	})
+	trace := NewTrace()
+	b := NewBIStream(binary.BigEndian, bytes.NewReader([]byte{6, 's', 'e', 'c', 'r', 'e', 't'})).WithTrace(trace).WithInterceptor(redact)
+	got, err := b.Label("password").ReadString()
+	if err != nil || got != "secret" {
+		t.Errorf("ReadString() = %v, %v, want secret", got, err)
+	}
+	if 1 != len(trace.Ops) || trace.Ops[0].Value != "***" {
+		t.Errorf("Ops = %+v, want the value redacted", trace.Ops)
+	}
+}
@@ -0,0 +1,51 @@
+package bitstream
+
+import "log/slog"
+
+// WithLogger log the operations of the stream to l, nil stop logging. Every Read* and Write* is logged at
+// debug level with its offset, label, type, size and value, truncated, and a failed one at error level. Like
+// a Trace, only the outermost operation is logged, and offsets count the bytes of the stream.
+func (b *BIStream) WithLogger(l *slog.Logger) *BIStream {
+	b.logger = l
+	return b.WithInterceptor()
+}
+
+// WithLogger log the operations of the stream to l, nil stop logging. Every Read* and Write* is logged at
+// debug level with its offset, label, type, size and value, truncated, and a failed one at error level. Like
+// a Trace, only the outermost operation is logged, and offsets count the bytes written, where the writes
+// inside a verified, compressed or sealed region do not move.
+func (p *BOStream) WithLogger(l *slog.Logger) *BOStream {
+	p.logger = l
+	return p.WithInterceptor()
+}
+
+// logInterceptor logs the operations of a stream.
+type logInterceptor struct {
+	l *slog.Logger
+}
+
+func (i logInterceptor) Intercept(c *Call, next func() error) error {
+	err := next()
+	level, msg := slog.LevelDebug, "bitstream: read"
+	if c.Write {
+		msg = "bitstream: write"
+	}
+	if err != nil {
+		level, msg = slog.LevelError, msg+" failed"
+	}
+	if !i.l.Enabled(c.Context, level) {
+		return err
+	}
+	attrs := []slog.Attr{
+		slog.Int64("offset", c.Offset),
+		slog.String("label", c.Label),
+		slog.String("type", c.Kind),
+		slog.Int64("size", c.Size),
+		slog.String("value", formatValue(c.Value, 32)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	i.l.LogAttrs(c.Context, level, msg, attrs...)
+	return err
+}
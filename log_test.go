@@ -0,0 +1,59 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// newTestLogger returns a logger writing text lines without time to buf.
+func newTestLogger(buf *bytes.Buffer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if slog.TimeKey == a.Key {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestBIStream_WithLogger(t *testing.T) {
+	var out bytes.Buffer
+	b := NewBIStream(binary.BigEndian, bytes.NewReader([]byte{0, 2, 2, 'g', 'o', 1})).WithLogger(newTestLogger(&out, slog.LevelDebug))
+	b.Label("version").ReadUint16()
+	b.ReadString()
+	b.Label("length").ReadUint32()
+	want := "" +
+		"level=DEBUG msg=\"bitstream: read\" offset=0 label=version type=uint16 size=2 value=2\n" +
+		"level=DEBUG msg=\"bitstream: read\" offset=2 label=\"\" type=string size=3 value=\"\\\"go\\\"\"\n" +
+		"level=ERROR msg=\"bitstream: read failed\" offset=5 label=length type=uint32 size=1 value=0 error=\"unexpected EOF\"\n"
+	if out.String() != want {
+		t.Errorf("log = \n%s, want \n%s", out.String(), want)
+	}
+}
+
+func TestBOStream_WithLogger(t *testing.T) {
+	var out bytes.Buffer
+	p := NewBOStream(binary.BigEndian, new(bytes.Buffer)).WithLogger(newTestLogger(&out, slog.LevelDebug))
+	p.Label("payload").WriteBytes(bytes.Repeat([]byte{0xab}, 20)).WriteInt8(-1)
+	want := "" +
+		"level=DEBUG msg=\"bitstream: write\" offset=0 label=payload type=bytes size=20 value=abababababababababababababababab...\n" +
+		"level=DEBUG msg=\"bitstream: write\" offset=20 label=\"\" type=int8 size=1 value=-1\n"
+	if out.String() != want {
+		t.Errorf("log = \n%s, want \n%s", out.String(), want)
+	}
+}
+
+func TestBIStream_WithLogger_ErrorLevel(t *testing.T) {
+	var out bytes.Buffer
+	b := NewBIStream(binary.BigEndian, bytes.NewReader([]byte{1})).WithLogger(newTestLogger(&out, slog.LevelError))
+	b.ReadUint8()
+	b.ReadUint8()
+	if lines := strings.Count(out.String(), "\n"); lines != 1 || !strings.Contains(out.String(), "error=EOF") {
+		t.Errorf("log = %q, want the error only", out.String())
+	}
+}
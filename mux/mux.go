@@ -0,0 +1,388 @@
+// Package mux splits one io.ReadWriter into numbered logical streams, each
+// exposed as its own BIStream/BOStream pair.
+//
+// Every frame on the wire is a 9 byte header followed by an optional payload:
+//
+//	stream id  uint32
+//	frame type uint8  (data, window update, close)
+//	length     uint32 (payload length, or the window increment for window updates)
+//
+// Each stream has a receive window. A peer never sends more data than the
+// window allows and the reader hands credit back as it consumes data, so one
+// slow stream never blocks the others. Pending frames are sent round-robin
+// across streams. A stream is forgotten once both ends closed it, and the
+// number of streams the peer may open is capped by Config.MaxStreams.
+package mux
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+const (
+	frameData uint8 = iota
+	frameWindowUpdate
+	frameClose
+)
+
+const headerSize = 9
+
+var (
+	// ErrSessionClosed is returned by streams of a closed session.
+	ErrSessionClosed = errors.New("mux: session closed")
+	// ErrStreamClosed is returned when writing to a closed stream.
+	ErrStreamClosed = errors.New("mux: stream closed")
+	// ErrFlowControl is returned when the peer sends more data than the window allows.
+	ErrFlowControl = errors.New("mux: flow control window exceeded")
+	// ErrUnknownFrame is returned when the peer sends a frame of unknown type.
+	ErrUnknownFrame = errors.New("mux: unknown frame type")
+	// ErrTooManyStreams is returned when the peer opens a stream past Config.MaxStreams.
+	ErrTooManyStreams = errors.New("mux: too many streams")
+)
+
+// Config tunes a Session. Both peers should use the same Window.
+type Config struct {
+	// Window is the per-stream receive window in bytes.
+	Window uint32
+	// MaxFrame is the largest data payload of a single frame.
+	MaxFrame uint32
+	// Endian is the byte order of frame headers and of the streams' BIStream/BOStream.
+	Endian binary.ByteOrder
+	// MaxStreams is the number of streams open at once past which a stream opened by the peer fails the
+	// session with ErrTooManyStreams.
+	MaxStreams int
+}
+
+// DefaultConfig is used by NewSession when config is nil.
+var DefaultConfig = Config{
+	Window:     256 * 1024,
+	MaxFrame:   16 * 1024,
+	Endian:     binary.BigEndian,
+	MaxStreams: 1024,
+}
+
+type frame struct {
+	id   uint32
+	typ  uint8
+	n    uint32
+	data []byte
+}
+
+// Session multiplexes logical streams over one connection.
+type Session struct {
+	conn   io.ReadWriter
+	config Config
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	streams map[uint32]*Stream
+	control []frame
+	ready   []*Stream
+	err     error
+}
+
+// NewSession start a session over conn. Both ends of conn must run a session.
+func NewSession(conn io.ReadWriter, config *Config) *Session {
+	s := &Session{
+		conn:    conn,
+		config:  DefaultConfig,
+		streams: make(map[uint32]*Stream),
+	}
+	if config != nil {
+		s.config = *config
+	}
+	if 0 == s.config.Window {
+		s.config.Window = DefaultConfig.Window
+	}
+	if 0 == s.config.MaxFrame {
+		s.config.MaxFrame = DefaultConfig.MaxFrame
+	}
+	if nil == s.config.Endian {
+		s.config.Endian = DefaultConfig.Endian
+	}
+	if 0 == s.config.MaxStreams {
+		s.config.MaxStreams = DefaultConfig.MaxStreams
+	}
+	s.cond = sync.NewCond(&s.mu)
+	go s.sendLoop()
+	go s.recvLoop()
+	return s
+}
+
+// Stream returns the logical stream with the given id, creating it if needed. Once both ends closed a
+// stream, Stream returns a new one for its id.
+func (s *Session) Stream(id uint32) *Stream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stream(id)
+}
+
+func (s *Session) stream(id uint32) *Stream {
+	st, ok := s.streams[id]
+	if !ok {
+		st = &Stream{
+			id:        id,
+			s:         s,
+			credit:    s.config.Window,
+			recvAvail: s.config.Window,
+		}
+		st.reader = bitstream.NewBIStream(s.config.Endian, st)
+		st.writer = bitstream.NewBOStream(s.config.Endian, st)
+		s.streams[id] = st
+	}
+	return st
+}
+
+// release forget st once both ends closed it. Its unread data can still be read.
+func (s *Session) release(st *Stream) {
+	if st.closed && st.remoteClosed && s.streams[st.id] == st {
+		delete(s.streams, st.id)
+	}
+}
+
+// Err returns the error that terminated the session, if any.
+func (s *Session) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close terminate the session and close the underlying connection if it is an io.Closer.
+func (s *Session) Close() error {
+	s.fail(ErrSessionClosed)
+	if c, ok := s.conn.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (s *Session) fail(err error) {
+	s.mu.Lock()
+	if nil == s.err {
+		s.err = err
+	}
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// next pick the next frame to send: control frames first, then one data frame per stream in turn.
+func (s *Session) next() (frame, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for 0 == len(s.control) && 0 == len(s.ready) && nil == s.err {
+		s.cond.Wait()
+	}
+	if s.err != nil {
+		return frame{}, false
+	}
+	if len(s.control) > 0 {
+		f := s.control[0]
+		s.control = s.control[1:]
+		return f, true
+	}
+	st := s.ready[0]
+	s.ready = s.ready[1:]
+	f := st.pending[0]
+	st.pending = st.pending[1:]
+	if len(st.pending) > 0 {
+		s.ready = append(s.ready, st)
+	} else {
+		st.queued = false
+	}
+	return f, true
+}
+
+func (s *Session) sendLoop() {
+	buf := new(bytes.Buffer)
+	w := bitstream.NewBOStream(s.config.Endian, buf)
+	for {
+		f, ok := s.next()
+		if !ok {
+			return
+		}
+		buf.Reset()
+		w.WriteUint32(f.id).WriteUint8(f.typ).WriteUint32(f.n).WriteBytes(f.data)
+		if _, err := s.conn.Write(buf.Bytes()); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+}
+
+func (s *Session) recvLoop() {
+	r := bitstream.NewBIStream(s.config.Endian, s.conn)
+	var (
+		id  uint32
+		typ uint8
+		n   uint32
+	)
+	for {
+		if err := r.FetchUint32(&id).FetchUint8(&typ).FetchUint32(&n).Error(); err != nil {
+			s.fail(err)
+			return
+		}
+		var data []byte
+		if frameData == typ {
+			avail := s.config.Window
+			s.mu.Lock()
+			if st, ok := s.streams[id]; ok {
+				avail = st.recvAvail
+			}
+			s.mu.Unlock()
+			if n > avail {
+				s.fail(ErrFlowControl)
+				return
+			}
+			if err := r.FetchBytes(&data, uint64(n)).Error(); err != nil {
+				s.fail(err)
+				return
+			}
+		}
+		if err := s.dispatch(id, typ, n, data); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+}
+
+func (s *Session) dispatch(id uint32, typ uint8, n uint32, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if typ > frameClose {
+		return ErrUnknownFrame
+	}
+	st, ok := s.streams[id]
+	if !ok {
+		if frameWindowUpdate == typ {
+			// credit for a stream both ends closed
+			return nil
+		}
+		if len(s.streams) >= s.config.MaxStreams {
+			return ErrTooManyStreams
+		}
+		st = s.stream(id)
+	}
+	switch typ {
+	case frameData:
+		st.recvAvail -= n
+		st.recv.Write(data)
+	case frameWindowUpdate:
+		st.credit += n
+	case frameClose:
+		st.remoteClosed = true
+		s.release(st)
+	}
+	s.cond.Broadcast()
+	return nil
+}
+
+// Stream is one logical stream of a Session. It is an io.ReadWriteCloser.
+type Stream struct {
+	id     uint32
+	s      *Session
+	reader *bitstream.BIStream
+	writer *bitstream.BOStream
+
+	// guarded by s.mu
+	recv         bytes.Buffer
+	recvAvail    uint32
+	consumed     uint32
+	remoteClosed bool
+	credit       uint32
+	pending      []frame
+	queued       bool
+	closed       bool
+}
+
+// ID returns the stream number.
+func (st *Stream) ID() uint32 {
+	return st.id
+}
+
+// Reader returns the BIStream reading from this stream.
+func (st *Stream) Reader() *bitstream.BIStream {
+	return st.reader
+}
+
+// Writer returns the BOStream writing to this stream.
+func (st *Stream) Writer() *bitstream.BOStream {
+	return st.writer
+}
+
+// Read read data sent by the peer on this stream. Returns io.EOF once the peer closed the stream.
+func (st *Stream) Read(p []byte) (int, error) {
+	s := st.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for 0 == st.recv.Len() && !st.remoteClosed && nil == s.err {
+		s.cond.Wait()
+	}
+	if 0 == st.recv.Len() {
+		if st.remoteClosed {
+			return 0, io.EOF
+		}
+		return 0, s.err
+	}
+	n, _ := st.recv.Read(p)
+	st.consumed += uint32(n)
+	if st.consumed >= s.config.Window/2 || 0 == st.recv.Len() {
+		st.recvAvail += st.consumed
+		s.control = append(s.control, frame{id: st.id, typ: frameWindowUpdate, n: st.consumed})
+		st.consumed = 0
+		s.cond.Broadcast()
+	}
+	return n, nil
+}
+
+// Write queue p for sending, blocking while the peer's window is exhausted.
+func (st *Stream) Write(p []byte) (int, error) {
+	s := st.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for len(p) > 0 {
+		for 0 == st.credit && !st.closed && nil == s.err {
+			s.cond.Wait()
+		}
+		if s.err != nil {
+			return n, s.err
+		}
+		if st.closed {
+			return n, ErrStreamClosed
+		}
+		k := min(uint32(len(p)), st.credit, s.config.MaxFrame)
+		st.credit -= k
+		st.enqueue(frame{id: st.id, typ: frameData, n: k, data: bytes.Clone(p[:k])})
+		p = p[k:]
+		n += int(k)
+	}
+	return n, nil
+}
+
+// Close send a close frame after any pending data. The peer reads io.EOF once it has drained the stream.
+func (st *Stream) Close() error {
+	s := st.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st.closed {
+		return nil
+	}
+	st.closed = true
+	st.enqueue(frame{id: st.id, typ: frameClose})
+	s.release(st)
+	return s.err
+}
+
+func (st *Stream) enqueue(f frame) {
+	st.pending = append(st.pending, f)
+	if !st.queued {
+		st.queued = true
+		st.s.ready = append(st.s.ready, st)
+	}
+	st.s.cond.Broadcast()
+}
@@ -0,0 +1,170 @@
+package mux
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newPipeSessions(config *Config) (*Session, *Session) {
+	a, b := net.Pipe()
+	return NewSession(a, config), NewSession(b, config)
+}
+
+func TestSession_Streams(t *testing.T) {
+	type msg struct {
+		U16 uint16
+		S   string
+	}
+	tests := []struct {
+		name string
+		id   uint32
+		want msg
+	}{
+		{name: "TestSession_Streams_control", id: 0, want: msg{U16: 1, S: "control"}},
+		{name: "TestSession_Streams_telemetry", id: 1, want: msg{U16: 2, S: "telemetry"}},
+		{name: "TestSession_Streams_bulk", id: 2, want: msg{U16: 3, S: "bulk"}},
+	}
+	client, server := newPipeSessions(nil)
+	defer client.Close()
+	defer server.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := client.Stream(tt.id).Writer()
+			w.WriteUint16(tt.want.U16).WriteString(tt.want.S)
+			if w.Error() != nil {
+				t.Fatalf("Write() has error %v", w.Error())
+			}
+			got := msg{}
+			r := server.Stream(tt.id).Reader()
+			r.FetchUint16(&got.U16).FetchString(&got.S)
+			if r.Error() != nil {
+				t.Fatalf("Read() has error %v", r.Error())
+			}
+			if got != tt.want {
+				t.Errorf("Read() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSession_FlowControl(t *testing.T) {
+	client, server := newPipeSessions(&Config{Window: 1024, MaxFrame: 100})
+	defer client.Close()
+	defer server.Close()
+
+	want := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	go func() {
+		st := client.Stream(7)
+		st.Writer().WriteBytes(want)
+		st.Close()
+	}()
+	got, err := io.ReadAll(server.Stream(7))
+	if err != nil {
+		t.Fatalf("ReadAll() has error %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("ReadAll() got %d bytes, want %d", len(got), len(want))
+	}
+}
+
+func TestSession_Fairness(t *testing.T) {
+	client, server := newPipeSessions(&Config{Window: 4096, MaxFrame: 512})
+	defer client.Close()
+	defer server.Close()
+
+	// bulk is never read, so its window fills up; control must still get through.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		client.Stream(2).Write(make([]byte, 1<<20))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	client.Stream(0).Writer().WriteUint32(0xdeadbeef)
+
+	done := make(chan uint32)
+	go func() {
+		v, _ := server.Stream(0).Reader().ReadUint32()
+		done <- v
+	}()
+	select {
+	case v := <-done:
+		if v != 0xdeadbeef {
+			t.Errorf("ReadUint32() = %x, want %x", v, 0xdeadbeef)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("control stream blocked behind bulk stream")
+	}
+	client.Close()
+	wg.Wait()
+}
+
+func TestSession_Close(t *testing.T) {
+	client, server := newPipeSessions(nil)
+	client.Close()
+	_, err := server.Stream(0).Reader().ReadByte()
+	if nil == err {
+		t.Errorf("ReadByte() after Close() want error")
+	}
+	client.Stream(0).Writer().WriteByte(1)
+	if !errors.Is(client.Stream(0).Writer().Error(), ErrSessionClosed) {
+		t.Errorf("WriteByte() error = %v, want %v", client.Stream(0).Writer().Error(), ErrSessionClosed)
+	}
+}
+
+// streams returns the number of streams s keeps.
+func streams(s *Session) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.streams)
+}
+
+func TestSession_Release(t *testing.T) {
+	client, server := newPipeSessions(nil)
+	defer client.Close()
+	defer server.Close()
+
+	for id := uint32(0); id < 10; id++ {
+		st := client.Stream(id)
+		st.Writer().WriteString("hello")
+		st.Close()
+		peer := server.Stream(id)
+		if s, err := peer.Reader().ReadString(); err != nil || s != "hello" {
+			t.Fatalf("ReadString() = %q, %v, want hello, nil", s, err)
+		}
+		if eof, err := peer.Reader().AtEOF(); !eof || err != nil {
+			t.Fatalf("AtEOF() = %v, %v, want true, nil", eof, err)
+		}
+		peer.Close()
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for streams(client)+streams(server) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("sessions keep %d and %d streams, want 0", streams(client), streams(server))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSession_MaxStreams(t *testing.T) {
+	a, b := net.Pipe()
+	client, server := NewSession(a, nil), NewSession(b, &Config{MaxStreams: 2})
+	defer client.Close()
+	defer server.Close()
+
+	for id := uint32(0); id < 3; id++ {
+		client.Stream(id).Write([]byte{1})
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !errors.Is(server.Err(), ErrTooManyStreams) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Err() = %v, want %v", server.Err(), ErrTooManyStreams)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
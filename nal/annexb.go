@@ -0,0 +1,90 @@
+package nal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+// Scanner splits an Annex-B byte stream into NAL units at 0x000001 and 0x00000001 start codes.
+type Scanner struct {
+	r       *bufio.Reader
+	started bool
+	nal     []byte
+}
+
+// NewScanner returns a Scanner reading an Annex-B byte stream from r.
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{r: bufio.NewReader(r)}
+}
+
+// ReadNAL returns the next NAL unit as stored in the stream, with its emulation prevention bytes and without
+// start code or trailing zero bytes. Returns io.EOF after the last NAL unit.
+func (s *Scanner) ReadNAL() ([]byte, error) {
+	if !s.started {
+		if err := s.skipToStartCode(); err != nil {
+			return nil, err
+		}
+		s.started = true
+	}
+	for {
+		s.nal = s.nal[:0]
+		zeros := 0
+		for {
+			c, err := s.r.ReadByte()
+			if err == io.EOF {
+				s.nal = bytes.TrimRight(s.nal, "\x00")
+				if 0 == len(s.nal) {
+					return nil, io.EOF
+				}
+				return bytes.Clone(s.nal), nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if 1 == c && zeros >= 2 {
+				s.nal = s.nal[:len(s.nal)-zeros]
+				break
+			}
+			if 0 == c {
+				zeros++
+			} else {
+				zeros = 0
+			}
+			s.nal = append(s.nal, c)
+		}
+		if len(s.nal) > 0 {
+			return bytes.Clone(s.nal), nil
+		}
+	}
+}
+
+// Next returns a BIStream over the RBSP of the next NAL unit, header included.
+func (s *Scanner) Next() (*bitstream.BIStream, error) {
+	nal, err := s.ReadNAL()
+	if err != nil {
+		return nil, err
+	}
+	return bitstream.NewBIStream(binary.BigEndian, NewRBSPReader(bytes.NewReader(nal))), nil
+}
+
+func (s *Scanner) skipToStartCode() error {
+	zeros := 0
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if 1 == c && zeros >= 2 {
+			return nil
+		}
+		if 0 == c {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package nal
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestScanner_ReadNAL(t *testing.T) {
+	tests := []struct {
+		name string
+		args []byte
+		want [][]byte
+	}{
+		{name: "TestScanner_ReadNAL_ThreeByte", args: []byte{0, 0, 1, 0x67, 0x42, 0, 0, 1, 0x68, 0xce}, want: [][]byte{{0x67, 0x42}, {0x68, 0xce}}},
+		{name: "TestScanner_ReadNAL_FourByte", args: []byte{0, 0, 0, 1, 0x67, 0x42, 0, 0, 0, 1, 0x68, 0xce, 0, 0}, want: [][]byte{{0x67, 0x42}, {0x68, 0xce}}},
+		{name: "TestScanner_ReadNAL_Leading", args: []byte{0xff, 0xee, 0, 0, 1, 0x65, 0, 0, 3, 1}, want: [][]byte{{0x65, 0, 0, 3, 1}}},
+		{name: "TestScanner_ReadNAL_Empty", args: []byte{0, 0, 1, 0, 0, 1, 0x09, 0xf0}, want: [][]byte{{0x09, 0xf0}}},
+		{name: "TestScanner_ReadNAL_None", args: []byte{0x01, 0x02}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScanner(bytes.NewReader(tt.args))
+			var got [][]byte
+			for {
+				nal, err := s.ReadNAL()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("ReadNAL() has error %v", err)
+				}
+				got = append(got, nal)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadNAL() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanner_Next(t *testing.T) {
+	s := NewScanner(bytes.NewReader([]byte{0, 0, 0, 1, 0x65, 0, 0, 3, 1, 0xaa, 0, 0, 1, 0x41}))
+	nal, err := s.Next()
+	if err != nil {
+		t.Fatalf("Next() has error %v", err)
+	}
+	header, _ := nal.ReadByte()
+	payload, err := nal.ReadUint32()
+	if err != nil || header != 0x65 || payload != 0x000001aa {
+		t.Errorf("Next() = %#x %#x, want 0x65 0x1aa", header, payload)
+	}
+	if _, err := nal.ReadByte(); err != io.EOF {
+		t.Errorf("ReadByte() past NAL error = %v, want %v", err, io.EOF)
+	}
+}
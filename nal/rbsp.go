@@ -0,0 +1,99 @@
+// Package nal handles the byte layer of H.264/HEVC NAL units: emulation prevention bytes and Annex-B start codes.
+//
+// Inside a NAL unit the encoder inserts 0x03 after any two zero bytes that are followed by a byte <= 0x03, so
+// the payload never contains a start code. RBSPReader removes those bytes and RBSPWriter inserts them, which
+// lets a BIStream or BOStream work on the raw byte sequence payload (RBSP):
+//
+//	r := bitstream.NewBIStream(binary.BigEndian, nal.NewRBSPReader(payload))
+package nal
+
+import (
+	"bufio"
+	"io"
+)
+
+const emulationPrevention = 0x03
+
+// RBSPReader strips emulation prevention bytes from a NAL unit payload.
+type RBSPReader struct {
+	r     io.ByteReader
+	zeros int
+}
+
+// NewRBSPReader returns a RBSPReader reading the NAL unit payload from r.
+func NewRBSPReader(r io.Reader) *RBSPReader {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &RBSPReader{r: br}
+}
+
+func (r *RBSPReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		c, err := r.r.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		if r.zeros >= 2 && emulationPrevention == c {
+			r.zeros = 0
+			continue
+		}
+		if 0 == c {
+			r.zeros++
+		} else {
+			r.zeros = 0
+		}
+		p[n] = c
+		n++
+	}
+	return n, nil
+}
+
+// RBSPWriter inserts emulation prevention bytes into a NAL unit payload.
+type RBSPWriter struct {
+	w     io.Writer
+	zeros int
+	last  byte
+	buf   []byte
+}
+
+// NewRBSPWriter returns a RBSPWriter writing the escaped NAL unit payload to w.
+func NewRBSPWriter(w io.Writer) *RBSPWriter {
+	return &RBSPWriter{w: w}
+}
+
+func (w *RBSPWriter) Write(p []byte) (int, error) {
+	w.buf = w.buf[:0]
+	for _, c := range p {
+		if w.zeros >= 2 && c <= emulationPrevention {
+			w.buf = append(w.buf, emulationPrevention)
+			w.zeros = 0
+		}
+		if 0 == c {
+			w.zeros++
+		} else {
+			w.zeros = 0
+		}
+		w.buf = append(w.buf, c)
+	}
+	if len(p) > 0 {
+		w.last = p[len(p)-1]
+	}
+	if _, err := w.w.Write(w.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close end the NAL unit. A payload ending with a zero byte gets a final 0x03, as the specification requires.
+// It does not close the underlying io.Writer.
+func (w *RBSPWriter) Close() error {
+	if w.zeros > 0 && 0 == w.last {
+		w.zeros = 0
+		_, err := w.w.Write([]byte{emulationPrevention})
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package nal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+func TestRBSPWriter_Write(t *testing.T) {
+	tests := []struct {
+		name string
+		args []byte
+		want []byte
+	}{
+		{name: "TestRBSPWriter_Write_None", args: []byte{0x00, 0x04, 0x00, 0x00, 0x04}, want: []byte{0x00, 0x04, 0x00, 0x00, 0x04}},
+		{name: "TestRBSPWriter_Write_StartCode", args: []byte{0x00, 0x00, 0x01}, want: []byte{0x00, 0x00, 0x03, 0x01}},
+		{name: "TestRBSPWriter_Write_Zeros", args: []byte{0x00, 0x00, 0x00, 0x00}, want: []byte{0x00, 0x00, 0x03, 0x00, 0x00, 0x03}},
+		{name: "TestRBSPWriter_Write_Three", args: []byte{0x11, 0x00, 0x00, 0x03, 0x22}, want: []byte{0x11, 0x00, 0x00, 0x03, 0x03, 0x22}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			w := NewRBSPWriter(buf)
+			// split writes must escape like a single write
+			for _, c := range tt.args {
+				w.Write([]byte{c})
+			}
+			w.Close()
+			if !reflect.DeepEqual(buf.Bytes(), tt.want) {
+				t.Errorf("Write() = %x, want %x", buf.Bytes(), tt.want)
+			}
+			got, err := io.ReadAll(NewRBSPReader(bytes.NewReader(buf.Bytes())))
+			if err != nil || !reflect.DeepEqual(got, tt.args) {
+				t.Errorf("Read() = %x, want %x", got, tt.args)
+			}
+		})
+	}
+}
+
+func TestRBSPReader_BIStream(t *testing.T) {
+	buf := new(bytes.Buffer)
+	rw := NewRBSPWriter(buf)
+	bitstream.NewBOStream(binary.BigEndian, rw).WriteUint32(1).WriteUint16(0).WriteUint32(0x00000302)
+	rw.Close()
+
+	r := bitstream.NewBIStream(binary.BigEndian, NewRBSPReader(buf))
+	var (
+		a, c uint32
+		b    uint16
+	)
+	r.FetchUint32(&a).FetchUint16(&b).FetchUint32(&c)
+	if r.Error() != nil || a != 1 || b != 0 || c != 0x00000302 {
+		t.Errorf("Read() = %v %v %#x, %v", a, b, c, r.Error())
+	}
+}
@@ -0,0 +1,568 @@
+package bitstream
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrPackFormat is returned for a format string that Pack, Unpack or CalcSize can not parse.
+var ErrPackFormat = errors.New("bitstream: invalid pack format")
+
+// packItem is a code of a format string with its repeat count. For s and p the count is the length.
+type packItem struct {
+	code  byte
+	count int
+}
+
+// packFormat is a compiled format string. A nil order means the endian of the stream.
+type packFormat struct {
+	order  binary.ByteOrder
+	items  []packItem
+	size   int
+	values int
+}
+
+var (
+	packFormats sync.Map
+	// packFormatCount is the number of formats in packFormats
+	packFormatCount atomic.Int64
+)
+
+// maxPackFormats is the number of formats cached, the others are compiled on each call, so that formats
+// built from input do not grow the cache without bound.
+const maxPackFormats = 256
+
+// maxPackSize is the largest size of a format, so that Pack can allocate its buffer.
+const maxPackSize = math.MaxInt32
+
+// packSize returns the size of one value of code.
+func packSize(code byte) int {
+	switch code {
+	case 'x', 'c', 'b', 'B', '?', 's', 'p':
+		return 1
+	case 'h', 'H', 'e':
+		return 2
+	case 'i', 'I', 'l', 'L', 'f':
+		return 4
+	case 'q', 'Q', 'd':
+		return 8
+	}
+	return 0
+}
+
+// compileFormat parse format like the struct module of Python, without native sizes and alignment.
+// The first maxPackFormats valid formats are cached.
+func compileFormat(format string) (*packFormat, error) {
+	if f, ok := packFormats.Load(format); ok {
+		return f.(*packFormat), nil
+	}
+	f := &packFormat{}
+	s := format
+	if len(s) > 0 {
+		switch s[0] {
+		case '<':
+			f.order = binary.LittleEndian
+		case '>', '!':
+			f.order = binary.BigEndian
+		case '=':
+			f.order = binary.NativeEndian
+		}
+		if f.order != nil {
+			s = s[1:]
+		}
+	}
+	for i := 0; i < len(s); {
+		if ' ' == s[i] || '\t' == s[i] || '\n' == s[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		count := 1
+		if j > i {
+			n, err := strconv.Atoi(s[i:j])
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: %v", ErrPackFormat, format, err)
+			}
+			count = n
+		}
+		if j == len(s) {
+			return nil, fmt.Errorf("%w %q: count without code", ErrPackFormat, format)
+		}
+		code := s[j]
+		size := packSize(code)
+		if 0 == size {
+			return nil, fmt.Errorf("%w %q: unknown code %q", ErrPackFormat, format, code)
+		}
+		switch code {
+		case 'x':
+		case 's', 'p':
+			f.values++
+		default:
+			f.values += count
+		}
+		if count > (maxPackSize-f.size)/size {
+			return nil, fmt.Errorf("%w %q: size exceeds %d bytes", ErrPackFormat, format, maxPackSize)
+		}
+		f.size += size * count
+		f.items = append(f.items, packItem{code: code, count: count})
+		i = j + 1
+	}
+	if packFormatCount.Add(1) > maxPackFormats {
+		packFormatCount.Add(-1)
+	} else if _, loaded := packFormats.LoadOrStore(format, f); loaded {
+		packFormatCount.Add(-1)
+	}
+	return f, nil
+}
+
+// CalcSize returns the number of bytes Pack writes for format and an error if exists. The size of a format is
+// at most math.MaxInt32 bytes.
+func CalcSize(format string) (int, error) {
+	f, err := compileFormat(format)
+	if err != nil {
+		return 0, err
+	}
+	return f.size, nil
+}
+
+// Pack write values as described by format, like struct.pack of Python. format starts with an optional byte
+// order: < little endian, > and ! big endian, = native endian, or the endian of the stream when it is missing.
+// Each code may be preceded by a count:
+//
+//	x       pad byte, takes no value
+//	c       byte, []byte or string of length 1
+//	b B     int8, uint8
+//	?       bool
+//	h H     int16, uint16
+//	i I l L int32, uint32
+//	q Q     int64, uint64
+//	e f d   float16, float32, float64
+//	s       []byte or string of count bytes, truncated or padded with zeros
+//	p       pascal string, a length byte followed by count-1 bytes
+//
+// Integers may be any Go integer type, they are checked against the range of the code.
+func (p *BOStream) Pack(format string, values ...any) *BOStream {
+	return interceptWrite(p, "pack", values, func() *BOStream {
+		return p.pack(format, values)
+	})
+}
+
+func (p *BOStream) pack(format string, values []any) *BOStream {
+	return p.catchError(func() {
+		f, err := compileFormat(format)
+		if err != nil {
+			p.err = err
+			return
+		}
+		if len(values) != f.values {
+			p.err = fmt.Errorf("bitstream: pack %q needs %d values, got %d", format, f.values, len(values))
+			return
+		}
+		order := f.order
+		if nil == order {
+			order = p.endian
+		}
+		buf := make([]byte, 0, f.size)
+		for _, it := range f.items {
+			switch it.code {
+			case 'x':
+				buf = append(buf, make([]byte, it.count)...)
+				continue
+			case 's', 'p':
+				if buf, err = packString(buf, it, values[0]); err != nil {
+					p.err = fmt.Errorf("bitstream: pack %q: %w", format, err)
+					return
+				}
+				values = values[1:]
+				continue
+			}
+			for k := 0; k < it.count; k++ {
+				if buf, err = packValue(buf, it.code, order, values[0]); err != nil {
+					p.err = fmt.Errorf("bitstream: pack %q: %w", format, err)
+					return
+				}
+				values = values[1:]
+			}
+		}
+		p.err = p.write(buf)
+	})
+}
+
+// Unpack read values as described by format into ptrs, see Pack for the codes. Integer codes accept a
+// pointer to any Go integer type large enough for the value, c accepts *byte, s and p accept *[]byte and
+// *string.
+func (b *BIStream) Unpack(format string, ptrs ...any) *BIStream {
+	return b.catchError(func() {
+		_, b.err = interceptRead(b, "pack", func() ([]any, error) {
+			if err := b.unpack(format, ptrs); err != nil {
+				return nil, err
+			}
+			if 0 == len(b.chain) {
+				return nil, nil
+			}
+			values := make([]any, len(ptrs))
+			for i, ptr := range ptrs {
+				values[i] = reflect.ValueOf(ptr).Elem().Interface()
+			}
+			return values, nil
+		})
+	})
+}
+
+func (b *BIStream) unpack(format string, ptrs []any) error {
+	f, err := compileFormat(format)
+	if err != nil {
+		return err
+	}
+	if len(ptrs) != f.values {
+		return fmt.Errorf("bitstream: unpack %q needs %d pointers, got %d", format, f.values, len(ptrs))
+	}
+	order := f.order
+	if nil == order {
+		order = b.endian
+	}
+	buf, err := b.ReadBytes(uint64(f.size))
+	if err != nil {
+		return err
+	}
+	for _, it := range f.items {
+		switch it.code {
+		case 'x':
+			buf = buf[it.count:]
+			continue
+		case 's', 'p':
+			if err = unpackString(buf[:it.count], it.code, ptrs[0]); err != nil {
+				return fmt.Errorf("bitstream: unpack %q: %w", format, err)
+			}
+			buf, ptrs = buf[it.count:], ptrs[1:]
+			continue
+		}
+		size := packSize(it.code)
+		for k := 0; k < it.count; k++ {
+			if err = unpackValue(buf[:size], it.code, order, ptrs[0]); err != nil {
+				return fmt.Errorf("bitstream: unpack %q: %w", format, err)
+			}
+			buf, ptrs = buf[size:], ptrs[1:]
+		}
+	}
+	return nil
+}
+
+func packString(buf []byte, it packItem, v any) ([]byte, error) {
+	var data []byte
+	switch v := v.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return nil, fmt.Errorf("%c needs []byte or string, got %T", it.code, v)
+	}
+	field := make([]byte, it.count)
+	if 'p' == it.code && it.count > 0 {
+		n := min(len(data), it.count-1, 255)
+		field[0] = byte(n)
+		copy(field[1:], data[:n])
+	} else {
+		copy(field, data)
+	}
+	return append(buf, field...), nil
+}
+
+func unpackString(field []byte, code byte, ptr any) error {
+	if 'p' == code && len(field) > 0 {
+		n := min(int(field[0]), len(field)-1)
+		field = field[1 : 1+n]
+	}
+	switch ptr := ptr.(type) {
+	case *[]byte:
+		*ptr = append([]byte(nil), field...)
+	case *string:
+		*ptr = string(field)
+	default:
+		return fmt.Errorf("%c needs *[]byte or *string, got %T", code, ptr)
+	}
+	return nil
+}
+
+func packValue(buf []byte, code byte, order binary.ByteOrder, v any) ([]byte, error) {
+	switch code {
+	case 'c':
+		switch v := v.(type) {
+		case byte:
+			return append(buf, v), nil
+		case []byte:
+			if 1 == len(v) {
+				return append(buf, v[0]), nil
+			}
+		case string:
+			if 1 == len(v) {
+				return append(buf, v[0]), nil
+			}
+		}
+		return nil, fmt.Errorf("c needs a byte or a []byte or string of length 1, got %v", v)
+	case '?':
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("? needs bool, got %T", v)
+		}
+		if b {
+			return append(buf, 1), nil
+		}
+		return append(buf, 0), nil
+	case 'e', 'f', 'd':
+		var x float64
+		switch v := v.(type) {
+		case float32:
+			x = float64(v)
+		case float64:
+			x = v
+		default:
+			return nil, fmt.Errorf("%c needs float32 or float64, got %T", code, v)
+		}
+		switch code {
+		case 'e':
+			h, err := float16bits(x)
+			if err != nil {
+				return nil, err
+			}
+			return appendUint(buf, order, 2, uint64(h)), nil
+		case 'f':
+			return appendUint(buf, order, 4, uint64(math.Float32bits(float32(x)))), nil
+		}
+		return appendUint(buf, order, 8, math.Float64bits(x)), nil
+	}
+	size := packSize(code)
+	signed := code >= 'a'
+	n, u, unsigned, ok := integer(v)
+	if !ok {
+		return nil, fmt.Errorf("%c needs an integer, got %T", code, v)
+	}
+	bits := uint(8 * size)
+	inRange := false
+	switch {
+	case signed && unsigned:
+		inRange = u <= math.MaxInt64>>(64-bits)
+		n = int64(u)
+	case signed:
+		inRange = n >= math.MinInt64>>(64-bits) && n <= math.MaxInt64>>(64-bits)
+	case unsigned:
+		inRange = u <= math.MaxUint64>>(64-bits)
+	default:
+		inRange = n >= 0 && uint64(n) <= math.MaxUint64>>(64-bits)
+		u = uint64(n)
+	}
+	if !inRange {
+		return nil, fmt.Errorf("%v is out of the range of %c", v, code)
+	}
+	if signed {
+		u = uint64(n)
+	}
+	return appendUint(buf, order, size, u), nil
+}
+
+func appendUint(buf []byte, order binary.ByteOrder, size int, u uint64) []byte {
+	buf = append(buf, make([]byte, size)...)
+	field := buf[len(buf)-size:]
+	switch size {
+	case 1:
+		field[0] = byte(u)
+	case 2:
+		order.PutUint16(field, uint16(u))
+	case 4:
+		order.PutUint32(field, uint32(u))
+	case 8:
+		order.PutUint64(field, u)
+	}
+	return buf
+}
+
+// float16bits returns the IEEE 754 half precision bits of f, rounded to nearest even.
+func float16bits(f float64) (uint16, error) {
+	var sign uint16
+	if math.Signbit(f) {
+		sign, f = 0x8000, -f
+	}
+	switch {
+	case math.IsNaN(f):
+		return 0x7e00, nil
+	case math.IsInf(f, 0):
+		return sign | 0x7c00, nil
+	case f >= 65520:
+		return 0, fmt.Errorf("%v is out of the range of e", f)
+	case f < 0x1p-14:
+		// subnormal, rounding up to 1024 gives the smallest normal number
+		return sign | uint16(math.RoundToEven(f*0x1p24)), nil
+	}
+	frac, exp := math.Frexp(f)
+	m := math.RoundToEven((2*frac - 1) * 1024)
+	exp--
+	if 1024 == m {
+		m, exp = 0, exp+1
+	}
+	return sign | uint16(exp+15)<<10 | uint16(m), nil
+}
+
+func float16frombits(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp, m := int(h>>10&0x1f), float64(h&0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(m, -24)
+	case 0x1f:
+		if 0 == m {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(1+m/1024, exp-15)
+}
+
+// integer returns v as int64, or as uint64 when unsigned is true.
+func integer(v any) (n int64, u uint64, unsigned bool, ok bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), 0, false, true
+	case int8:
+		return int64(v), 0, false, true
+	case int16:
+		return int64(v), 0, false, true
+	case int32:
+		return int64(v), 0, false, true
+	case int64:
+		return v, 0, false, true
+	case uint:
+		return 0, uint64(v), true, true
+	case uint8:
+		return 0, uint64(v), true, true
+	case uint16:
+		return 0, uint64(v), true, true
+	case uint32:
+		return 0, uint64(v), true, true
+	case uint64:
+		return 0, v, true, true
+	case uintptr:
+		return 0, uint64(v), true, true
+	}
+	return 0, 0, false, false
+}
+
+func unpackValue(field []byte, code byte, order binary.ByteOrder, ptr any) error {
+	var u uint64
+	switch len(field) {
+	case 1:
+		u = uint64(field[0])
+	case 2:
+		u = uint64(order.Uint16(field))
+	case 4:
+		u = uint64(order.Uint32(field))
+	case 8:
+		u = order.Uint64(field)
+	}
+	switch code {
+	case 'c':
+		if ptr, ok := ptr.(*byte); ok {
+			*ptr = byte(u)
+			return nil
+		}
+		return fmt.Errorf("c needs *byte, got %T", ptr)
+	case '?':
+		if ptr, ok := ptr.(*bool); ok {
+			*ptr = 0 != u
+			return nil
+		}
+		return fmt.Errorf("? needs *bool, got %T", ptr)
+	case 'e', 'f', 'd':
+		var x float64
+		switch code {
+		case 'e':
+			x = float16frombits(uint16(u))
+		case 'f':
+			x = float64(math.Float32frombits(uint32(u)))
+		default:
+			x = math.Float64frombits(u)
+		}
+		switch ptr := ptr.(type) {
+		case *float32:
+			*ptr = float32(x)
+		case *float64:
+			*ptr = x
+		default:
+			return fmt.Errorf("%c needs *float32 or *float64, got %T", code, ptr)
+		}
+		return nil
+	}
+	if code >= 'a' {
+		shift := 64 - 8*len(field)
+		return setInt(ptr, code, int64(u<<shift)>>shift)
+	}
+	return setUint(ptr, code, u)
+}
+
+func setInt(ptr any, code byte, n int64) error {
+	var ok bool
+	switch ptr := ptr.(type) {
+	case *int:
+		*ptr, ok = int(n), int64(int(n)) == n
+	case *int8:
+		*ptr, ok = int8(n), int64(int8(n)) == n
+	case *int16:
+		*ptr, ok = int16(n), int64(int16(n)) == n
+	case *int32:
+		*ptr, ok = int32(n), int64(int32(n)) == n
+	case *int64:
+		*ptr, ok = n, true
+	case *uint, *uint8, *uint16, *uint32, *uint64, *uintptr:
+		if n < 0 {
+			return fmt.Errorf("%d does not fit in %T", n, ptr)
+		}
+		return setUint(ptr, code, uint64(n))
+	default:
+		return fmt.Errorf("%c needs a pointer to an integer, got %T", code, ptr)
+	}
+	if !ok {
+		return fmt.Errorf("%d does not fit in %T", n, ptr)
+	}
+	return nil
+}
+
+func setUint(ptr any, code byte, u uint64) error {
+	var ok bool
+	switch ptr := ptr.(type) {
+	case *uint:
+		*ptr, ok = uint(u), uint64(uint(u)) == u
+	case *uint8:
+		*ptr, ok = uint8(u), uint64(uint8(u)) == u
+	case *uint16:
+		*ptr, ok = uint16(u), uint64(uint16(u)) == u
+	case *uint32:
+		*ptr, ok = uint32(u), uint64(uint32(u)) == u
+	case *uint64:
+		*ptr, ok = u, true
+	case *uintptr:
+		*ptr, ok = uintptr(u), uint64(uintptr(u)) == u
+	case *int, *int8, *int16, *int32, *int64:
+		if u > math.MaxInt64 {
+			return fmt.Errorf("%d does not fit in %T", u, ptr)
+		}
+		return setInt(ptr, code, int64(u))
+	default:
+		return fmt.Errorf("%c needs a pointer to an integer, got %T", code, ptr)
+	}
+	if !ok {
+		return fmt.Errorf("%d does not fit in %T", u, ptr)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCalcSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      string
+		want      int
+		wantError bool
+	}{
+		{name: "TestCalcSize_Codes", args: ">IhQ4s", want: 18},
+		{name: "TestCalcSize_AllCodes", args: "<xcbB?hHiIlLqQefd", want: 1 + 1 + 1 + 1 + 1 + 2 + 2 + 4 + 4 + 4 + 4 + 8 + 8 + 2 + 4 + 8},
+		{name: "TestCalcSize_Counts", args: "! 3h 2x 10p", want: 18},
+		{name: "TestCalcSize_Empty", args: "", want: 0},
+		{name: "TestCalcSize_UnknownCode", args: "<z", wantError: true},
+		{name: "TestCalcSize_CountWithoutCode", args: "<4", wantError: true},
+		{name: "TestCalcSize_Native", args: "@i", wantError: true},
+		{name: "TestCalcSize_Overflow", args: "9223372036854775807x2x", wantError: true},
+		{name: "TestCalcSize_MultiplyOverflow", args: "4611686018427387904Q", wantError: true},
+		{name: "TestCalcSize_TooLarge", args: "3000000000000000000x", wantError: true},
+		{name: "TestCalcSize_Max", args: "2147483647x", want: math.MaxInt32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CalcSize(tt.args)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("CalcSize() error = %v, wantError %v", err, tt.wantError)
+			}
+			if err != nil && !errors.Is(err, ErrPackFormat) {
+				t.Errorf("CalcSize() error = %v, want ErrPackFormat", err)
+			}
+			if got != tt.want {
+				t.Errorf("CalcSize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBOStream_Pack(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		args      []any
+		want      []byte
+		wantError bool
+	}{
+		{name: "TestBOStream_Pack_BigEndian", format: ">IhQ4s", args: []any{uint32(1), int16(-2), 3, "ab"}, want: []byte{0, 0, 0, 1, 0xff, 0xfe, 0, 0, 0, 0, 0, 0, 0, 3, 'a', 'b', 0, 0}},
+		{name: "TestBOStream_Pack_LittleEndian", format: "<HiB", args: []any{0x1234, -1, 255}, want: []byte{0x34, 0x12, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{name: "TestBOStream_Pack_StreamEndian", format: "H", args: []any{0x1234}, want: []byte{0x34, 0x12}},
+		{name: "TestBOStream_Pack_Network", format: "!H", args: []any{0x1234}, want: []byte{0x12, 0x34}},
+		{name: "TestBOStream_Pack_Counts", format: ">2b3x?", args: []any{1, -1, true}, want: []byte{1, 0xff, 0, 0, 0, 1}},
+		{name: "TestBOStream_Pack_Truncate", format: "2s", args: []any{[]byte("abc")}, want: []byte("ab")},
+		{name: "TestBOStream_Pack_Pascal", format: "4p", args: []any{"abcdef"}, want: []byte{3, 'a', 'b', 'c'}},
+		{name: "TestBOStream_Pack_PascalPad", format: "5p", args: []any{"ab"}, want: []byte{2, 'a', 'b', 0, 0}},
+		{name: "TestBOStream_Pack_Char", format: "3c", args: []any{byte('a'), "b", []byte("c")}, want: []byte("abc")},
+		{name: "TestBOStream_Pack_Floats", format: ">efd", args: []any{1.5, float32(-2), 0.5}, want: []byte{0x3e, 0, 0xc0, 0, 0, 0, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0}},
+		{name: "TestBOStream_Pack_HalfSubnormal", format: ">e", args: []any{math.Ldexp(1, -24)}, want: []byte{0, 1}},
+		{name: "TestBOStream_Pack_HalfMax", format: ">e", args: []any{65504.0}, want: []byte{0x7b, 0xff}},
+		{name: "TestBOStream_Pack_HalfOverflow", format: ">e", args: []any{65520.0}, wantError: true},
+		{name: "TestBOStream_Pack_Range", format: "b", args: []any{128}, wantError: true},
+		{name: "TestBOStream_Pack_Negative", format: "H", args: []any{-1}, wantError: true},
+		{name: "TestBOStream_Pack_UnsignedToSigned", format: "q", args: []any{uint64(math.MaxUint64)}, wantError: true},
+		{name: "TestBOStream_Pack_Count", format: "2h", args: []any{1}, wantError: true},
+		{name: "TestBOStream_Pack_Type", format: "?", args: []any{1}, wantError: true},
+		{name: "TestBOStream_Pack_TooLarge", format: "3000000000000000000x", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p := NewBOStream(binary.LittleEndian, &buf)
+			err := p.Pack(tt.format, tt.args...).Error()
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Pack() error = %v, wantError %v", err, tt.wantError)
+			}
+			if nil == err && !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("Pack() = %v, want %v", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBIStream_Unpack(t *testing.T) {
+	var (
+		u32 uint32
+		i16 int16
+		i   int
+		s   string
+		b   []byte
+		c   byte
+		ok  bool
+		f32 float32
+		f64 float64
+		u8  uint8
+	)
+	tests := []struct {
+		name      string
+		format    string
+		args      []byte
+		ptrs      []any
+		want      []any
+		wantError bool
+	}{
+		{name: "TestBIStream_Unpack_BigEndian", format: ">IhQ4s", args: []byte{0, 0, 0, 1, 0xff, 0xfe, 0, 0, 0, 0, 0, 0, 0, 3, 'a', 'b', 0, 0}, ptrs: []any{&u32, &i16, &i, &b}, want: []any{uint32(1), int16(-2), 3, []byte{'a', 'b', 0, 0}}},
+		{name: "TestBIStream_Unpack_StreamEndian", format: "hx?", args: []byte{0xfe, 0xff, 9, 2}, ptrs: []any{&i, &ok}, want: []any{-2, true}},
+		{name: "TestBIStream_Unpack_Pascal", format: "5pc", args: []byte{2, 'a', 'b', 0, 0, 'z'}, ptrs: []any{&s, &c}, want: []any{"ab", byte('z')}},
+		{name: "TestBIStream_Unpack_Floats", format: ">ef", args: []byte{0x3e, 0, 0xc0, 0, 0, 0}, ptrs: []any{&f64, &f32}, want: []any{1.5, float32(-2)}},
+		{name: "TestBIStream_Unpack_Overflow", format: ">H", args: []byte{1, 0}, ptrs: []any{&u8}, wantError: true},
+		{name: "TestBIStream_Unpack_NegativeToUnsigned", format: "b", args: []byte{0xff}, ptrs: []any{&u8}, wantError: true},
+		{name: "TestBIStream_Unpack_Short", format: "I", args: []byte{1, 2}, ptrs: []any{&u32}, wantError: true},
+		{name: "TestBIStream_Unpack_Type", format: "I", args: []byte{1, 2, 3, 4}, ptrs: []any{&s}, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewBIStream(binary.LittleEndian, bytes.NewReader(tt.args))
+			err := p.Unpack(tt.format, tt.ptrs...).Error()
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Unpack() error = %v, wantError %v", err, tt.wantError)
+			}
+			if err != nil {
+				return
+			}
+			for i, ptr := range tt.ptrs {
+				got := reflect.ValueOf(ptr).Elem().Interface()
+				if !reflect.DeepEqual(got, tt.want[i]) {
+					t.Errorf("Unpack() value %d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPack_RoundTrip(t *testing.T) {
+	const format = "<bBhHiIqQ?3s"
+	var buf bytes.Buffer
+	p := NewBOStream(binary.BigEndian, &buf)
+	if err := p.Pack(format, math.MinInt8, math.MaxUint8, math.MinInt16, math.MaxUint16, math.MinInt32, uint32(math.MaxUint32), int64(math.MinInt64), uint64(math.MaxUint64), false, "xyz").Error(); err != nil {
+		t.Fatalf("Pack() error = %v", err)
+	}
+	if size, _ := CalcSize(format); size != buf.Len() {
+		t.Errorf("CalcSize() = %v, want %v", size, buf.Len())
+	}
+	var (
+		b  int8
+		B  uint8
+		h  int16
+		H  uint16
+		i  int32
+		I  uint32
+		q  int64
+		Q  uint64
+		ok = true
+		s  string
+	)
+	r := NewBIStream(binary.BigEndian, &buf)
+	if err := r.Unpack(format, &b, &B, &h, &H, &i, &I, &q, &Q, &ok, &s).Error(); err != nil {
+		t.Fatalf("Unpack() error = %v", err)
+	}
+	got := []any{b, B, h, H, i, I, q, Q, ok, s}
+	want := []any{int8(math.MinInt8), uint8(math.MaxUint8), int16(math.MinInt16), uint16(math.MaxUint16), int32(math.MinInt32), uint32(math.MaxUint32), int64(math.MinInt64), uint64(math.MaxUint64), false, "xyz"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unpack() = %v, want %v", got, want)
+	}
+}
+
+func TestFloat16(t *testing.T) {
+	for h := 0; h < 0x10000; h++ {
+		f := float16frombits(uint16(h))
+		if math.IsNaN(f) {
+			continue
+		}
+		got, err := float16bits(f)
+		if err != nil || got != uint16(h) {
+			t.Fatalf("float16bits(%v) = %#x, %v, want %#x", f, got, err, h)
+		}
+	}
+}
+
+func Test_compileFormat_Cache(t *testing.T) {
+	for i := 0; i < 2*maxPackFormats; i++ {
+		if _, err := CalcSize(fmt.Sprintf("%dx", i)); err != nil {
+			t.Fatal(err)
+		}
+		CalcSize(fmt.Sprintf("%dz", i))
+	}
+	n := 0
+	packFormats.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	if n > maxPackFormats || int64(n) != packFormatCount.Load() {
+		t.Errorf("cached %d formats, counted %d, want at most %d", n, packFormatCount.Load(), maxPackFormats)
+	}
+	if size, err := CalcSize(fmt.Sprintf("%dx", 2*maxPackFormats)); err != nil || size != 2*maxPackFormats {
+		t.Errorf("CalcSize() = %v, %v past the cache, want %v, nil", size, err, 2*maxPackFormats)
+	}
+}
@@ -0,0 +1,93 @@
+package bitstream
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"errors"
+	"hash"
+	"io"
+)
+
+// ErrRegionMismatch is reported when a region is ended without a matching begin.
+var ErrRegionMismatch = errors.New("bitstream: region end does not match region begin")
+
+type regionKind int
+
+const (
+	regionChecksum regionKind = iota
+	regionHash
+	regionVerified
+	regionCompressed
+	regionSealed
+)
+
+// region is a span of the stream whose bytes are routed through an extra io.Reader or io.Writer.
+// Regions nest, and ending one restores the reader or writer saved when it began.
+type region struct {
+	kind   regionKind
+	hash   hash.Hash
+	buf    *bytes.Buffer
+	closer io.Closer
+	aead   cipher.AEAD
+	nonce  io.Reader
+	reader io.Reader
+	writer io.Writer
+}
+
+func (b *BIStream) pushRegion(r region, reader io.Reader) {
+	r.reader = b.reader
+	b.regions = append(b.regions, r)
+	b.reader = reader
+}
+
+func (b *BIStream) popRegion(kind regionKind) (region, bool) {
+	n := len(b.regions)
+	if 0 == n || b.regions[n-1].kind != kind {
+		if nil == b.err {
+			b.err = ErrRegionMismatch
+		}
+		return region{}, false
+	}
+	r := b.regions[n-1]
+	b.regions = b.regions[:n-1]
+	b.reader = r.reader
+	return r, true
+}
+
+func (p *BOStream) pushRegion(r region, writer io.Writer) {
+	r.writer = p.writer
+	p.regions = append(p.regions, r)
+	p.writer = writer
+}
+
+func (p *BOStream) popRegion(kind regionKind) (region, bool) {
+	n := len(p.regions)
+	if 0 == n || p.regions[n-1].kind != kind {
+		if nil == p.err {
+			p.err = ErrRegionMismatch
+		}
+		return region{}, false
+	}
+	r := p.regions[n-1]
+	p.regions = p.regions[:n-1]
+	p.writer = r.writer
+	return r, true
+}
+
+// buffered reports whether the bytes written are held by a region until it ends.
+func (p *BOStream) buffered() bool {
+	for _, r := range p.regions {
+		if r.buf != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// sink returns the io.Writer the stream was created with.
+func (p *BOStream) sink() io.Writer {
+	if len(p.regions) > 0 {
+		return p.regions[0].writer
+	}
+	return p.writer
+}
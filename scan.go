@@ -0,0 +1,197 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrScanPattern is returned for an empty pattern or an unsupported pattern width.
+var ErrScanPattern = errors.New("bitstream: invalid scan pattern")
+
+// ScanFor advance to the next occurrence of pattern, so that the next read starts with pattern.
+// Returns the number of bytes skipped and an error if exists
+func (b *BIStream) ScanFor(pattern []byte) (int, error) {
+	if 0 == len(pattern) {
+		return 0, ErrScanPattern
+	}
+	var (
+		skipped int
+		err     error
+	)
+	b.catchError(func() {
+		skipped, err = b.scanFor(pattern)
+	})
+	if nil == err {
+		err = b.err
+	}
+	return skipped, err
+}
+
+func (b *BIStream) scanFor(pattern []byte) (int, error) {
+	window := make([]byte, 0, 2*len(pattern))
+	skipped := 0
+	c := make([]byte, 1)
+	for {
+		if _, err := b.peek(c); err != nil {
+			b.consume(window)
+			return skipped + len(window), err
+		}
+		window = append(window, c[0])
+		if len(window) < len(pattern) {
+			continue
+		}
+		if bytes.Equal(window[len(window)-len(pattern):], pattern) {
+			skipped += len(window) - len(pattern)
+			b.consume(window[:len(window)-len(pattern)])
+			b.unread(pattern)
+			return skipped, nil
+		}
+		if len(window) == cap(window) {
+			drop := len(window) - len(pattern) + 1
+			skipped += drop
+			b.consume(window[:drop])
+			window = append(window[:0], window[drop:]...)
+		}
+	}
+}
+
+// ScanForBits advance to the next occurrence of the width low bits of pattern, searched bit by bit with the
+// most significant bit first. The next read starts with the byte holding the first bit of the pattern.
+// Returns the number of bytes skipped, the bit offset of the pattern in that byte and an error if exists.
+// width is at most 64.
+func (b *BIStream) ScanForBits(pattern uint64, width uint) (int, uint, error) {
+	if 0 == width || width > 64 {
+		return 0, 0, ErrScanPattern
+	}
+	var (
+		skipped int
+		offset  uint
+		err     error
+	)
+	b.catchError(func() {
+		skipped, offset, err = b.scanForBits(pattern, width)
+	})
+	if nil == err {
+		err = b.err
+	}
+	return skipped, offset, err
+}
+
+func (b *BIStream) scanForBits(pattern uint64, width uint) (int, uint, error) {
+	mask := uint64(1)<<width - 1
+	pattern &= mask
+	var (
+		reg     uint64
+		nbits   uint
+		history []byte
+		base    int
+	)
+	keep := int(width+7)/8 + 1
+	c := make([]byte, 1)
+	for {
+		if _, err := b.peek(c); err != nil {
+			b.consume(history)
+			return base + len(history), 0, err
+		}
+		history = append(history, c[0])
+		for i := 7; i >= 0; i-- {
+			reg = reg<<1 | uint64(c[0]>>i&1)
+			nbits++
+			if nbits >= width && reg&mask == pattern {
+				start := nbits - width
+				b.consume(history[:int(start/8)-base])
+				b.unread(history[int(start/8)-base:])
+				return int(start / 8), start % 8, nil
+			}
+		}
+		if len(history) > 2*keep {
+			drop := len(history) - keep
+			base += drop
+			b.consume(history[:drop])
+			history = append(history[:0], history[drop:]...)
+		}
+	}
+}
+
+// Resync clear the sticky error and any open region, then advance to the next occurrence of pattern
+// like ScanFor. Returns the number of bytes skipped and an error if exists
+func (b *BIStream) Resync(pattern []byte) (int, error) {
+	b.err = nil
+	if len(b.regions) > 0 {
+		b.reader = b.regions[0].reader
+		b.regions = nil
+	}
+	return b.ScanFor(pattern)
+}
+
+// AtEOF reports whether io.Reader has no byte left, without consuming any.
+func (b *BIStream) AtEOF() (bool, error) {
+	c := make([]byte, 1)
+	if _, err := b.peek(c); err != nil {
+		if err == io.EOF {
+			return true, nil
+		}
+		return false, err
+	}
+	b.unread(c)
+	return false, nil
+}
+
+// peek read buf like readFull, beneath the open regions. The bytes must be given back to unread, or to consume
+// to be seen by the regions.
+func (b *BIStream) peek(buf []byte) (int, error) {
+	return b.readFrom(b.pushback, buf)
+}
+
+// consume feed the open regions with p, bytes read by peek.
+func (b *BIStream) consume(p []byte) {
+	for _, r := range b.regions {
+		if r.hash != nil {
+			r.hash.Write(p)
+		}
+	}
+}
+
+// unread push p back in front of io.Reader, beneath the regions, so that they see the bytes when they are
+// read again.
+func (b *BIStream) unread(p []byte) {
+	b.pushback.buf = append(bytes.Clone(p), b.pushback.buf...)
+	b.nread -= int64(len(p))
+	if b.trace != nil {
+		b.trace.unread(len(p))
+	}
+}
+
+// readFull fill buf from io.Reader. Returns the number of bytes read and an error if exists. The bytes read
+// before an error are counted and traced too.
+func (b *BIStream) readFull(buf []byte) (int, error) {
+	return b.readFrom(b.reader, buf)
+}
+
+func (b *BIStream) readFrom(r io.Reader, buf []byte) (int, error) {
+	n, err := b.readReader(r, buf)
+	if n > 0 {
+		b.nread += int64(n)
+		if b.trace != nil {
+			b.trace.read(buf[:n])
+		}
+	}
+	return n, err
+}
+
+// pushback is the io.Reader of a stream beneath its regions, returning the bytes pushed back by unread before
+// the ones of reader.
+type pushback struct {
+	buf    []byte
+	reader io.Reader
+}
+
+func (r *pushback) Read(buf []byte) (int, error) {
+	if 0 == len(r.buf) {
+		return r.reader.Read(buf)
+	}
+	n := copy(buf, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
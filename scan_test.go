@@ -0,0 +1,200 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"testing"
+)
+
+func TestBIStream_ScanFor(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []byte
+		pattern     []byte
+		wantSkipped int
+		wantNext    []byte
+		wantError   error
+	}{
+		{name: "TestBIStream_ScanFor_AtStart", args: []byte{'R', 'I', 'F', 'F', 1}, pattern: []byte("RIFF"), wantSkipped: 0, wantNext: []byte{'R', 'I', 'F', 'F', 1}},
+		{name: "TestBIStream_ScanFor_Garbage", args: []byte{0, 'R', 'I', 'R', 'I', 'F', 'F', 2}, pattern: []byte("RIFF"), wantSkipped: 3, wantNext: []byte{'R', 'I', 'F', 'F', 2}},
+		{name: "TestBIStream_ScanFor_LongGarbage", args: append(bytes.Repeat([]byte{'R'}, 100), 'R', 'I', 'F', 'F'), pattern: []byte("RIFF"), wantSkipped: 100, wantNext: []byte("RIFF")},
+		{name: "TestBIStream_ScanFor_NotFound", args: []byte{'R', 'I', 'F'}, pattern: []byte("RIFF"), wantSkipped: 3, wantError: io.EOF},
+		{name: "TestBIStream_ScanFor_Empty", args: []byte{1}, pattern: nil, wantError: ErrScanPattern},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewBIStream(binary.BigEndian, bytes.NewReader(tt.args))
+			skipped, err := p.ScanFor(tt.pattern)
+			if err != tt.wantError {
+				t.Fatalf("ScanFor() error = %v, want %v", err, tt.wantError)
+			}
+			if skipped != tt.wantSkipped {
+				t.Errorf("ScanFor() = %v, want %v", skipped, tt.wantSkipped)
+			}
+			if nil == err {
+				next, _ := p.ReadBytes(uint64(len(tt.wantNext)))
+				if !bytes.Equal(next, tt.wantNext) {
+					t.Errorf("ReadBytes() after ScanFor() = %v, want %v", next, tt.wantNext)
+				}
+			}
+		})
+	}
+}
+
+func TestBIStream_ScanForBits(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []byte
+		pattern     uint64
+		width       uint
+		wantSkipped int
+		wantOffset  uint
+		wantNext    byte
+		wantError   error
+	}{
+		{name: "TestBIStream_ScanForBits_MPEG", args: []byte{0x12, 0x34, 0xff, 0xfb, 0x90}, pattern: 0x7ff, width: 11, wantSkipped: 2, wantOffset: 0, wantNext: 0xff},
+		{name: "TestBIStream_ScanForBits_Unaligned", args: []byte{0x00, 0x0b, 0xc0}, pattern: 0x2f, width: 6, wantSkipped: 1, wantOffset: 4, wantNext: 0x0b},
+		{name: "TestBIStream_ScanForBits_NotFound", args: []byte{0x00, 0x00}, pattern: 1, width: 1, wantSkipped: 2, wantError: io.EOF},
+		{name: "TestBIStream_ScanForBits_64", args: []byte{0xaa, 1, 2, 3, 4, 5, 6, 7, 8, 9}, pattern: 0x0102030405060708, width: 64, wantSkipped: 1, wantOffset: 0, wantNext: 1},
+		{name: "TestBIStream_ScanForBits_64Unaligned", args: []byte{0xff, 0x00, 0x08, 0x0c, 0x10, 0x14, 0x18, 0x1c, 0x20, 0x24}, pattern: 0x0203040506070809, width: 64, wantSkipped: 1, wantOffset: 6, wantNext: 0x00},
+		{name: "TestBIStream_ScanForBits_Width", args: []byte{0x00}, pattern: 1, width: 65, wantError: ErrScanPattern},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewBIStream(binary.BigEndian, bytes.NewReader(tt.args))
+			skipped, offset, err := p.ScanForBits(tt.pattern, tt.width)
+			if err != tt.wantError {
+				t.Fatalf("ScanForBits() error = %v, want %v", err, tt.wantError)
+			}
+			if skipped != tt.wantSkipped || offset != tt.wantOffset {
+				t.Errorf("ScanForBits() = %v, %v, want %v, %v", skipped, offset, tt.wantSkipped, tt.wantOffset)
+			}
+			if nil == err {
+				if next, _ := p.ReadByte(); next != tt.wantNext {
+					t.Errorf("ReadByte() after ScanForBits() = %#x, want %#x", next, tt.wantNext)
+				}
+			}
+		})
+	}
+}
+
+func TestBIStream_Resync(t *testing.T) {
+	magic := []byte{0xca, 0xfe}
+	buf := new(bytes.Buffer)
+	w := NewBOStream(binary.BigEndian, buf)
+	for i := uint16(1); i <= 3; i++ {
+		w.WriteBytes(magic).BeginChecksum(crc32.NewIEEE()).WriteUint16(i).EndChecksum()
+	}
+	data := buf.Bytes()
+	data[13] ^= 0xff // corrupt the checksum of the second record
+
+	var (
+		got  []uint16
+		head []byte
+		v    uint16
+	)
+	p := NewBIStream(binary.BigEndian, bytes.NewReader(data))
+	for {
+		p.FetchBytes(&head, 2).BeginChecksum(crc32.NewIEEE()).FetchUint16(&v).EndChecksum()
+		if nil == p.Error() {
+			got = append(got, v)
+			continue
+		}
+		if p.Error() != ErrChecksumMismatch {
+			break
+		}
+		skipped, err := p.Resync(magic)
+		if err != nil || skipped != 0 {
+			t.Fatalf("Resync() = %v, %v, want 0, nil", skipped, err)
+		}
+	}
+	if p.Error() != io.EOF || len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Errorf("Resync() records = %v, %v, want [1 3], %v", got, p.Error(), io.EOF)
+	}
+}
+
+func TestBIStream_ScanFor_Checksum(t *testing.T) {
+	buf := new(bytes.Buffer)
+	NewBOStream(binary.BigEndian, buf).
+		BeginChecksum(crc32.NewIEEE()).WriteBytes([]byte("MAGC")).WriteUint16(7).EndChecksum()
+	tests := []struct {
+		name string
+		scan func(p *BIStream) error
+	}{
+		{name: "TestBIStream_ScanFor_Checksum_Before", scan: func(p *BIStream) error {
+			_, err := p.ScanFor([]byte("MAGC"))
+			p.BeginChecksum(crc32.NewIEEE())
+			return err
+		}},
+		{name: "TestBIStream_ScanFor_Checksum_Inside", scan: func(p *BIStream) error {
+			p.BeginChecksum(crc32.NewIEEE())
+			_, err := p.ScanFor([]byte("MAGC"))
+			return err
+		}},
+		{name: "TestBIStream_ScanFor_Checksum_AtEOF", scan: func(p *BIStream) error {
+			_, err := p.AtEOF()
+			p.BeginChecksum(crc32.NewIEEE())
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewBIStream(binary.BigEndian, bytes.NewReader(buf.Bytes()))
+			if err := tt.scan(p); err != nil {
+				t.Fatalf("scan error = %v", err)
+			}
+			var v uint16
+			p.ExpectBytes([]byte("MAGC")).FetchUint16(&v).EndChecksum()
+			if err := p.Error(); err != nil || v != 7 {
+				t.Errorf("EndChecksum() = %v, %v, want 7, nil", v, err)
+			}
+		})
+	}
+}
+
+func TestBIStream_ScanFor_Error(t *testing.T) {
+	p := NewBIStream(binary.BigEndian, bytes.NewReader([]byte{1, 'B', 'S', 0xff}))
+	p.ExpectUint8(2)
+	want := p.Error()
+	if nil == want {
+		t.Fatal("ExpectUint8() error = nil")
+	}
+	if skipped, err := p.ScanFor([]byte("BS")); err != want || skipped != 0 {
+		t.Errorf("ScanFor() = %v, %v, want 0, %v", skipped, err, want)
+	}
+	if skipped, offset, err := p.ScanForBits(0xff, 8); err != want || skipped != 0 || offset != 0 {
+		t.Errorf("ScanForBits() = %v, %v, %v, want 0, 0, %v", skipped, offset, err, want)
+	}
+	if skipped, err := p.Resync([]byte("BS")); err != nil || skipped != 0 {
+		t.Errorf("Resync() = %v, %v, want 0, nil", skipped, err)
+	}
+}
+
+func TestBIStream_AtEOF_Region(t *testing.T) {
+	buf := new(bytes.Buffer)
+	NewBOStream(binary.BigEndian, buf).
+		BeginChecksum(crc32.NewIEEE()).WriteUint8(1).WriteBytes([]byte{9, 9, 'M', 'G'}).WriteUint8(2).EndChecksum().
+		WriteUint8(3)
+	var v, w, tail uint8
+	p := NewBIStream(binary.BigEndian, bytes.NewReader(buf.Bytes()))
+	p.BeginChecksum(crc32.NewIEEE()).FetchUint8(&v)
+	if eof, err := p.AtEOF(); eof || err != nil {
+		t.Fatalf("AtEOF() = %v, %v, want false, nil", eof, err)
+	}
+	if skipped, err := p.ScanFor([]byte("MG")); skipped != 2 || err != nil {
+		t.Fatalf("ScanFor() = %v, %v, want 2, nil", skipped, err)
+	}
+	p.ExpectBytes([]byte("MG")).FetchUint8(&w)
+	p.AtEOF()
+	p.EndChecksum()
+	p.AtEOF()
+	p.FetchUint8(&tail)
+	if err := p.Error(); err != nil || v != 1 || w != 2 || tail != 3 {
+		t.Errorf("EndChecksum() = %v, %v, %v, %v, want 1, 2, 3, nil", v, w, tail, err)
+	}
+	if eof, err := p.AtEOF(); !eof || err != nil {
+		t.Errorf("AtEOF() at the end = %v, %v, want true, nil", eof, err)
+	}
+}
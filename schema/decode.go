@@ -0,0 +1,469 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+// Decode read the root type from r. Multi-byte fields without an explicit endian use the endian of the
+// description, or the endian of r when the description has none.
+//
+// When an instance uses pos, Decode reads the rest of r into memory first, and pos is an offset in it.
+//
+// When r has a Trace, the operations are labeled with the field ids and user types are sections.
+//
+// Fields with a process are decompressed within the limit set by r.WithDecompressLimit, failing with
+// bitstream.ErrDecompressedTooLarge past it.
+func (s *Schema) Decode(r *bitstream.BIStream) (*Struct, error) {
+	if !s.seekable {
+		return (&decoder{r: r}).decodeType(s.Root, nil, "")
+	}
+	data, err := r.Label(s.ID).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	d := newDecoder(r, data)
+	defer d.nest(r.Trace())()
+	return d.decodeType(s.Root, nil, "")
+}
+
+// decoder keeps the partial byte of bit fields. Any byte aligned read drops the bits left in it.
+type decoder struct {
+	r     *bitstream.BIStream
+	bits  byte
+	nbits int
+	// data is the whole stream of r when it is in memory.
+	data []byte
+}
+
+// newDecoder returns a decoder of data with the byte order and the decompress limit of r.
+func newDecoder(r *bitstream.BIStream, data []byte) *decoder {
+	b := bitstream.NewBIStream(r.Endian(), bytes.NewReader(data)).WithDecompressLimit(r.DecompressLimit())
+	return &decoder{r: b, data: data}
+}
+
+// nest start tracing d, and returns a func adding its trace to the last operation of outer. It does nothing
+// without outer.
+func (d *decoder) nest(outer *bitstream.Trace) func() {
+	if nil == outer {
+		return func() {}
+	}
+	t := bitstream.NewTrace()
+	d.r.WithTrace(t)
+	return func() { outer.Nest(t) }
+}
+
+// annotate set the kind and value of the last traced read, see bitstream.Trace.Annotate.
+func (d *decoder) annotate(kind string, v any) {
+	if t := d.r.Trace(); t != nil {
+		t.Annotate(kind, v)
+	}
+}
+
+// label name the next read of d. A bit field only reads when it starts a new byte.
+func (d *decoder) label(path string) {
+	if 0 == d.nbits {
+		d.r.Label(path[strings.LastIndexByte(path, '.')+1:])
+	}
+}
+
+func (d *decoder) align() {
+	d.nbits = 0
+}
+
+func (d *decoder) readBits(n int) (uint64, error) {
+	var v uint64
+	for n > 0 {
+		if 0 == d.nbits {
+			c, err := d.r.ReadByte()
+			if err != nil {
+				return 0, err
+			}
+			d.bits, d.nbits = c, 8
+		}
+		k := min(n, d.nbits)
+		v = v<<k | uint64(d.bits>>(d.nbits-k))&(1<<k-1)
+		d.nbits -= k
+		n -= k
+	}
+	return v, nil
+}
+
+func (d *decoder) decodeType(t *Type, parent *scope, path string) (*Struct, error) {
+	sc := &scope{t: t, s: NewStruct(), parent: parent, vars: map[string]any{}}
+	sc.root = sc
+	if parent != nil {
+		sc.root = parent.root
+	}
+	pending := map[string]*Field{}
+	for _, f := range t.Instances {
+		pending[f.ID] = f
+	}
+	sc.instance = func(name string) (any, bool, error) {
+		f, ok := pending[name]
+		if !ok {
+			return nil, false, nil
+		}
+		// deleting first also stops instances that refer to themselves
+		delete(pending, name)
+		if err := d.decodeInstance(f, sc, join(path, f.ID)); err != nil {
+			return nil, true, err
+		}
+		v, ok := sc.s.Get(name)
+		return v, ok, nil
+	}
+	for _, f := range t.Seq {
+		if err := d.decodeField(f, sc, join(path, f.ID)); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range t.Instances {
+		if _, _, err := sc.instance(f.ID); err != nil {
+			return nil, err
+		}
+	}
+	return sc.s, nil
+}
+
+// decodeInstance set the value of a value instance, or read a pos instance from its offset.
+func (d *decoder) decodeInstance(f *Field, sc *scope, path string) error {
+	if f.Value != nil {
+		if f.If != nil {
+			ok, err := f.If.evalBool(sc)
+			if err != nil {
+				return fieldError(path, err)
+			}
+			if !ok {
+				return nil
+			}
+		}
+		v, err := f.Value.eval(sc)
+		if err != nil {
+			return fieldError(path, err)
+		}
+		if f.Enum != "" {
+			if v, err = d.enum(f, sc, v); err != nil {
+				return fieldError(path, err)
+			}
+		}
+		sc.s.Set(f.ID, v)
+		return nil
+	}
+	pos, err := f.Pos.evalInt(sc)
+	if err != nil {
+		return fieldError(path, err)
+	}
+	if nil == d.data {
+		return fieldError(path, fmt.Errorf("pos needs the stream in memory"))
+	}
+	if pos < 0 || pos > int64(len(d.data)) {
+		return fieldError(path, fmt.Errorf("pos %d is out of the stream", pos))
+	}
+	sub := &decoder{r: bitstream.NewBIStream(d.r.Endian(), bytes.NewReader(d.data[pos:])), data: d.data}
+	return sub.decodeField(f, sc, path)
+}
+
+func (d *decoder) enum(f *Field, sc *scope, v any) (any, error) {
+	i, ok := toInt(v)
+	if !ok {
+		return nil, fmt.Errorf("%v is not an integer", v)
+	}
+	e := sc.t.lookupEnum(strings.Split(f.Enum, "::"))
+	return EnumValue{Name: e.Values[i], Value: i}, nil
+}
+
+func join(path, id string) string {
+	if "" == path {
+		return id
+	}
+	return path + "." + id
+}
+
+func fieldError(path string, err error) error {
+	if _, ok := err.(*FieldError); ok {
+		return err
+	}
+	return &FieldError{Path: path, Err: err}
+}
+
+func (d *decoder) decodeField(f *Field, sc *scope, path string) error {
+	if f.If != nil {
+		ok, err := f.If.evalBool(sc)
+		if err != nil {
+			return fieldError(path, err)
+		}
+		if !ok {
+			return nil
+		}
+	}
+	if "" == f.Repeat {
+		v, err := d.decodeValue(f, sc, path)
+		if err != nil {
+			return fieldError(path, err)
+		}
+		sc.s.Set(f.ID, v)
+		return nil
+	}
+	list := []any{}
+	sc.s.Set(f.ID, list)
+	defer delete(sc.vars, "_index")
+	defer delete(sc.vars, "_")
+	var count int64 = -1
+	if "expr" == f.Repeat {
+		n, err := f.RepeatExpr.evalInt(sc)
+		if err != nil {
+			return fieldError(path, err)
+		}
+		if n < 0 {
+			return fieldError(path, fmt.Errorf("negative repeat count %d", n))
+		}
+		count = n
+	}
+	for i := int64(0); count < 0 || i < count; i++ {
+		if "eos" == f.Repeat && 0 == d.nbits {
+			eof, err := d.r.AtEOF()
+			if err != nil {
+				return fieldError(path, err)
+			}
+			if eof {
+				break
+			}
+		}
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		sc.vars["_index"] = i
+		v, err := d.decodeValue(f, sc, elemPath)
+		if err != nil {
+			return fieldError(elemPath, err)
+		}
+		list = append(list, v)
+		sc.s.Set(f.ID, list)
+		if "until" == f.Repeat {
+			sc.vars["_"] = v
+			done, err := f.RepeatUntil.evalBool(sc)
+			if err != nil {
+				return fieldError(elemPath, err)
+			}
+			if done {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// resolve returns the type name of f, evaluating its switch.
+func resolve(f *Field, sc *scope) (string, error) {
+	if nil == f.Switch {
+		return f.Type, nil
+	}
+	on, err := f.Switch.On.eval(sc)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range f.Switch.Cases {
+		if nil == c.Match {
+			continue
+		}
+		v, err := c.Match.eval(sc)
+		if err != nil {
+			return "", err
+		}
+		if equal(on, v) {
+			return c.Type, nil
+		}
+	}
+	for _, c := range f.Switch.Cases {
+		if nil == c.Match {
+			return c.Type, nil
+		}
+	}
+	// like Kaitai Struct, a sized field without a matching case is raw bytes
+	if f.Size != nil || f.SizeEOS || f.Terminator >= 0 {
+		return "", nil
+	}
+	return "", fmt.Errorf("no case matches %v", on)
+}
+
+// endian returns the byte order of a multi-byte field of t.
+func (d *decoder) endian(p prim, t *Type) binary.ByteOrder {
+	return fieldEndian(p, t, d.r.Endian())
+}
+
+func fieldEndian(p prim, t *Type, fallback binary.ByteOrder) binary.ByteOrder {
+	if p.endian != nil {
+		return p.endian
+	}
+	for ; t != nil; t = t.Parent {
+		if t.Endian != nil {
+			return t.Endian
+		}
+	}
+	return fallback
+}
+
+func (d *decoder) decodeValue(f *Field, sc *scope, path string) (any, error) {
+	if f.Contents != nil {
+		d.align()
+		d.label(path)
+		if err := d.r.ExpectBytes(f.Contents).Error(); err != nil {
+			return nil, err
+		}
+		return bytes.Clone(f.Contents), nil
+	}
+	name, err := resolve(f, sc)
+	if err != nil {
+		return nil, err
+	}
+	p := primitive(name)
+	if kindBits == p.kind {
+		d.label(path)
+		v, err := d.readBits(p.size)
+		if err != nil {
+			return nil, err
+		}
+		if f.Enum != "" {
+			return d.enum(f, sc, v)
+		}
+		if 1 == p.size {
+			return 1 == v, nil
+		}
+		return v, nil
+	}
+	d.align()
+	sized := f.Size != nil || f.SizeEOS || f.Terminator >= 0
+	if p.kind != 0 || sized {
+		d.label(path)
+	}
+	switch p.kind {
+	case kindUint, kindSint, kindFloat:
+		buf, err := d.r.ReadBytes(uint64(p.size))
+		if err != nil {
+			return nil, err
+		}
+		var v any = decodeNumber(p, d.endian(p, sc.t), buf)
+		if f.Enum != "" {
+			if v, err = d.enum(f, sc, v); err != nil {
+				return nil, err
+			}
+		}
+		d.annotate(name, v)
+		return v, nil
+	case kindLP:
+		buf, err := d.r.ReadBytesWithLengthPrefix()
+		if "lpstr" == name {
+			return string(buf), err
+		}
+		return buf, err
+	}
+	var buf []byte
+	if sized {
+		if buf, err = d.readSized(f, sc); err != nil {
+			return nil, err
+		}
+		if f.Process != "" {
+			if buf, err = unprocess(d.r, f.Process, buf); err != nil {
+				return nil, err
+			}
+		}
+	}
+	switch name {
+	case "":
+		return buf, nil
+	case "str", "strz":
+		if "" == f.Process && (f.Size != nil || f.SizeEOS) {
+			d.annotate(name, string(buf))
+		}
+		return string(buf), nil
+	}
+	t := sc.t.lookupType(name)
+	if !sized {
+		d.r.BeginSection(path[strings.LastIndexByte(path, '.')+1:])
+		defer d.r.EndSection()
+		return d.decodeType(t, sc, path)
+	}
+	sub := newDecoder(d.r, buf)
+	if "" == f.Process && (f.Size != nil || f.SizeEOS) {
+		defer sub.nest(d.r.Trace())()
+	}
+	return sub.decodeType(t, sc, path)
+}
+
+// unprocess decompress buf with the codec named process, within the decompress limit of r.
+func unprocess(r *bitstream.BIStream, process string, buf []byte) ([]byte, error) {
+	codec, ok := bitstream.LookupCodec(process)
+	if !ok {
+		return nil, fmt.Errorf("unknown process %q", process)
+	}
+	sub, err := r.Decompress(buf, codec)
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Close()
+	return sub.ReadAll()
+}
+
+func (d *decoder) readSized(f *Field, sc *scope) ([]byte, error) {
+	switch {
+	case f.Size != nil:
+		n, err := f.Size.evalInt(sc)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("negative size %d", n)
+		}
+		buf, err := d.r.ReadBytes(uint64(n))
+		if err != nil {
+			return nil, err
+		}
+		if f.Terminator >= 0 {
+			if i := bytes.IndexByte(buf, byte(f.Terminator)); i >= 0 {
+				buf = buf[:i]
+			}
+		}
+		return buf, nil
+	case f.SizeEOS:
+		return d.r.ReadAll()
+	}
+	var buf []byte
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if int(c) == f.Terminator {
+			return buf, nil
+		}
+		buf = append(buf, c)
+	}
+}
+
+func decodeNumber(p prim, order binary.ByteOrder, buf []byte) any {
+	var u uint64
+	switch p.size {
+	case 1:
+		u = uint64(buf[0])
+	case 2:
+		u = uint64(order.Uint16(buf))
+	case 4:
+		u = uint64(order.Uint32(buf))
+	case 8:
+		u = order.Uint64(buf)
+	}
+	switch p.kind {
+	case kindSint:
+		shift := 64 - 8*p.size
+		return int64(u<<shift) >> shift
+	case kindFloat:
+		if 4 == p.size {
+			return float64(math.Float32frombits(uint32(u)))
+		}
+		return math.Float64frombits(u)
+	}
+	return u
+}
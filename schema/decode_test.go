@@ -0,0 +1,194 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+const testSchema = `
+meta:
+  endian: be
+seq:
+  - id: magic
+    contents: "PK"
+  - id: count
+    type: u2
+  - id: entries
+    type: entry
+    repeat: expr
+    repeat-expr: count
+  - id: trailer
+    type: trailer
+types:
+  entry:
+    seq:
+      - id: flags
+        type: b3
+      - id: big
+        type: b1
+      - id: kind
+        type: b4
+      - id: body
+        type:
+          switch-on: kind
+          cases:
+            1: u2le
+            2: s4
+            _: blob
+  blob:
+    seq:
+      - id: len
+        type: u1
+      - id: data
+        size: len
+      - id: name
+        type: strz
+        if: _parent.big
+  trailer:
+    seq:
+      - id: words
+        type: u2
+        repeat: until
+        repeat-until: _ == 0
+      - id: rest
+        size-eos: true
+`
+
+func TestSchema_Decode(t *testing.T) {
+	s, err := Load([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte{
+		'P', 'K', 0x00, 0x03,
+		0x21, 0x34, 0x12,
+		0x02, 0xff, 0xff, 0xff, 0xfe,
+		0x13, 0x02, 0xaa, 0xbb, 'h', 'i', 0x00,
+		0x00, 0x05, 0x00, 0x00, 0xee,
+	}
+	got, err := s.Decode(bitstream.NewBIStream(binary.LittleEndian, bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"magic":"UEs=","count":3,"entries":[` +
+		`{"flags":1,"big":false,"kind":1,"body":4660},` +
+		`{"flags":0,"big":false,"kind":2,"body":-2},` +
+		`{"flags":0,"big":true,"kind":3,"body":{"len":2,"data":"qrs=","name":"hi"}}],` +
+		`"trailer":{"words":[5,0],"rest":"7g=="}}`
+	if js, _ := json.Marshal(got); string(js) != want {
+		t.Errorf("Decode() = %s, want %s", js, want)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := s.Encode(bitstream.NewBOStream(binary.LittleEndian, buf), got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(buf.Bytes(), data) {
+		t.Errorf("Encode() = %x, want %x", buf.Bytes(), data)
+	}
+
+	// the JSON form encodes to the same bytes
+	var m map[string]any
+	dec := json.NewDecoder(bytes.NewReader([]byte(want)))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := s.Encode(bitstream.NewBOStream(binary.LittleEndian, buf), m); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(buf.Bytes(), data) {
+		t.Errorf("Encode(map) = %x, want %x", buf.Bytes(), data)
+	}
+}
+
+func TestSchema_DecodeFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		endian    binary.ByteOrder
+		schema    string
+		args      []byte
+		want      string
+		wantError error
+	}{
+		{name: "TestSchema_DecodeFields_StreamEndian", endian: binary.LittleEndian, schema: "seq:\n  - id: a\n    type: u2\n", args: []byte{0x01, 0x02}, want: `{"a":513}`},
+		{name: "TestSchema_DecodeFields_Float", endian: binary.BigEndian, schema: "seq:\n  - id: a\n    type: f4\n", args: []byte{0x3f, 0xc0, 0, 0}, want: `{"a":1.5}`},
+		{name: "TestSchema_DecodeFields_Signed", endian: binary.BigEndian, schema: "seq:\n  - id: a\n    type: s1\n", args: []byte{0x80}, want: `{"a":-128}`},
+		{name: "TestSchema_DecodeFields_Bits", endian: binary.BigEndian, schema: "seq:\n  - id: a\n    type: b12\n  - id: b\n    type: u1\n", args: []byte{0xab, 0xc0, 0x01}, want: `{"a":2748,"b":1}`},
+		{name: "TestSchema_DecodeFields_StrPadded", endian: binary.BigEndian, schema: "seq:\n  - id: a\n    type: str\n    size: 4\n    terminator: 0\n", args: []byte{'a', 'b', 0, 0}, want: `{"a":"ab"}`},
+		{name: "TestSchema_DecodeFields_LP", endian: binary.BigEndian, schema: "seq:\n  - id: a\n    type: lpstr\n", args: []byte{0x02, 'o', 'k'}, want: `{"a":"ok"}`},
+		{name: "TestSchema_DecodeFields_RepeatEOS", endian: binary.BigEndian, schema: "seq:\n  - id: a\n    type: u1\n    repeat: eos\n", args: []byte{1, 2, 3}, want: `{"a":[1,2,3]}`},
+		{name: "TestSchema_DecodeFields_SizedType", endian: binary.BigEndian, schema: "seq:\n  - id: a\n    type: t\n    size: 3\n  - id: b\n    type: u1\ntypes:\n  t:\n    seq:\n      - id: x\n        type: u1\n", args: []byte{1, 0, 0, 4}, want: `{"a":{"x":1},"b":4}`},
+		{name: "TestSchema_DecodeFields_Magic", endian: binary.BigEndian, schema: "seq:\n  - id: a\n    contents: [1, 2]\n", args: []byte{1, 3}, wantError: &bitstream.ValidationError{}},
+		{name: "TestSchema_DecodeFields_Short", endian: binary.BigEndian, schema: "seq:\n  - id: a\n    type: u4\n", args: []byte{1, 2}, wantError: io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Load([]byte(tt.schema))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.Decode(bitstream.NewBIStream(tt.endian, bytes.NewReader(tt.args)))
+			if tt.wantError != nil {
+				var fe *FieldError
+				if !errors.As(err, &fe) || fe.Path != "a" {
+					t.Fatalf("Decode() error = %v, want a FieldError on a", err)
+				}
+				if ve := new(bitstream.ValidationError); !errors.Is(err, tt.wantError) && !errors.As(err, &ve) {
+					t.Errorf("Decode() error = %v, want %v", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if js, _ := json.Marshal(got); string(js) != tt.want {
+				t.Errorf("Decode() = %s, want %s", js, tt.want)
+			}
+			buf := new(bytes.Buffer)
+			if err := s.Encode(bitstream.NewBOStream(tt.endian, buf), got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(buf.Bytes(), tt.args) {
+				t.Errorf("Encode() = %x, want %x", buf.Bytes(), tt.args)
+			}
+		})
+	}
+}
+
+func TestSchema_EncodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		args   map[string]any
+	}{
+		{name: "TestSchema_EncodeErrors_Missing", schema: "seq:\n  - id: a\n    type: u1\n", args: map[string]any{}},
+		{name: "TestSchema_EncodeErrors_Overflow", schema: "seq:\n  - id: a\n    type: u1\n", args: map[string]any{"a": 256}},
+		{name: "TestSchema_EncodeErrors_BitsOverflow", schema: "seq:\n  - id: a\n    type: b3\n", args: map[string]any{"a": 8}},
+		{name: "TestSchema_EncodeErrors_Negative", schema: "seq:\n  - id: a\n    type: u4\n", args: map[string]any{"a": -1}},
+		{name: "TestSchema_EncodeErrors_Count", schema: "seq:\n  - id: n\n    type: u1\n  - id: a\n    type: u1\n    repeat: expr\n    repeat-expr: n\n", args: map[string]any{"n": 2, "a": []any{1}}},
+		{name: "TestSchema_EncodeErrors_Size", schema: "seq:\n  - id: a\n    size: 2\n", args: map[string]any{"a": []byte{1, 2, 3}}},
+		{name: "TestSchema_EncodeErrors_Terminator", schema: "seq:\n  - id: a\n    type: strz\n", args: map[string]any{"a": "a\x00b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Load([]byte(tt.schema))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.Encode(bitstream.NewBOStream(binary.BigEndian, io.Discard), tt.args)
+			var fe *FieldError
+			if !errors.As(err, &fe) {
+				t.Errorf("Encode() error = %v, want a FieldError", err)
+			}
+		})
+	}
+}
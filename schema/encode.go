@@ -0,0 +1,450 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+// Encode write v as the root type to w. v is a *Struct, like the ones returned by Decode, or a
+// map[string]any, like the result of json.Unmarshal.
+//
+// Integers may be any Go integer type, an integral float64 or a json.Number. Raw bytes may be a []byte,
+// a base64 string or a sequence of integers. A str shorter than its size is padded with zeros, and so is a
+// sized user type. Enums may be an EnumValue, a name or an integer. Sizes and counts are not computed: the
+// fields they refer to must already hold them. Instances are not written.
+func (s *Schema) Encode(w *bitstream.BOStream, v any) error {
+	e := &encoder{w: w}
+	if _, err := e.encodeType(s.Root, v, nil, ""); err != nil {
+		return err
+	}
+	e.align()
+	return w.Error()
+}
+
+// Size returns the number of bytes Encode writes for v, and an error if exists.
+func (s *Schema) Size(v any) (int64, error) {
+	p := bitstream.NewSizer(binary.BigEndian)
+	if err := s.Encode(p, v); err != nil {
+		return 0, err
+	}
+	return p.Len(), nil
+}
+
+// encoder keeps the partial byte of bit fields. Any byte aligned write pads it with zeros first.
+type encoder struct {
+	w     *bitstream.BOStream
+	bits  byte
+	nbits int
+}
+
+func (e *encoder) align() {
+	if e.nbits > 0 {
+		e.w.WriteByte(e.bits)
+		e.bits, e.nbits = 0, 0
+	}
+}
+
+func (e *encoder) writeBits(v uint64, n int) {
+	for n > 0 {
+		k := min(n, 8-e.nbits)
+		e.bits |= (byte(v>>(n-k)) & (1<<k - 1)) << (8 - e.nbits - k)
+		e.nbits += k
+		n -= k
+		if 8 == e.nbits {
+			e.w.WriteByte(e.bits)
+			e.bits, e.nbits = 0, 0
+		}
+	}
+}
+
+// fields returns the field getter of a *Struct or a map.
+func fields(v any) (func(string) (any, bool), error) {
+	switch v := v.(type) {
+	case *Struct:
+		return v.Get, nil
+	case map[string]any:
+		return func(k string) (any, bool) {
+			f, ok := v[k]
+			return f, ok
+		}, nil
+	}
+	return nil, fmt.Errorf("%T is not a struct", v)
+}
+
+func (e *encoder) encodeType(t *Type, v any, parent *scope, path string) (*Struct, error) {
+	get, err := fields(v)
+	if err != nil {
+		return nil, fieldError(path, err)
+	}
+	sc := &scope{t: t, s: NewStruct(), parent: parent, vars: map[string]any{}}
+	sc.root = sc
+	if parent != nil {
+		sc.root = parent.root
+	}
+	for _, f := range t.Seq {
+		if err := e.encodeField(f, sc, get, join(path, f.ID)); err != nil {
+			return nil, err
+		}
+	}
+	return sc.s, nil
+}
+
+func (e *encoder) encodeField(f *Field, sc *scope, get func(string) (any, bool), path string) error {
+	if f.If != nil {
+		ok, err := f.If.evalBool(sc)
+		if err != nil {
+			return fieldError(path, err)
+		}
+		if !ok {
+			return nil
+		}
+	}
+	v, ok := get(f.ID)
+	if !ok && nil == f.Contents {
+		return fieldError(path, fmt.Errorf("missing value"))
+	}
+	if "" == f.Repeat {
+		nv, err := e.encodeValue(f, sc, v, path)
+		if err != nil {
+			return fieldError(path, err)
+		}
+		sc.s.Set(f.ID, nv)
+		return e.w.Error()
+	}
+	list, ok := v.([]any)
+	if !ok {
+		return fieldError(path, fmt.Errorf("%T is not a sequence", v))
+	}
+	if "expr" == f.Repeat {
+		n, err := f.RepeatExpr.evalInt(sc)
+		if err != nil {
+			return fieldError(path, err)
+		}
+		if n != int64(len(list)) {
+			return fieldError(path, fmt.Errorf("%d elements, repeat-expr is %d", len(list), n))
+		}
+	}
+	defer delete(sc.vars, "_index")
+	defer delete(sc.vars, "_")
+	out := make([]any, 0, len(list))
+	sc.s.Set(f.ID, out)
+	for i, elem := range list {
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		sc.vars["_index"] = int64(i)
+		nv, err := e.encodeValue(f, sc, elem, elemPath)
+		if err != nil {
+			return fieldError(elemPath, err)
+		}
+		out = append(out, nv)
+		sc.s.Set(f.ID, out)
+		if "until" == f.Repeat {
+			sc.vars["_"] = nv
+			done, err := f.RepeatUntil.evalBool(sc)
+			if err != nil {
+				return fieldError(elemPath, err)
+			}
+			if done != (i == len(list)-1) {
+				return fieldError(elemPath, fmt.Errorf("repeat-until is %v", done))
+			}
+		}
+	}
+	if "until" == f.Repeat && 0 == len(list) {
+		return fieldError(path, fmt.Errorf("repeat: until needs at least one element"))
+	}
+	return e.w.Error()
+}
+
+func (e *encoder) encodeValue(f *Field, sc *scope, v any, path string) (any, error) {
+	if f.Contents != nil {
+		e.align()
+		e.w.WriteBytes(f.Contents)
+		return bytes.Clone(f.Contents), nil
+	}
+	name, err := resolve(f, sc)
+	if err != nil {
+		return nil, err
+	}
+	p := primitive(name)
+	var enum *Enum
+	if f.Enum != "" {
+		enum = sc.t.lookupEnum(strings.Split(f.Enum, "::"))
+		if v, err = enumValue(enum, v); err != nil {
+			return nil, err
+		}
+	}
+	if kindBits == p.kind {
+		if b, ok := v.(bool); ok && 1 == p.size {
+			v = uint64(0)
+			if b {
+				v = uint64(1)
+			}
+		}
+		u, err := uintValue(v)
+		if err != nil {
+			return nil, err
+		}
+		if p.size < 64 && u>>p.size != 0 {
+			return nil, fmt.Errorf("%d does not fit in %d bits", u, p.size)
+		}
+		e.writeBits(u, p.size)
+		if enum != nil {
+			return v, nil
+		}
+		if 1 == p.size {
+			return 1 == u, nil
+		}
+		return u, nil
+	}
+	e.align()
+	switch p.kind {
+	case kindUint, kindSint, kindFloat:
+		buf, nv, err := encodeNumber(p, fieldEndian(p, sc.t, e.w.Endian()), v)
+		if err != nil {
+			return nil, err
+		}
+		e.w.WriteBytes(buf)
+		if enum != nil {
+			return v, nil
+		}
+		return nv, nil
+	case kindLP:
+		if "lpstr" == name {
+			s, err := stringValue(v)
+			e.w.WriteString(s)
+			return s, err
+		}
+		buf, err := bytesValue(v)
+		e.w.WriteBytesWithLengthPrefix(buf)
+		return buf, err
+	}
+	switch name {
+	case "":
+		buf, err := bytesValue(v)
+		if err != nil {
+			return nil, err
+		}
+		return buf, e.writeProcessed(f, sc, buf, false)
+	case "str", "strz":
+		s, err := stringValue(v)
+		if err != nil {
+			return nil, err
+		}
+		return s, e.writeProcessed(f, sc, []byte(s), true)
+	}
+	t := sc.t.lookupType(name)
+	if nil == f.Size && !f.SizeEOS && f.Terminator < 0 {
+		return e.encodeType(t, v, sc, path)
+	}
+	buf := new(bytes.Buffer)
+	sub := &encoder{w: bitstream.NewBOStream(e.w.Endian(), buf)}
+	st, err := sub.encodeType(t, v, sc, path)
+	if err != nil {
+		return nil, err
+	}
+	sub.align()
+	if err := sub.w.Error(); err != nil {
+		return nil, err
+	}
+	return st, e.writeProcessed(f, sc, buf.Bytes(), true)
+}
+
+// writeProcessed compress buf with the codec of f, if any, then write it with writeSized.
+func (e *encoder) writeProcessed(f *Field, sc *scope, buf []byte, pad bool) error {
+	if "" == f.Process {
+		return e.writeSized(f, sc, buf, pad)
+	}
+	codec, ok := bitstream.LookupCodec(f.Process)
+	if !ok {
+		return fmt.Errorf("unknown process %q", f.Process)
+	}
+	out := new(bytes.Buffer)
+	w, err := codec.NewWriter(out)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return e.writeSized(f, sc, out.Bytes(), pad)
+}
+
+// enumValue convert a name or an integer to a value of enum.
+func enumValue(enum *Enum, v any) (EnumValue, error) {
+	if name, ok := v.(string); ok {
+		n, ok := enum.value(name)
+		if !ok {
+			return EnumValue{}, fmt.Errorf("enum %s has no %s", enum.Name, name)
+		}
+		return EnumValue{Name: name, Value: n}, nil
+	}
+	n, err := intValue(v)
+	if err != nil {
+		return EnumValue{}, err
+	}
+	return EnumValue{Name: enum.Values[n], Value: n}, nil
+}
+
+// writeSized write buf with the size, size-eos or terminator of f. A short buf is padded with zeros
+// when pad is true, and is an error otherwise.
+func (e *encoder) writeSized(f *Field, sc *scope, buf []byte, pad bool) error {
+	if f.Terminator >= 0 && bytes.IndexByte(buf, byte(f.Terminator)) >= 0 {
+		return fmt.Errorf("value contains the terminator %#02x", f.Terminator)
+	}
+	switch {
+	case f.Size != nil:
+		n, err := f.Size.evalInt(sc)
+		if err != nil {
+			return err
+		}
+		if f.Terminator >= 0 && int64(len(buf)) < n {
+			buf = append(bytes.Clone(buf), byte(f.Terminator))
+		}
+		if int64(len(buf)) > n || (!pad && int64(len(buf)) < n) {
+			return fmt.Errorf("%d bytes, size is %d", len(buf), n)
+		}
+		e.w.WriteBytes(buf).WriteBytes(make([]byte, n-int64(len(buf))))
+	case f.SizeEOS:
+		e.w.WriteBytes(buf)
+	default:
+		e.w.WriteBytes(buf).WriteByte(byte(f.Terminator))
+	}
+	return nil
+}
+
+func encodeNumber(p prim, order binary.ByteOrder, v any) ([]byte, any, error) {
+	buf := make([]byte, p.size)
+	var (
+		u  uint64
+		nv any
+	)
+	switch p.kind {
+	case kindUint:
+		n, err := uintValue(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		if p.size < 8 && n>>(8*p.size) != 0 {
+			return nil, nil, fmt.Errorf("%d does not fit in u%d", n, p.size)
+		}
+		u, nv = n, n
+	case kindSint:
+		n, err := intValue(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		if shift := 64 - 8*p.size; n<<shift>>shift != n {
+			return nil, nil, fmt.Errorf("%d does not fit in s%d", n, p.size)
+		}
+		u, nv = uint64(n), n
+	case kindFloat:
+		f, err := floatValue(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		nv = f
+		u = math.Float64bits(f)
+		if 4 == p.size {
+			u = uint64(math.Float32bits(float32(f)))
+			nv = float64(float32(f))
+		}
+	}
+	switch p.size {
+	case 1:
+		buf[0] = byte(u)
+	case 2:
+		order.PutUint16(buf, uint16(u))
+	case 4:
+		order.PutUint32(buf, uint32(u))
+	case 8:
+		order.PutUint64(buf, u)
+	}
+	return buf, nv, nil
+}
+
+func uintValue(v any) (uint64, error) {
+	switch n := v.(type) {
+	case uint64:
+		return n, nil
+	case json.Number:
+		return strconv.ParseUint(string(n), 10, 64)
+	case float64:
+		if n >= 0 && n == math.Trunc(n) && n < math.MaxUint64 {
+			return uint64(n), nil
+		}
+	}
+	if i, ok := toInt(v); ok && i >= 0 {
+		return uint64(i), nil
+	}
+	return 0, fmt.Errorf("%v is not an unsigned integer", v)
+}
+
+func intValue(v any) (int64, error) {
+	switch n := v.(type) {
+	case uint64:
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+	case json.Number:
+		return n.Int64()
+	case float64:
+		if n == math.Trunc(n) && n >= math.MinInt64 && n < math.MaxInt64 {
+			return int64(n), nil
+		}
+	default:
+		if i, ok := toInt(v); ok {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%v is not an integer", v)
+}
+
+func floatValue(v any) (float64, error) {
+	if n, ok := v.(json.Number); ok {
+		return n.Float64()
+	}
+	if f, ok := toFloat(v); ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
+
+func stringValue(v any) (string, error) {
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case []byte:
+		return string(s), nil
+	}
+	return "", fmt.Errorf("%T is not a string", v)
+}
+
+func bytesValue(v any) ([]byte, error) {
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case string:
+		return base64.StdEncoding.DecodeString(b)
+	case []any:
+		buf := make([]byte, len(b))
+		for i, e := range b {
+			n, err := uintValue(e)
+			if err != nil || n > 0xff {
+				return nil, fmt.Errorf("element %d: %v is not a byte", i, e)
+			}
+			buf[i] = byte(n)
+		}
+		return buf, nil
+	}
+	return nil, fmt.Errorf("%T is not bytes", v)
+}
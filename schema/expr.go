@@ -0,0 +1,749 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Expr is a compiled expression used by size, if, repeat-expr, repeat-until and switch-on.
+//
+// It supports integer, float, string and boolean literals, field references including _parent, _root,
+// _ (the current element in repeat-until) and _index, member access with ".", indexing with "[]",
+// the properties size, length, first, last and to_i, the unary operators - ! ~ not, the binary operators
+// * / % + - << >> < <= > >= == != & ^ | && || and or, the ternary operator "c ? a : b", and enum values
+// written enum::name or type::enum::name.
+type Expr struct {
+	src  string
+	root node
+}
+
+// ParseExpr compile an expression.
+func ParseExpr(src string) (*Expr, error) {
+	p := &exprParser{src: src}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	n, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected %q", p.peek().text)
+	}
+	return &Expr{src: src, root: n}, nil
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+func (e *Expr) eval(s *scope) (any, error) {
+	v, err := e.root.eval(s)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", e.src, err)
+	}
+	return v, nil
+}
+
+func (e *Expr) evalInt(s *scope) (int64, error) {
+	v, err := e.eval(s)
+	if err != nil {
+		return 0, err
+	}
+	i, ok := toInt(v)
+	if !ok {
+		return 0, fmt.Errorf("expression %q: %v is not an integer", e.src, v)
+	}
+	return i, nil
+}
+
+func (e *Expr) evalBool(s *scope) (bool, error) {
+	v, err := e.eval(s)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q: %v is not a boolean", e.src, v)
+	}
+	return b, nil
+}
+
+// scope resolves the names used by expressions.
+type scope struct {
+	t      *Type
+	s      *Struct
+	parent *scope
+	root   *scope
+	vars   map[string]any
+	// instance computes the instance name on first use.
+	instance func(name string) (any, bool, error)
+}
+
+func (s *scope) lookup(name string) (any, error) {
+	switch name {
+	case "_root":
+		return s.root.s, nil
+	case "_parent":
+		if nil == s.parent {
+			return nil, fmt.Errorf("_parent of the root type")
+		}
+		return s.parent.s, nil
+	}
+	if v, ok := s.vars[name]; ok {
+		return v, nil
+	}
+	if v, ok := s.s.Get(name); ok {
+		return v, nil
+	}
+	if s.instance != nil {
+		if v, ok, err := s.instance(name); ok || err != nil {
+			return v, err
+		}
+	}
+	return nil, fmt.Errorf("unknown field %q", name)
+}
+
+type node interface {
+	eval(s *scope) (any, error)
+}
+
+type (
+	literal struct {
+		v any
+	}
+	ident struct {
+		name string
+	}
+	member struct {
+		x    node
+		name string
+	}
+	index struct {
+		x, i node
+	}
+	unary struct {
+		op string
+		x  node
+	}
+	binaryOp struct {
+		op   string
+		l, r node
+	}
+	ternary struct {
+		c, a, b node
+	}
+	enumRef struct {
+		path []string
+	}
+)
+
+func (n *literal) eval(*scope) (any, error) {
+	return n.v, nil
+}
+
+func (n *ident) eval(s *scope) (any, error) {
+	return s.lookup(n.name)
+}
+
+func (n *member) eval(s *scope) (any, error) {
+	x, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	switch v := x.(type) {
+	case *Struct:
+		if f, ok := v.Get(n.name); ok {
+			return f, nil
+		}
+		return nil, fmt.Errorf("unknown field %q", n.name)
+	case []any:
+		switch n.name {
+		case "size", "length":
+			return int64(len(v)), nil
+		case "first", "last":
+			if 0 == len(v) {
+				return nil, fmt.Errorf("%s of an empty array", n.name)
+			}
+			if "first" == n.name {
+				return v[0], nil
+			}
+			return v[len(v)-1], nil
+		}
+	case []byte:
+		switch n.name {
+		case "size", "length":
+			return int64(len(v)), nil
+		}
+	case string:
+		switch n.name {
+		case "size", "length":
+			return int64(len(v)), nil
+		case "to_i":
+			return strconv.ParseInt(v, 10, 64)
+		}
+	}
+	if i, ok := toInt(x); ok && "to_i" == n.name {
+		return i, nil
+	}
+	return nil, fmt.Errorf("%T has no property %q", x, n.name)
+}
+
+func (n *index) eval(s *scope) (any, error) {
+	x, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := n.i.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	i, ok := toInt(iv)
+	if !ok {
+		return nil, fmt.Errorf("index %v is not an integer", iv)
+	}
+	switch v := x.(type) {
+	case []any:
+		if i < 0 || i >= int64(len(v)) {
+			return nil, fmt.Errorf("index %d out of range", i)
+		}
+		return v[i], nil
+	case []byte:
+		if i < 0 || i >= int64(len(v)) {
+			return nil, fmt.Errorf("index %d out of range", i)
+		}
+		return int64(v[i]), nil
+	}
+	return nil, fmt.Errorf("%T can not be indexed", x)
+}
+
+func (n *unary) eval(s *scope) (any, error) {
+	x, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "-":
+		if i, ok := toInt(x); ok {
+			return -i, nil
+		}
+		if f, ok := x.(float64); ok {
+			return -f, nil
+		}
+	case "~":
+		if i, ok := toInt(x); ok {
+			return ^i, nil
+		}
+	case "!", "not":
+		if b, ok := x.(bool); ok {
+			return !b, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid operand %v for %s", x, n.op)
+}
+
+func (n *enumRef) eval(s *scope) (any, error) {
+	e := s.t.lookupEnum(n.path[:len(n.path)-1])
+	if nil == e {
+		return nil, fmt.Errorf("unknown enum %s", strings.Join(n.path[:len(n.path)-1], "::"))
+	}
+	name := n.path[len(n.path)-1]
+	v, ok := e.value(name)
+	if !ok {
+		return nil, fmt.Errorf("enum %s has no %s", e.Name, name)
+	}
+	return EnumValue{Name: name, Value: v}, nil
+}
+
+func (n *ternary) eval(s *scope) (any, error) {
+	c, err := n.c.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := c.(bool)
+	if !ok {
+		return nil, fmt.Errorf("condition %v is not a boolean", c)
+	}
+	if b {
+		return n.a.eval(s)
+	}
+	return n.b.eval(s)
+}
+
+func (n *binaryOp) eval(s *scope) (any, error) {
+	l, err := n.l.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "&&", "and", "||", "or":
+		lb, ok := l.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid operand %v for %s", l, n.op)
+		}
+		if lb == ("||" == n.op || "or" == n.op) {
+			return lb, nil
+		}
+		r, err := n.r.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := r.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid operand %v for %s", r, n.op)
+		}
+		return rb, nil
+	}
+	r, err := n.r.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	}
+	if li, ok := toInt(l); ok {
+		if ri, ok := toInt(r); ok {
+			return intOp(n.op, li, ri)
+		}
+	}
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	if lok && rok {
+		return floatOp(n.op, lf, rf)
+	}
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if lok && rok {
+		switch n.op {
+		case "+":
+			return ls + rs, nil
+		case "<":
+			return ls < rs, nil
+		case "<=":
+			return ls <= rs, nil
+		case ">":
+			return ls > rs, nil
+		case ">=":
+			return ls >= rs, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid operands %v %s %v", l, n.op, r)
+}
+
+func intOp(op string, l, r int64) (any, error) {
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/", "%":
+		if 0 == r {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if "/" == op {
+			return l / r, nil
+		}
+		return l % r, nil
+	case "<<":
+		return l << uint64(r), nil
+	case ">>":
+		return l >> uint64(r), nil
+	case "&":
+		return l & r, nil
+	case "|":
+		return l | r, nil
+	case "^":
+		return l ^ r, nil
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	}
+	return nil, fmt.Errorf("invalid integer operator %s", op)
+}
+
+func floatOp(op string, l, r float64) (any, error) {
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		return l / r, nil
+	case "%":
+		return math.Mod(l, r), nil
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	}
+	return nil, fmt.Errorf("invalid float operator %s", op)
+}
+
+func equal(l, r any) bool {
+	if li, ok := toInt(l); ok {
+		if ri, ok := toInt(r); ok {
+			return li == ri
+		}
+	}
+	if lf, ok := toFloat(l); ok {
+		if rf, ok := toFloat(r); ok {
+			return lf == rf
+		}
+	}
+	if lb, ok := l.([]byte); ok {
+		if rb, ok := r.([]byte); ok {
+			return bytes.Equal(lb, rb)
+		}
+	}
+	switch l.(type) {
+	case string, bool, nil:
+		return l == r
+	}
+	return false
+}
+
+// toInt convert any integer type to int64. uint64 values above math.MaxInt64 wrap around.
+func toInt(v any) (int64, bool) {
+	switch i := v.(type) {
+	case int64:
+		return i, true
+	case EnumValue:
+		return i.Value, true
+	case uint64:
+		return int64(i), true
+	case int:
+		return int64(i), true
+	case int8:
+		return int64(i), true
+	case int16:
+		return int64(i), true
+	case int32:
+		return int64(i), true
+	case uint:
+		return int64(i), true
+	case uint8:
+		return int64(i), true
+	case uint16:
+		return int64(i), true
+	case uint32:
+		return int64(i), true
+	}
+	return 0, false
+}
+
+func toFloat(v any) (float64, bool) {
+	if f, ok := v.(float64); ok {
+		return f, true
+	}
+	if f, ok := v.(float32); ok {
+		return float64(f), true
+	}
+	if i, ok := toInt(v); ok {
+		return float64(i), true
+	}
+	return 0, false
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokInt
+	tokFloat
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	v    any
+}
+
+type exprParser struct {
+	src    string
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("expression %q: %s", p.src, fmt.Sprintf(format, args...))
+}
+
+// operators are matched longest first.
+var operators = []string{"::", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"+", "-", "*", "/", "%", "<", ">", "&", "|", "^", "~", "!", "?", ":", ".", "[", "]", "(", ")", ","}
+
+func (p *exprParser) tokenize() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case ' ' == c || '\t' == c || '\n' == c:
+			i++
+		case isDigit(c):
+			j := i
+			for j < len(s) && (isIdentChar(s[j]) || ('.' == s[j] && j+1 < len(s) && isDigit(s[j+1]))) {
+				j++
+			}
+			text := strings.ReplaceAll(s[i:j], "_", "")
+			if v, err := strconv.ParseInt(text, 0, 64); nil == err {
+				p.tokens = append(p.tokens, token{kind: tokInt, text: s[i:j], v: v})
+			} else if v, err := strconv.ParseUint(text, 0, 64); nil == err {
+				p.tokens = append(p.tokens, token{kind: tokInt, text: s[i:j], v: int64(v)})
+			} else if v, err := strconv.ParseFloat(text, 64); nil == err {
+				p.tokens = append(p.tokens, token{kind: tokFloat, text: s[i:j], v: v})
+			} else {
+				return p.errorf("invalid number %q", s[i:j])
+			}
+			i = j
+		case isIdentChar(c):
+			j := i
+			for j < len(s) && isIdentChar(s[j]) {
+				j++
+			}
+			p.tokens = append(p.tokens, token{kind: tokIdent, text: s[i:j]})
+			i = j
+		case '"' == c || '\'' == c:
+			j := i + 1
+			for j < len(s) && s[j] != c {
+				if '\\' == s[j] && '"' == c {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return p.errorf("unterminated string")
+			}
+			v := s[i+1 : j]
+			if '"' == c {
+				var err error
+				if v, err = strconv.Unquote(s[i : j+1]); err != nil {
+					return p.errorf("invalid string %s", s[i:j+1])
+				}
+			}
+			p.tokens = append(p.tokens, token{kind: tokString, text: s[i : j+1], v: v})
+			i = j + 1
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if "" == op {
+				return p.errorf("unexpected character %q", c)
+			}
+			p.tokens = append(p.tokens, token{kind: tokOp, text: op})
+			i += len(op)
+		}
+	}
+	p.tokens = append(p.tokens, token{kind: tokEOF})
+	return nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return '_' == c || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) isOp(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokIdent {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) expect(op string) error {
+	if _, ok := p.isOp(op); !ok {
+		return p.errorf("expected %q", op)
+	}
+	p.next()
+	return nil
+}
+
+func (p *exprParser) ternary() (node, error) {
+	c, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.isOp("?"); !ok {
+		return c, nil
+	}
+	p.next()
+	a, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	b, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return &ternary{c: c, a: a, b: b}, nil
+}
+
+// precedence lists the binary operators from the loosest to the tightest.
+var precedence = [][]string{
+	{"||", "or"},
+	{"&&", "and"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) binary(level int) (node, error) {
+	if level == len(precedence) {
+		return p.unary()
+	}
+	l, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.isOp(precedence[level]...)
+		if !ok {
+			return l, nil
+		}
+		p.next()
+		r, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryOp{op: op, l: l, r: r}
+	}
+}
+
+func (p *exprParser) unary() (node, error) {
+	if op, ok := p.isOp("-", "!", "~", "not"); ok {
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{op: op, x: x}, nil
+	}
+	return p.postfix()
+}
+
+func (p *exprParser) postfix() (node, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.isOp("."); ok {
+			p.next()
+			t := p.next()
+			if t.kind != tokIdent {
+				return nil, p.errorf("expected a name after '.'")
+			}
+			x = &member{x: x, name: t.text}
+			continue
+		}
+		if _, ok := p.isOp("["); ok {
+			p.next()
+			i, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &index{x: x, i: i}
+			continue
+		}
+		return x, nil
+	}
+}
+
+func (p *exprParser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokInt, tokFloat, tokString:
+		return &literal{v: t.v}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literal{v: true}, nil
+		case "false":
+			return &literal{v: false}, nil
+		}
+		if _, ok := p.isOp("::"); ok {
+			path := []string{t.text}
+			for {
+				if _, ok := p.isOp("::"); !ok {
+					return &enumRef{path: path}, nil
+				}
+				p.next()
+				n := p.next()
+				if n.kind != tokIdent {
+					return nil, p.errorf("expected a name after '::'")
+				}
+				path = append(path, n.text)
+			}
+		}
+		return &ident{name: t.text}, nil
+	case tokOp:
+		if "(" == t.text {
+			x, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	}
+	return nil, p.errorf("unexpected %q", t.text)
+}
@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpr_eval(t *testing.T) {
+	parent := NewStruct()
+	parent.Set("version", uint64(2))
+	s := NewStruct()
+	s.Set("len", uint64(5))
+	s.Set("flags", uint64(0x81))
+	s.Set("name", "abc")
+	s.Set("data", []byte{1, 2, 3})
+	s.Set("items", []any{uint64(7), uint64(9)})
+	root := &scope{s: parent}
+	root.root = root
+	sc := &scope{s: s, parent: root, root: root, vars: map[string]any{"_index": int64(1)}}
+
+	tests := []struct {
+		name      string
+		args      string
+		want      any
+		wantError bool
+	}{
+		{name: "TestExpr_eval_Arith", args: "len * 2 + 1", want: int64(11)},
+		{name: "TestExpr_eval_Precedence", args: "1 + 2 << 1", want: int64(6)},
+		{name: "TestExpr_eval_Bits", args: "(flags & 0x80) != 0", want: true},
+		{name: "TestExpr_eval_Logic", args: "len > 3 and not (len == 4)", want: true},
+		{name: "TestExpr_eval_Ternary", args: "len > 10 ? 1 : 2", want: int64(2)},
+		{name: "TestExpr_eval_String", args: "name == \"abc\"", want: true},
+		{name: "TestExpr_eval_Size", args: "data.size + name.length", want: int64(6)},
+		{name: "TestExpr_eval_Index", args: "items[_index] + data[0]", want: int64(10)},
+		{name: "TestExpr_eval_Last", args: "items.last", want: uint64(9)},
+		{name: "TestExpr_eval_Parent", args: "_parent.version == _root.version", want: true},
+		{name: "TestExpr_eval_Float", args: "len / 2.0", want: 2.5},
+		{name: "TestExpr_eval_Unknown", args: "missing + 1", wantError: true},
+		{name: "TestExpr_eval_DivZero", args: "len / 0", wantError: true},
+		{name: "TestExpr_eval_OutOfRange", args: "items[2]", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := ParseExpr(tt.args)
+			if err != nil {
+				t.Fatalf("ParseExpr() error = %v", err)
+			}
+			got, err := e.eval(sc)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("eval() error = %v, wantError %v", err, tt.wantError)
+			}
+			if !tt.wantError && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("eval() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      string
+		wantError bool
+	}{
+		{name: "TestParseExpr_Valid", args: "a.b[1] + -c"},
+		{name: "TestParseExpr_Unbalanced", args: "(a + 1", wantError: true},
+		{name: "TestParseExpr_Trailing", args: "a b", wantError: true},
+		{name: "TestParseExpr_BadString", args: "\"abc", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseExpr(tt.args)
+			if (err != nil) != tt.wantError {
+				t.Errorf("ParseExpr() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,955 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GenerateGo write the Go source of package pkg, with a struct and a reader per type of s. The reader of the
+// root type is exported as Read<ID>, or ReadRoot without a meta id, and takes a *bitstream.BIStream.
+//
+// Generated readers support the descriptions Decode supports, except pos instances and the _root, _parent
+// and _io names in expressions. Integers keep their size, like uint16 for u2, enums become named integer
+// types, switches become any, and value instances are computed after the seq.
+//
+// No writer is generated, so the generated types do not implement bitstream.Marshaler and bitstream.Size can
+// not size them. Encode and Size of the schema take the values Decode returns.
+func (s *Schema) GenerateGo(w io.Writer, pkg string) error {
+	g := &generator{
+		schema:   s,
+		names:    map[*Type]string{},
+		enums:    map[*Enum]string{},
+		taken:    map[string]bool{},
+		visiting: map[*Field]bool{},
+	}
+	root := "Root"
+	if s.ID != "" {
+		root = camel(s.ID)
+	}
+	if err := g.collect(s.Root, root); err != nil {
+		return err
+	}
+	body := new(bytes.Buffer)
+	g.out = body
+	for _, t := range g.types {
+		if err := g.genType(t); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.enumOrder {
+		g.genEnum(e)
+	}
+	body.WriteString(genHelpers)
+
+	src := new(bytes.Buffer)
+	fmt.Fprintf(src, "// Code generated by schema.GenerateGo. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	imports := []string{"bytes", "encoding/binary", "fmt"}
+	if g.useMath {
+		imports = append(imports, "math")
+	}
+	if len(g.enumOrder) > 0 {
+		imports = append(imports, "strconv")
+	}
+	for _, imp := range imports {
+		fmt.Fprintf(src, "\t%q\n", imp)
+	}
+	src.WriteString("\n\tbitstream \"github.com/meetleev/go_bitstream\"\n)\n")
+	src.Write(body.Bytes())
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("schema: generated code does not parse: %w", err)
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+type generator struct {
+	schema    *Schema
+	names     map[*Type]string
+	types     []*Type
+	enums     map[*Enum]string
+	enumOrder []*Enum
+	taken     map[string]bool
+	visiting  map[*Field]bool
+	useMath   bool
+	out       *bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(g.out, format, args...)
+}
+
+// camel convert a snake_case id to an exported Go name.
+func camel(id string) string {
+	var sb strings.Builder
+	for _, part := range strings.Split(id, "_") {
+		if part != "" {
+			sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return sb.String()
+}
+
+func (g *generator) claim(name, what string) error {
+	if "" == name || !isIdentChar(name[0]) || isDigit(name[0]) {
+		return fmt.Errorf("schema: %s can not be a Go name", what)
+	}
+	if g.taken[name] {
+		return fmt.Errorf("schema: %s and another type or enum are both named %s in Go", what, name)
+	}
+	g.taken[name] = true
+	return nil
+}
+
+// collect name the types and enums of t and its nested types.
+func (g *generator) collect(t *Type, name string) error {
+	if err := g.claim(name, "type "+t.path("")); err != nil {
+		return err
+	}
+	g.names[t] = name
+	g.types = append(g.types, t)
+	enumNames := make([]string, 0, len(t.Enums))
+	for k := range t.Enums {
+		enumNames = append(enumNames, k)
+	}
+	sort.Strings(enumNames)
+	for _, k := range enumNames {
+		e := t.Enums[k]
+		if err := g.claim(camel(k), "enum "+k); err != nil {
+			return err
+		}
+		g.enums[e] = camel(k)
+		g.enumOrder = append(g.enumOrder, e)
+	}
+	for _, u := range sortedTypes(t) {
+		if err := g.collect(u, camel(u.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// label returns the name of t in error messages of the generated code.
+func (g *generator) label(t *Type) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	if g.schema.ID != "" {
+		return g.schema.ID
+	}
+	return "root"
+}
+
+func (g *generator) genType(t *Type) error {
+	name := g.names[t]
+	g.printf("\n// %s is generated from the %s type.\ntype %s struct {\n", name, g.label(t), name)
+	for _, f := range append(t.Seq[:len(t.Seq):len(t.Seq)], t.Instances...) {
+		if f.Pos != nil {
+			return fmt.Errorf("schema: %s: pos instances are not supported by GenerateGo", t.path(f.ID))
+		}
+		_, goType, err := g.fieldType(t, f)
+		if err != nil {
+			return err
+		}
+		g.printf("\t%s %s\n", camel(f.ID), goType)
+	}
+	g.printf("}\n")
+	if t == g.schema.Root {
+		g.printf("\n// Read%s read a %s from r.\nfunc Read%s(r *bitstream.BIStream) (*%s, error) {\n", name, name, name, name)
+		g.printf("\treturn read%s(&bitReader{r: r})\n}\n", name)
+	}
+	g.printf("\nfunc read%s(br *bitReader) (*%s, error) {\n\tv := &%s{}\n", name, name, name)
+	if len(t.Seq) > 0 {
+		g.printf("\tvar err error\n")
+	}
+	sc := &genScope{g: g, t: t, vars: map[string]gval{}}
+	for _, f := range t.Seq {
+		if err := g.genField(sc, f); err != nil {
+			return err
+		}
+	}
+	for _, f := range t.Instances {
+		if err := g.genValueInstance(sc, f); err != nil {
+			return err
+		}
+	}
+	g.printf("\treturn v, nil\n}\n")
+	return nil
+}
+
+func (g *generator) genEnum(e *Enum) {
+	name := g.enums[e]
+	values := make([]int64, 0, len(e.Values))
+	for v := range e.Values {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	g.printf("\n// %s is generated from the %s enum.\ntype %s int64\n\nconst (\n", name, e.Name, name)
+	for _, v := range values {
+		g.printf("\t%s%s %s = %d\n", name, camel(e.Values[v]), name, v)
+	}
+	g.printf(")\n\nfunc (e %s) String() string {\n\tswitch e {\n", name)
+	for _, v := range values {
+		g.printf("\tcase %s%s:\n\t\treturn %q\n", name, camel(e.Values[v]), e.Values[v])
+	}
+	g.printf("\t}\n\treturn strconv.FormatInt(int64(e), 10)\n}\n")
+}
+
+// check returns the error check of a generated read.
+func (g *generator) check(t *Type, f *Field) string {
+	return fmt.Sprintf("if err != nil {\nreturn nil, fmt.Errorf(\"%s.%s: %%w\", err)\n}\n", g.label(t), f.ID)
+}
+
+func (g *generator) genField(sc *genScope, f *Field) error {
+	target := "v." + camel(f.ID)
+	if f.If != nil {
+		c, err := sc.expr(f.If.root)
+		if err != nil {
+			return sc.fail(f, err)
+		}
+		if c.gt.kind != gBool {
+			return sc.fail(f, fmt.Errorf("if is not a boolean"))
+		}
+		g.printf("if %s {\n", bare(c.code))
+		defer g.printf("}\n")
+	}
+	if "" == f.Repeat {
+		return g.genOne(sc, f, target)
+	}
+	elemGT, elemType, err := g.elemType(sc.t, f)
+	if err != nil {
+		return err
+	}
+	loop := sc.with("_index", gval{code: "i", gt: gtype{kind: gInt}, goType: "int64"})
+	switch f.Repeat {
+	case "expr":
+		n, err := sc.expr(f.RepeatExpr.root)
+		if err != nil {
+			return sc.fail(f, err)
+		}
+		if n.gt.kind != gInt {
+			return sc.fail(f, fmt.Errorf("repeat-expr is not an integer"))
+		}
+		g.printf("for i, n := int64(0), %s; i < n; i++ {\n", asInt(n))
+	case "eos":
+		g.printf("for i := int64(0); ; i++ {\nvar eof bool\neof, err = br.atEOF()\n%sif eof {\nbreak\n}\n", g.check(sc.t, f))
+	default:
+		g.printf("for i := int64(0); ; i++ {\n")
+	}
+	g.printf("var elem %s\n", elemType)
+	if err := g.genOne(loop, f, "elem"); err != nil {
+		return err
+	}
+	g.printf("%s = append(%s, elem)\n", target, target)
+	if "until" == f.Repeat {
+		until, err := loop.with("_", gval{code: "elem", gt: elemGT, goType: elemType}).expr(f.RepeatUntil.root)
+		if err != nil {
+			return sc.fail(f, err)
+		}
+		if until.gt.kind != gBool {
+			return sc.fail(f, fmt.Errorf("repeat-until is not a boolean"))
+		}
+		g.printf("if %s {\nbreak\n}\n", bare(until.code))
+	}
+	g.printf("}\n")
+	return nil
+}
+
+// genOne read one element of f into target.
+func (g *generator) genOne(sc *genScope, f *Field, target string) error {
+	if f.Contents != nil {
+		g.printf("if err = br.expect(%s); err != nil {\nreturn nil, fmt.Errorf(\"%s.%s: %%w\", err)\n}\n",
+			byteLiteral(f.Contents), g.label(sc.t), f.ID)
+		g.printf("%s = %s\n", target, byteLiteral(f.Contents))
+		return nil
+	}
+	if nil == f.Size && !f.SizeEOS && f.Terminator < 0 {
+		return g.genSwitch(sc, f, target, "br", false)
+	}
+	g.printf("{\nvar buf []byte\n")
+	switch {
+	case f.Size != nil:
+		n, err := sc.expr(f.Size.root)
+		if err != nil {
+			return sc.fail(f, err)
+		}
+		if n.gt.kind != gInt {
+			return sc.fail(f, fmt.Errorf("size is not an integer"))
+		}
+		g.printf("buf, err = br.readBytes(%s)\n%s", asInt(n), g.check(sc.t, f))
+		if f.Terminator >= 0 {
+			g.printf("buf = trimTerminator(buf, %d)\n", f.Terminator)
+		}
+	case f.SizeEOS:
+		g.printf("buf, err = br.readAll()\n%s", g.check(sc.t, f))
+	default:
+		g.printf("buf, err = br.readTerminated(%d)\n%s", f.Terminator, g.check(sc.t, f))
+	}
+	if f.Process != "" {
+		g.printf("buf, err = unprocess(br.r, %q, buf)\n%s", f.Process, g.check(sc.t, f))
+	}
+	if err := g.genSwitch(sc, f, target, "br.sub(buf)", true); err != nil {
+		return err
+	}
+	g.printf("}\n")
+	return nil
+}
+
+// genSwitch read target with the type of f, or with a switch on its cases.
+func (g *generator) genSwitch(sc *genScope, f *Field, target, rdr string, sized bool) error {
+	if nil == f.Switch {
+		return g.genTyped(sc, f, f.Type, target, rdr, sized)
+	}
+	on, err := sc.expr(f.Switch.On.root)
+	if err != nil {
+		return sc.fail(f, err)
+	}
+	g.printf("switch on := %s; on {\n", valueOf(on))
+	var def *Case
+	for i, c := range f.Switch.Cases {
+		if nil == c.Match {
+			def = &f.Switch.Cases[i]
+			continue
+		}
+		m, err := sc.expr(c.Match.root)
+		if err != nil {
+			return sc.fail(f, err)
+		}
+		g.printf("case %s:\n", bare(valueOf(m)))
+		if err := g.genTyped(sc, f, c.Type, target, rdr, sized); err != nil {
+			return err
+		}
+	}
+	g.printf("default:\n")
+	switch {
+	case def != nil:
+		if err := g.genTyped(sc, f, def.Type, target, rdr, sized); err != nil {
+			return err
+		}
+	case sized:
+		g.printf("%s = buf\n", target)
+	default:
+		g.printf("return nil, fmt.Errorf(\"%s.%s: no case matches %%v\", on)\n", g.label(sc.t), f.ID)
+	}
+	g.printf("}\n")
+	return nil
+}
+
+// genTyped read target as type name from rdr, or from buf when sized.
+func (g *generator) genTyped(sc *genScope, f *Field, name, target, rdr string, sized bool) error {
+	check := g.check(sc.t, f)
+	p := primitive(name)
+	conv := "x"
+	switch p.kind {
+	case kindBits:
+		switch {
+		case f.Enum != "":
+			conv = g.enumName(sc.t, f) + "(x)"
+		case 1 == p.size:
+			conv = "x == 1"
+		}
+		g.printf("{\nvar x uint64\nx, err = %s.readBits(%d)\n%s%s = %s\n}\n", rdr, p.size, check, target, conv)
+		return nil
+	case kindUint, kindSint, kindFloat:
+		conv = goNumber(p) + "(x)"
+		if kindFloat == p.kind {
+			g.useMath = true
+			conv = "math.Float64frombits(x)"
+			if 4 == p.size {
+				conv = "math.Float32frombits(uint32(x))"
+			}
+		}
+		if f.Enum != "" {
+			conv = g.enumName(sc.t, f) + "(" + conv + ")"
+		}
+		order := "nil"
+		if e := fieldEndian(p, sc.t, nil); e != nil && p.size > 1 {
+			order = "binary." + e.String()
+		}
+		g.printf("{\nvar x uint64\nx, err = %s.readUint(%d, %s)\n%s%s = %s\n}\n", rdr, p.size, order, check, target, conv)
+		return nil
+	case kindLP:
+		conv = "b"
+		if "lpstr" == name {
+			conv = "string(b)"
+		}
+		g.printf("{\nvar b []byte\nb, err = %s.readLP()\n%s%s = %s\n}\n", rdr, check, target, conv)
+		return nil
+	}
+	switch name {
+	case "":
+		g.printf("%s = buf\n", target)
+	case "str", "strz":
+		g.printf("%s = string(buf)\n", target)
+	default:
+		if !sized {
+			rdr = "br"
+		}
+		g.printf("%s, err = read%s(%s)\n%s", target, g.names[sc.t.lookupType(name)], rdr, check)
+	}
+	return nil
+}
+
+func (g *generator) genValueInstance(sc *genScope, f *Field) error {
+	v, err := sc.expr(f.Value.root)
+	if err != nil {
+		return sc.fail(f, err)
+	}
+	code := valueOf(v)
+	if f.Enum != "" {
+		code = g.enumName(sc.t, f) + "(" + asInt(v) + ")"
+	}
+	if f.If != nil {
+		c, err := sc.expr(f.If.root)
+		if err != nil {
+			return sc.fail(f, err)
+		}
+		if c.gt.kind != gBool {
+			return sc.fail(f, fmt.Errorf("if is not a boolean"))
+		}
+		g.printf("if %s {\nv.%s = %s\n}\n", bare(c.code), camel(f.ID), bare(code))
+		return nil
+	}
+	g.printf("v.%s = %s\n", camel(f.ID), bare(code))
+	return nil
+}
+
+func (g *generator) enumName(t *Type, f *Field) string {
+	return g.enums[t.lookupEnum(strings.Split(f.Enum, "::"))]
+}
+
+func goNumber(p prim) string {
+	bits := strconv.Itoa(8 * p.size)
+	switch p.kind {
+	case kindSint:
+		return "int" + bits
+	case kindFloat:
+		return "float" + bits
+	}
+	return "uint" + bits
+}
+
+func byteLiteral(buf []byte) string {
+	parts := make([]string, len(buf))
+	for i, c := range buf {
+		parts[i] = fmt.Sprintf("0x%02x", c)
+	}
+	return "[]byte{" + strings.Join(parts, ", ") + "}"
+}
+
+// kinds of generated expression values
+type gkind int
+
+const (
+	gAny gkind = iota
+	gInt
+	gFloat
+	gBool
+	gString
+	gBytes
+	gList
+	gStruct
+)
+
+type gtype struct {
+	kind gkind
+	t    *Type
+	elem *gtype
+}
+
+// gval is a generated expression: its code, its kind and its Go type, empty for untyped constants.
+type gval struct {
+	code   string
+	gt     gtype
+	goType string
+}
+
+func asInt(v gval) string {
+	if "int64" == v.goType || "" == v.goType {
+		return v.code
+	}
+	return "int64(" + v.code + ")"
+}
+
+func asFloat(v gval) string {
+	if "float64" == v.goType || "" == v.goType {
+		return v.code
+	}
+	return "float64(" + v.code + ")"
+}
+
+// valueOf returns the code of v with integers as int64, so that they compare with each other.
+func valueOf(v gval) string {
+	switch v.gt.kind {
+	case gInt:
+		return asInt(v)
+	case gFloat:
+		return asFloat(v)
+	}
+	return v.code
+}
+
+// bare remove the parentheses around a whole expression.
+func bare(code string) string {
+	if len(code) < 2 || code[0] != '(' || code[len(code)-1] != ')' {
+		return code
+	}
+	depth := 0
+	for i, c := range code {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if 0 == depth && i != len(code)-1 {
+				return code
+			}
+		}
+	}
+	return code[1 : len(code)-1]
+}
+
+// elemType returns the kind and the Go type of one element of f.
+func (g *generator) elemType(t *Type, f *Field) (gtype, string, error) {
+	if f.Contents != nil {
+		return gtype{kind: gBytes}, "[]byte", nil
+	}
+	if f.Value != nil {
+		if g.visiting[f] {
+			return gtype{}, "", fmt.Errorf("schema: %s: the instance refers to itself", t.path(f.ID))
+		}
+		g.visiting[f] = true
+		defer delete(g.visiting, f)
+		v, err := (&genScope{g: g, t: t, vars: map[string]gval{}}).expr(f.Value.root)
+		if err != nil {
+			return gtype{}, "", fmt.Errorf("schema: %s: %w", t.path(f.ID), err)
+		}
+		if f.Enum != "" {
+			return gtype{kind: gInt}, g.enumName(t, f), nil
+		}
+		switch v.gt.kind {
+		case gInt:
+			return v.gt, "int64", nil
+		case gFloat:
+			return v.gt, "float64", nil
+		case gBool, gString, gBytes:
+			return v.gt, goTypeOf(v.gt.kind), nil
+		case gAny:
+			return gtype{}, "", fmt.Errorf("schema: %s: the type of the value is unknown", t.path(f.ID))
+		}
+		return v.gt, v.goType, nil
+	}
+	if f.Switch != nil {
+		return gtype{kind: gAny}, "any", nil
+	}
+	p := primitive(f.Type)
+	switch {
+	case f.Enum != "":
+		return gtype{kind: gInt}, g.enumName(t, f), nil
+	case kindBits == p.kind && 1 == p.size:
+		return gtype{kind: gBool}, "bool", nil
+	case kindBits == p.kind:
+		return gtype{kind: gInt}, "uint64", nil
+	case kindUint == p.kind || kindSint == p.kind:
+		return gtype{kind: gInt}, goNumber(p), nil
+	case kindFloat == p.kind:
+		return gtype{kind: gFloat}, goNumber(p), nil
+	}
+	switch f.Type {
+	case "", "lpbytes":
+		return gtype{kind: gBytes}, "[]byte", nil
+	case "str", "strz", "lpstr":
+		return gtype{kind: gString}, "string", nil
+	}
+	u := t.lookupType(f.Type)
+	return gtype{kind: gStruct, t: u}, "*" + g.names[u], nil
+}
+
+// fieldType returns the kind and the Go type of f, with its repeat.
+func (g *generator) fieldType(t *Type, f *Field) (gtype, string, error) {
+	gt, goType, err := g.elemType(t, f)
+	if err != nil || "" == f.Repeat {
+		return gt, goType, err
+	}
+	return gtype{kind: gList, elem: &gt}, "[]" + goType, nil
+}
+
+// genScope resolves names while generating the expressions of a type.
+type genScope struct {
+	g    *generator
+	t    *Type
+	vars map[string]gval
+}
+
+func (sc *genScope) with(name string, v gval) *genScope {
+	vars := make(map[string]gval, len(sc.vars)+1)
+	for k, e := range sc.vars {
+		vars[k] = e
+	}
+	vars[name] = v
+	return &genScope{g: sc.g, t: sc.t, vars: vars}
+}
+
+func (sc *genScope) fail(f *Field, err error) error {
+	if strings.HasPrefix(err.Error(), "schema: ") {
+		return err
+	}
+	return fmt.Errorf("schema: %s: %w", sc.t.path(f.ID), err)
+}
+
+// field returns the field or the instance id of t, and the code reading it from x.
+func (sc *genScope) field(t *Type, x, id string) (gval, error) {
+	for _, f := range append(t.Seq[:len(t.Seq):len(t.Seq)], t.Instances...) {
+		if f.ID == id {
+			gt, goType, err := sc.g.fieldType(t, f)
+			if err != nil {
+				return gval{}, err
+			}
+			return gval{code: x + "." + camel(id), gt: gt, goType: goType}, nil
+		}
+	}
+	return gval{}, fmt.Errorf("unknown field %q", id)
+}
+
+func (sc *genScope) expr(n node) (gval, error) {
+	switch n := n.(type) {
+	case *literal:
+		switch v := n.v.(type) {
+		case int64:
+			return gval{code: strconv.FormatInt(v, 10), gt: gtype{kind: gInt}}, nil
+		case float64:
+			return gval{code: "float64(" + strconv.FormatFloat(v, 'g', -1, 64) + ")", gt: gtype{kind: gFloat}, goType: "float64"}, nil
+		case string:
+			return gval{code: strconv.Quote(v), gt: gtype{kind: gString}}, nil
+		case bool:
+			return gval{code: strconv.FormatBool(v), gt: gtype{kind: gBool}}, nil
+		}
+	case *ident:
+		if v, ok := sc.vars[n.name]; ok {
+			return v, nil
+		}
+		switch n.name {
+		case "_root", "_parent", "_io", "_", "_index":
+			return gval{}, fmt.Errorf("%s is not supported by GenerateGo here", n.name)
+		}
+		return sc.field(sc.t, "v", n.name)
+	case *enumRef:
+		e := sc.t.lookupEnum(n.path[:len(n.path)-1])
+		name := n.path[len(n.path)-1]
+		if nil == e {
+			return gval{}, fmt.Errorf("unknown enum %s", strings.Join(n.path[:len(n.path)-1], "::"))
+		}
+		if _, ok := e.value(name); !ok {
+			return gval{}, fmt.Errorf("enum %s has no %s", e.Name, name)
+		}
+		c := sc.g.enums[e] + camel(name)
+		return gval{code: c, gt: gtype{kind: gInt}, goType: sc.g.enums[e]}, nil
+	case *member:
+		x, err := sc.expr(n.x)
+		if err != nil {
+			return gval{}, err
+		}
+		switch {
+		case gStruct == x.gt.kind:
+			return sc.field(x.gt.t, x.code, n.name)
+		case ("size" == n.name || "length" == n.name) &&
+			(gList == x.gt.kind || gBytes == x.gt.kind || gString == x.gt.kind):
+			return gval{code: "int64(len(" + x.code + "))", gt: gtype{kind: gInt}, goType: "int64"}, nil
+		case "first" == n.name && gList == x.gt.kind:
+			return gval{code: x.code + "[0]", gt: *x.gt.elem, goType: strings.TrimPrefix(x.goType, "[]")}, nil
+		case "last" == n.name && gList == x.gt.kind:
+			return gval{code: x.code + "[len(" + x.code + ")-1]", gt: *x.gt.elem, goType: strings.TrimPrefix(x.goType, "[]")}, nil
+		case "to_i" == n.name && gInt == x.gt.kind:
+			return gval{code: asInt(x), gt: x.gt, goType: "int64"}, nil
+		}
+		return gval{}, fmt.Errorf("property %q is not supported by GenerateGo", n.name)
+	case *index:
+		x, err := sc.expr(n.x)
+		if err != nil {
+			return gval{}, err
+		}
+		i, err := sc.expr(n.i)
+		if err != nil {
+			return gval{}, err
+		}
+		if i.gt.kind != gInt {
+			return gval{}, fmt.Errorf("index is not an integer")
+		}
+		switch x.gt.kind {
+		case gList:
+			return gval{code: x.code + "[" + bare(i.code) + "]", gt: *x.gt.elem, goType: strings.TrimPrefix(x.goType, "[]")}, nil
+		case gBytes:
+			return gval{code: "int64(" + x.code + "[" + bare(i.code) + "])", gt: gtype{kind: gInt}, goType: "int64"}, nil
+		}
+		return gval{}, fmt.Errorf("value can not be indexed")
+	case *unary:
+		x, err := sc.expr(n.x)
+		if err != nil {
+			return gval{}, err
+		}
+		switch {
+		case "-" == n.op && gInt == x.gt.kind:
+			return gval{code: "(-" + asInt(x) + ")", gt: x.gt, goType: intType(x)}, nil
+		case "-" == n.op && gFloat == x.gt.kind:
+			return gval{code: "(-" + asFloat(x) + ")", gt: x.gt, goType: "float64"}, nil
+		case "~" == n.op && gInt == x.gt.kind:
+			return gval{code: "(^" + asInt(x) + ")", gt: x.gt, goType: intType(x)}, nil
+		case ("!" == n.op || "not" == n.op) && gBool == x.gt.kind:
+			return gval{code: "(!" + x.code + ")", gt: x.gt}, nil
+		}
+		return gval{}, fmt.Errorf("invalid operand for %s", n.op)
+	case *ternary:
+		c, err := sc.expr(n.c)
+		if err != nil {
+			return gval{}, err
+		}
+		a, err := sc.expr(n.a)
+		if err != nil {
+			return gval{}, err
+		}
+		b, err := sc.expr(n.b)
+		if err != nil {
+			return gval{}, err
+		}
+		if c.gt.kind != gBool || a.gt.kind != b.gt.kind {
+			return gval{}, fmt.Errorf("invalid ternary operands")
+		}
+		goType := a.goType
+		switch a.gt.kind {
+		case gInt:
+			goType = "int64"
+		case gFloat:
+			goType = "float64"
+		}
+		code := fmt.Sprintf("cond(%s, %s, %s)", bare(c.code), bare(valueOf(a)), bare(valueOf(b)))
+		if "" == goType {
+			code = fmt.Sprintf("cond[%s](%s, %s, %s)", goTypeOf(a.gt.kind), bare(c.code), a.code, b.code)
+			goType = goTypeOf(a.gt.kind)
+		}
+		return gval{code: code, gt: a.gt, goType: goType}, nil
+	case *binaryOp:
+		return sc.binary(n)
+	}
+	return gval{}, fmt.Errorf("expression is not supported by GenerateGo")
+}
+
+func goTypeOf(k gkind) string {
+	switch k {
+	case gInt:
+		return "int64"
+	case gFloat:
+		return "float64"
+	case gBool:
+		return "bool"
+	case gString:
+		return "string"
+	}
+	return "[]byte"
+}
+
+// intType returns int64, or "" when v is an untyped constant.
+func intType(v gval) string {
+	if "" == v.goType {
+		return ""
+	}
+	return "int64"
+}
+
+func (sc *genScope) binary(n *binaryOp) (gval, error) {
+	l, err := sc.expr(n.l)
+	if err != nil {
+		return gval{}, err
+	}
+	r, err := sc.expr(n.r)
+	if err != nil {
+		return gval{}, err
+	}
+	op := n.op
+	switch op {
+	case "and":
+		op = "&&"
+	case "or":
+		op = "||"
+	}
+	boolean := gtype{kind: gBool}
+	typed := "int64"
+	if "" == l.goType && "" == r.goType {
+		typed = ""
+	}
+	switch {
+	case "&&" == op || "||" == op:
+		if l.gt.kind != gBool || r.gt.kind != gBool {
+			return gval{}, fmt.Errorf("invalid operands for %s", n.op)
+		}
+		return gval{code: "(" + l.code + " " + op + " " + r.code + ")", gt: boolean}, nil
+	case gInt == l.gt.kind && gInt == r.gt.kind:
+		code := "(" + asInt(l) + " " + op + " " + asInt(r) + ")"
+		switch op {
+		case "==", "!=", "<", "<=", ">", ">=":
+			return gval{code: code, gt: boolean}, nil
+		}
+		return gval{code: code, gt: l.gt, goType: typed}, nil
+	case (gInt == l.gt.kind || gFloat == l.gt.kind) && (gInt == r.gt.kind || gFloat == r.gt.kind):
+		if "%" == op || "<<" == op || ">>" == op || "&" == op || "|" == op || "^" == op {
+			return gval{}, fmt.Errorf("invalid float operator %s", op)
+		}
+		lc, rc := "float64("+l.code+")", "float64("+r.code+")"
+		if gFloat == l.gt.kind {
+			lc = asFloat(l)
+		}
+		if gFloat == r.gt.kind {
+			rc = asFloat(r)
+		}
+		code := "(" + lc + " " + op + " " + rc + ")"
+		switch op {
+		case "==", "!=", "<", "<=", ">", ">=":
+			return gval{code: code, gt: boolean}, nil
+		}
+		return gval{code: code, gt: gtype{kind: gFloat}, goType: "float64"}, nil
+	case gBytes == l.gt.kind && gBytes == r.gt.kind && ("==" == op || "!=" == op):
+		code := "bytes.Equal(" + l.code + ", " + r.code + ")"
+		if "!=" == op {
+			code = "(!" + code + ")"
+		}
+		return gval{code: code, gt: boolean}, nil
+	case l.gt.kind == r.gt.kind && (gString == l.gt.kind || gBool == l.gt.kind):
+		code := "(" + l.code + " " + op + " " + r.code + ")"
+		switch {
+		case "==" == op || "!=" == op:
+			return gval{code: code, gt: boolean}, nil
+		case gString == l.gt.kind && "+" == op:
+			return gval{code: code, gt: l.gt, goType: "string"}, nil
+		case gString == l.gt.kind && ("<" == op || "<=" == op || ">" == op || ">=" == op):
+			return gval{code: code, gt: boolean}, nil
+		}
+	}
+	return gval{}, fmt.Errorf("invalid operands for %s", n.op)
+}
+
+// genHelpers is the runtime shared by the generated readers.
+const genHelpers = `
+// bitReader is a BIStream with the partial byte of bit fields. Byte reads drop the bits left in it.
+type bitReader struct {
+	r     *bitstream.BIStream
+	bits  byte
+	nbits int
+}
+
+func (br *bitReader) sub(buf []byte) *bitReader {
+	return &bitReader{r: bitstream.NewBIStream(br.r.Endian(), bytes.NewReader(buf)).WithDecompressLimit(br.r.DecompressLimit())}
+}
+
+func (br *bitReader) readBits(n int) (uint64, error) {
+	var v uint64
+	for n > 0 {
+		if 0 == br.nbits {
+			c, err := br.r.ReadByte()
+			if err != nil {
+				return 0, err
+			}
+			br.bits, br.nbits = c, 8
+		}
+		k := min(n, br.nbits)
+		v = v<<k | uint64(br.bits>>(br.nbits-k))&(1<<k-1)
+		br.nbits -= k
+		n -= k
+	}
+	return v, nil
+}
+
+func (br *bitReader) readBytes(n int64) ([]byte, error) {
+	br.nbits = 0
+	if n < 0 {
+		return nil, fmt.Errorf("negative size %d", n)
+	}
+	return br.r.ReadBytes(uint64(n))
+}
+
+func (br *bitReader) readUint(n int, order binary.ByteOrder) (uint64, error) {
+	buf, err := br.readBytes(int64(n))
+	if err != nil {
+		return 0, err
+	}
+	if nil == order {
+		order = br.r.Endian()
+	}
+	switch n {
+	case 2:
+		return uint64(order.Uint16(buf)), nil
+	case 4:
+		return uint64(order.Uint32(buf)), nil
+	case 8:
+		return order.Uint64(buf), nil
+	}
+	return uint64(buf[0]), nil
+}
+
+func (br *bitReader) readLP() ([]byte, error) {
+	br.nbits = 0
+	return br.r.ReadBytesWithLengthPrefix()
+}
+
+func (br *bitReader) readAll() ([]byte, error) {
+	br.nbits = 0
+	return br.r.ReadAll()
+}
+
+func (br *bitReader) readTerminated(term byte) ([]byte, error) {
+	br.nbits = 0
+	var buf []byte
+	for {
+		c, err := br.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if term == c {
+			return buf, nil
+		}
+		buf = append(buf, c)
+	}
+}
+
+func (br *bitReader) expect(magic []byte) error {
+	br.nbits = 0
+	return br.r.ExpectBytes(magic).Error()
+}
+
+func (br *bitReader) atEOF() (bool, error) {
+	if br.nbits > 0 {
+		return false, nil
+	}
+	return br.r.AtEOF()
+}
+
+func trimTerminator(buf []byte, term byte) []byte {
+	if i := bytes.IndexByte(buf, term); i >= 0 {
+		return buf[:i]
+	}
+	return buf
+}
+
+func unprocess(r *bitstream.BIStream, name string, buf []byte) ([]byte, error) {
+	codec, ok := bitstream.LookupCodec(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown process %q", name)
+	}
+	sub, err := r.Decompress(buf, codec)
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Close()
+	return sub.ReadAll()
+}
+
+func cond[T any](c bool, a, b T) T {
+	if c {
+		return a
+	}
+	return b
+}
+`
@@ -0,0 +1,69 @@
+package schema
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the generated readers in internal/ksytest")
+
+func TestSchema_GenerateGo(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+	}{
+		{name: "TestSchema_GenerateGo_PNG", args: "png"},
+		{name: "TestSchema_GenerateGo_WAV", args: "wav"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := LoadFile("testdata/" + tt.args + ".ksy")
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := new(bytes.Buffer)
+			if err := s.GenerateGo(buf, tt.args); err != nil {
+				t.Fatal(err)
+			}
+			name := filepath.Join("internal", "ksytest", tt.args, tt.args+".go")
+			if *update {
+				if err := os.WriteFile(name, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("GenerateGo() differs from %s, run go test -update", name)
+			}
+		})
+	}
+}
+
+func TestSchema_GenerateGoErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+	}{
+		{name: "TestSchema_GenerateGoErrors_Pos", args: "seq:\n  - id: a\n    type: u1\ninstances:\n  b:\n    pos: a\n    type: u1\n"},
+		{name: "TestSchema_GenerateGoErrors_Parent", args: "seq:\n  - id: a\n    type: t\ntypes:\n  t:\n    seq:\n      - id: b\n        size: _parent.x\n"},
+		{name: "TestSchema_GenerateGoErrors_Collision", args: "seq:\n  - id: a\n    type: u1\n    enum: ab\ntypes:\n  ab:\n    seq: []\nenums:\n  ab:\n    1: x\n"},
+		{name: "TestSchema_GenerateGoErrors_Mixed", args: "seq:\n  - id: a\n    size: '\"x\" + 1'\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Load([]byte(tt.args))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.GenerateGo(new(bytes.Buffer), "x"); nil == err {
+				t.Errorf("GenerateGo() error = nil, want an error")
+			}
+		})
+	}
+}
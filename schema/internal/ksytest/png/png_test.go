@@ -0,0 +1,66 @@
+package png
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+func TestReadPng(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/png.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ReadPng(bitstream.NewBIStream(binary.LittleEndian, bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Chunks) != 4 || p.Chunks[3].Type != "IEND" {
+		t.Fatalf("Chunks = %+v", p.Chunks)
+	}
+	ihdr, ok := p.Chunks[0].Body.(*IhdrChunk)
+	if !ok || ihdr.Width != 2 || ihdr.Height != 1 || ihdr.ColorType != ColorTypeTruecolor {
+		t.Errorf("IHDR = %+v", p.Chunks[0].Body)
+	}
+	text, ok := p.Chunks[1].Body.(*TextChunk)
+	if !ok || text.Keyword != "Comment" || text.Text != "bitstream" {
+		t.Errorf("tEXt = %+v", p.Chunks[1].Body)
+	}
+	idat, ok := p.Chunks[2].Body.(*IdatChunk)
+	if !ok || !bytes.Equal(idat.Scanlines, []byte{0, 255, 0, 0, 0, 0, 255}) {
+		t.Errorf("IDAT = %+v", p.Chunks[2].Body)
+	}
+	if ColorTypeTruecolor.String() != "truecolor" || ColorType(5).String() != "5" {
+		t.Errorf("String() = %s, %s", ColorTypeTruecolor, ColorType(5))
+	}
+}
+
+func TestReadPng_DecompressLimit(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/png.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPng(bitstream.NewBIStream(binary.BigEndian, bytes.NewReader(data)).WithDecompressLimit(7)); err != nil {
+		t.Errorf("ReadPng() error = %v", err)
+	}
+	_, err = ReadPng(bitstream.NewBIStream(binary.BigEndian, bytes.NewReader(data)).WithDecompressLimit(6))
+	if !errors.Is(err, bitstream.ErrDecompressedTooLarge) {
+		t.Errorf("ReadPng() error = %v, want %v", err, bitstream.ErrDecompressedTooLarge)
+	}
+}
+
+func TestReadPng_Truncated(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/png.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < len(data); n++ {
+		if _, err := ReadPng(bitstream.NewBIStream(binary.BigEndian, bytes.NewReader(data[:n]))); nil == err {
+			t.Errorf("ReadPng(%d bytes) error = nil", n)
+		}
+	}
+}
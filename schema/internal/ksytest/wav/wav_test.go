@@ -0,0 +1,33 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"reflect"
+	"testing"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+func TestReadWav(t *testing.T) {
+	data, err := os.ReadFile("../../../testdata/wav.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := ReadWav(bitstream.NewBIStream(binary.BigEndian, bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Chunks) != 2 {
+		t.Fatalf("Chunks = %+v", w.Chunks)
+	}
+	format, ok := w.Chunks[0].Body.(*FormatChunk)
+	if !ok || format.FormatTag != WaveFormatPcm || !format.IsPcm || format.SampleRate != 8000 {
+		t.Errorf("fmt = %+v", w.Chunks[0].Body)
+	}
+	samples, ok := w.Chunks[1].Body.(*DataChunk)
+	if !ok || !reflect.DeepEqual(samples.Samples, []int16{0, 1000, -1000, 32767, -32768, 7}) {
+		t.Errorf("data = %+v", w.Chunks[1].Body)
+	}
+}
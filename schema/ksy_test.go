@@ -0,0 +1,216 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+func TestLoadFile_Ksy(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+	}{
+		{name: "TestLoadFile_Ksy_PNG", args: "png"},
+		{name: "TestLoadFile_Ksy_WAV", args: "wav"},
+		{name: "TestLoadFile_Ksy_BMP", args: "bmp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := LoadFile("testdata/" + tt.args + ".ksy")
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile("testdata/" + tt.args + ".bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile("testdata/" + tt.args + ".json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the stream endian must not matter, every description has one
+			got, err := s.Decode(bitstream.NewBIStream(binary.BigEndian, bytes.NewReader(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			js, _ := json.MarshalIndent(got, "", "  ")
+			if string(js)+"\n" != string(want) {
+				t.Errorf("Decode() = %s, want %s", js, want)
+			}
+		})
+	}
+}
+
+func TestSchema_EncodeKsy(t *testing.T) {
+	s, err := LoadFile("testdata/wav.ksy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("testdata/wav.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := s.Decode(bitstream.NewBIStream(binary.LittleEndian, bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := s.Encode(bitstream.NewBOStream(binary.LittleEndian, buf), v); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(buf.Bytes(), data) {
+		t.Errorf("Encode() = %x, want %x", buf.Bytes(), data)
+	}
+	if n, err := s.Size(v); err != nil || n != int64(len(data)) {
+		t.Errorf("Size() = %v, %v, want %v", n, err, len(data))
+	}
+}
+
+func TestSchema_Process(t *testing.T) {
+	s, err := Load([]byte("seq:\n  - id: len\n    type: u1\n  - id: text\n    type: str\n    size: len\n    process: zlib\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the encoder does not compute len, so write once to learn the compressed size
+	m := map[string]any{"len": 0, "text": "hello hello hello"}
+	sized := new(bytes.Buffer)
+	err = s.Encode(bitstream.NewBOStream(binary.BigEndian, sized), m)
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Path != "text" {
+		t.Fatalf("Encode() error = %v, want a FieldError on text", err)
+	}
+	buf := new(bytes.Buffer)
+	w, _ := bitstream.Zlib.NewWriter(buf)
+	w.Write([]byte("hello hello hello"))
+	w.Close()
+	m["len"] = buf.Len()
+	out := new(bytes.Buffer)
+	if err := s.Encode(bitstream.NewBOStream(binary.BigEndian, out), m); err != nil {
+		t.Fatal(err)
+	}
+	data := out.Bytes()
+	got, err := s.Decode(bitstream.NewBIStream(binary.BigEndian, bytes.NewReader(data)).WithDecompressLimit(17))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := got.Get("text"); text != "hello hello hello" {
+		t.Errorf("Decode() text = %q", text)
+	}
+	_, err = s.Decode(bitstream.NewBIStream(binary.BigEndian, bytes.NewReader(data)).WithDecompressLimit(16))
+	if !errors.Is(err, bitstream.ErrDecompressedTooLarge) {
+		t.Errorf("Decode() error = %v, want %v", err, bitstream.ErrDecompressedTooLarge)
+	}
+}
+
+func TestSchema_Enums(t *testing.T) {
+	s, err := Load([]byte(`
+seq:
+  - id: kind
+    type: u1
+    enum: kind
+  - id: body
+    type:
+      switch-on: kind
+      cases:
+        kind::short: u1
+        kind::long: u4be
+instances:
+  is_long:
+    value: kind == kind::long
+  raw:
+    value: kind.to_i
+enums:
+  kind:
+    1: short
+    2: long
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		args []byte
+		want string
+	}{
+		{name: "TestSchema_Enums_Short", args: []byte{1, 7}, want: `{"kind":"short","body":7,"is_long":false,"raw":1}`},
+		{name: "TestSchema_Enums_Long", args: []byte{2, 0, 0, 1, 0}, want: `{"kind":"long","body":256,"is_long":true,"raw":2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Decode(bitstream.NewBIStream(binary.BigEndian, bytes.NewReader(tt.args)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if js, _ := json.Marshal(got); string(js) != tt.want {
+				t.Errorf("Decode() = %s, want %s", js, tt.want)
+			}
+			buf := new(bytes.Buffer)
+			if err := s.Encode(bitstream.NewBOStream(binary.BigEndian, buf), got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(buf.Bytes(), tt.args) {
+				t.Errorf("Encode() = %x, want %x", buf.Bytes(), tt.args)
+			}
+		})
+	}
+}
+
+func TestLoad_KsyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+	}{
+		{name: "TestLoad_KsyErrors_UnknownEnum", args: "seq:\n  - id: a\n    type: u1\n    enum: nope\n"},
+		{name: "TestLoad_KsyErrors_EnumOnStr", args: "seq:\n  - id: a\n    type: str\n    size: 1\n    enum: e\nenums:\n  e:\n    1: x\n"},
+		{name: "TestLoad_KsyErrors_UnknownProcess", args: "seq:\n  - id: a\n    size: 1\n    process: rot13\n"},
+		{name: "TestLoad_KsyErrors_PosInSeq", args: "seq:\n  - id: a\n    type: u1\n    pos: 2\n"},
+		{name: "TestLoad_KsyErrors_BareInstance", args: "instances:\n  a:\n    type: u1\n"},
+		{name: "TestLoad_KsyErrors_Imports", args: "meta:\n  imports:\n    - common\n"},
+		{name: "TestLoad_KsyErrors_BitEndian", args: "meta:\n  bit-endian: le\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load([]byte(tt.args)); nil == err {
+				t.Errorf("Load() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestSchema_DecodeTrace(t *testing.T) {
+	s, err := LoadFile("testdata/wav.ksy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("testdata/wav.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := bitstream.NewTrace()
+	if _, err := s.Decode(bitstream.NewBIStream(binary.LittleEndian, bytes.NewReader(data)).WithTrace(trace)); err != nil {
+		t.Fatal(err)
+	}
+	want := []bitstream.Op{
+		{Kind: "bytes", Offset: 0, Size: 4, Value: []byte("RIFF"), Label: "riff_id"},
+		{Kind: "u4", Offset: 4, Size: 4, Value: uint64(48), Label: "file_size"},
+		{Kind: "bytes", Offset: 8, Size: 4, Value: []byte("WAVE"), Label: "wave_id"},
+		{Kind: "section", Offset: 12, Size: 24, Label: "chunks[0]"},
+		{Kind: "str", Offset: 12, Size: 4, Value: "fmt ", Label: "id", Depth: 1},
+		{Kind: "u4", Offset: 16, Size: 4, Value: uint64(16), Label: "len", Depth: 1},
+		{Kind: "section", Offset: 20, Size: 16, Label: "body", Depth: 1},
+		{Kind: "u2", Offset: 20, Size: 2, Value: EnumValue{Name: "pcm", Value: 1}, Label: "format_tag", Depth: 2},
+	}
+	if !reflect.DeepEqual(trace.Ops[:len(want)], want) {
+		t.Errorf("Ops = %+v, want %+v", trace.Ops[:len(want)], want)
+	}
+	if !bytes.Equal(trace.Bytes(), data) {
+		t.Errorf("Bytes() = %x, want %x", trace.Bytes(), data)
+	}
+}
@@ -0,0 +1,762 @@
+// Package schema decodes and encodes binary formats described declaratively in YAML or JSON.
+//
+// A description lists the fields of the root type in seq, and the user types in types:
+//
+//	meta:
+//	  endian: be
+//	seq:
+//	  - id: magic
+//	    contents: "PK"
+//	  - id: count
+//	    type: u2
+//	  - id: entries
+//	    type: entry
+//	    repeat: expr
+//	    repeat-expr: count
+//	types:
+//	  entry:
+//	    seq:
+//	      - id: flags
+//	        type: b4
+//	      - id: kind
+//	        type: b4
+//	      - id: body
+//	        size: 4
+//	        if: kind != 0
+//
+// The primitive types are u1, u2, u4, u8 and s1, s2, s4, s8 for integers, f4 and f8 for floats, with an
+// optional le or be suffix, bN for a big-endian field of N bits (b1 is a bool), str and strz for strings,
+// and lpstr and lpbytes for the length prefixed strings and bytes of BIStream. A field without type is raw
+// bytes. The size of str, raw bytes and sized user types comes from size, size-eos or terminator.
+//
+// A type is either a name or a switch:
+//
+//	type:
+//	  switch-on: kind
+//	  cases:
+//	    1: header
+//	    2: u4
+//	    _: body
+//
+// The keys follow Kaitai Struct, and the supported subset of .ksy files loads as is: enums with the enum key
+// of integer fields and enum::name in expressions, instances with pos or value, and process with the name
+// of a registered codec, like zlib. A sized switch without a matching case reads raw bytes. GenerateGo
+// writes Go readers for a description, without writers: the generated types are not bitstream.Marshaler,
+// use Schema.Size to size a value.
+package schema
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	bitstream "github.com/meetleev/go_bitstream"
+)
+
+// Schema is a loaded format description.
+type Schema struct {
+	// ID is the meta id of the description, if any.
+	ID string
+	// Root is the top level type.
+	Root *Type
+	// seekable is set when an instance uses pos, which needs the whole stream in memory.
+	seekable bool
+}
+
+// Type is a user type: a sequence of fields and the types nested in it.
+type Type struct {
+	Name string
+	// Endian is the default byte order of the fields. nil inherits the parent's, then the stream's.
+	Endian binary.ByteOrder
+	Seq    []*Field
+	Types  map[string]*Type
+	Enums  map[string]*Enum
+	// Instances are decoded after Seq, or on first use by an expression. They are sorted by ID.
+	Instances []*Field
+	Parent    *Type
+}
+
+// Enum maps integer values to names.
+type Enum struct {
+	Name   string
+	Values map[int64]string
+}
+
+func (e *Enum) value(name string) (int64, bool) {
+	for v, n := range e.Values {
+		if n == name {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// Field is one entry of a seq.
+type Field struct {
+	ID string
+	// Type is the primitive or user type name. It is empty for raw bytes and switches.
+	Type   string
+	Switch *Switch
+	// Size is the size in bytes of str, raw bytes and user types.
+	Size *Expr
+	// SizeEOS makes the field span the rest of the stream.
+	SizeEOS bool
+	// Terminator ends the field when it is 0 or more. It is consumed but not included in the value.
+	Terminator int
+	// Contents is the fixed value of a magic field.
+	Contents []byte
+	// Repeat is "", "expr", "eos" or "until".
+	Repeat      string
+	RepeatExpr  *Expr
+	RepeatUntil *Expr
+	// If skips the field when it is false.
+	If *Expr
+	// Enum names the enum of an integer field.
+	Enum string
+	// Process is the name of the codec that decompresses the bytes of the field, like "zlib".
+	Process string
+	// Pos is the offset of an instance from the start of its stream.
+	Pos *Expr
+	// Value is the expression of a value instance.
+	Value *Expr
+}
+
+// Switch picks the type of a field from the value of On.
+type Switch struct {
+	On    *Expr
+	Cases []Case
+}
+
+// Case is a branch of a Switch. Match is nil for the default branch "_".
+type Case struct {
+	Match *Expr
+	Type  string
+}
+
+// FieldError is returned by Decode and Encode. Path locates the field, like "entries[2].body".
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("schema: %s: %v", e.Path, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Load parse a description in JSON or YAML.
+func Load(data []byte) (*Schema, error) {
+	var (
+		doc any
+		err error
+	)
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && '{' == trimmed[0] {
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		dec.UseNumber()
+		if err = dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("schema: %w", err)
+		}
+		doc = fromJSON(doc)
+	} else if doc, err = parseYAML(data); err != nil {
+		return nil, err
+	}
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("schema: the description is not a mapping")
+	}
+	root, err := loadType("", m, nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &Schema{Root: root}
+	if meta, ok := m["meta"].(map[string]any); ok {
+		s.ID, _ = meta["id"].(string)
+	}
+	if err := s.check(root); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadFile read and parse a description file.
+func LoadFile(name string) (*Schema, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data)
+}
+
+// fromJSON convert json.Number to int64 or float64, like the YAML scalars.
+func fromJSON(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); nil == err {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(v), 10, 64); nil == err {
+			return int64(u)
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = fromJSON(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = fromJSON(e)
+		}
+	}
+	return v
+}
+
+// lookupType resolve a user type name from t up to the root.
+func (t *Type) lookupType(name string) *Type {
+	for ; t != nil; t = t.Parent {
+		if u, ok := t.Types[name]; ok {
+			return u
+		}
+	}
+	return nil
+}
+
+// lookupEnum resolve an enum name, optionally prefixed by the path of the type declaring it.
+func (t *Type) lookupEnum(path []string) *Enum {
+	if 0 == len(path) {
+		return nil
+	}
+	if len(path) > 1 {
+		u := t.lookupType(path[0])
+		for _, name := range path[1 : len(path)-1] {
+			if nil == u {
+				return nil
+			}
+			u = u.Types[name]
+		}
+		if nil == u {
+			return nil
+		}
+		return u.Enums[path[len(path)-1]]
+	}
+	for ; t != nil; t = t.Parent {
+		if e, ok := t.Enums[path[0]]; ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// check verify that every user type, enum and codec used by t and its nested types exists.
+func (s *Schema) check(t *Type) error {
+	for _, f := range append(t.Seq[:len(t.Seq):len(t.Seq)], t.Instances...) {
+		names := []string{f.Type}
+		if f.Switch != nil {
+			names = names[:0]
+			for _, c := range f.Switch.Cases {
+				names = append(names, c.Type)
+			}
+		}
+		for _, name := range names {
+			if name != "" && !isPrimitive(name) && nil == t.lookupType(name) {
+				return fmt.Errorf("schema: %s: unknown type %q", t.path(f.ID), name)
+			}
+		}
+		if f.Enum != "" && nil == t.lookupEnum(strings.Split(f.Enum, "::")) {
+			return fmt.Errorf("schema: %s: unknown enum %q", t.path(f.ID), f.Enum)
+		}
+		if _, ok := bitstream.LookupCodec(f.Process); f.Process != "" && !ok {
+			return fmt.Errorf("schema: %s: unknown process %q", t.path(f.ID), f.Process)
+		}
+		if f.Pos != nil {
+			s.seekable = true
+		}
+	}
+	for _, u := range sortedTypes(t) {
+		if err := s.check(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedTypes(t *Type) []*Type {
+	names := make([]string, 0, len(t.Types))
+	for name := range t.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	types := make([]*Type, len(names))
+	for i, name := range names {
+		types[i] = t.Types[name]
+	}
+	return types
+}
+
+// path returns the location of a field of t in the description, for load errors.
+func (t *Type) path(id string) string {
+	var parts []string
+	for u := t; u != nil && u.Name != ""; u = u.Parent {
+		parts = append([]string{"types." + u.Name}, parts...)
+	}
+	return strings.Join(append(parts, id), ".")
+}
+
+var typeKeys = map[string]bool{"meta": true, "seq": true, "types": true, "enums": true, "instances": true,
+	"doc": true, "doc-ref": true}
+
+var metaKeys = map[string]bool{"id": true, "title": true, "application": true, "file-extension": true,
+	"xref": true, "license": true, "ks-version": true, "ks-debug": true, "ks-opaque-types": true,
+	"endian": true, "bit-endian": true, "encoding": true}
+
+func loadType(name string, m map[string]any, parent *Type) (*Type, error) {
+	t := &Type{Name: name, Parent: parent, Types: map[string]*Type{}, Enums: map[string]*Enum{}}
+	for k := range m {
+		if !typeKeys[k] {
+			return nil, fmt.Errorf("schema: %s: unknown key %q", t.path(""), k)
+		}
+	}
+	if meta, ok := m["meta"]; ok {
+		mm, ok := meta.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("schema: %s: meta is not a mapping", t.path("meta"))
+		}
+		for k := range mm {
+			if !metaKeys[k] {
+				return nil, fmt.Errorf("schema: %s: unsupported meta key %q", t.path("meta"), k)
+			}
+		}
+		if e, ok := mm["bit-endian"]; ok && e != "be" {
+			return nil, fmt.Errorf("schema: %s: only bit-endian be is supported", t.path("meta"))
+		}
+		if e, ok := mm["encoding"]; ok {
+			if err := checkEncoding(e); err != nil {
+				return nil, fmt.Errorf("schema: %s: %w", t.path("meta"), err)
+			}
+		}
+		if e, ok := mm["endian"]; ok {
+			switch e {
+			case "be":
+				t.Endian = binary.BigEndian
+			case "le":
+				t.Endian = binary.LittleEndian
+			default:
+				return nil, fmt.Errorf("schema: %s: endian must be be or le, not %v", t.path("meta"), e)
+			}
+		}
+	}
+	if types, ok := m["types"]; ok {
+		tm, ok := types.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("schema: %s: types is not a mapping", t.path("types"))
+		}
+		for k, v := range tm {
+			um, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("schema: %s: type %q is not a mapping", t.path("types"), k)
+			}
+			if isPrimitive(k) {
+				return nil, fmt.Errorf("schema: %s: type %q shadows a primitive type", t.path("types"), k)
+			}
+			u, err := loadType(k, um, t)
+			if err != nil {
+				return nil, err
+			}
+			t.Types[k] = u
+		}
+	}
+	if enums, ok := m["enums"]; ok {
+		em, ok := enums.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("schema: %s: enums is not a mapping", t.path("enums"))
+		}
+		for k, v := range em {
+			e, err := loadEnum(k, v)
+			if err != nil {
+				return nil, fmt.Errorf("schema: %s: %w", t.path("enums."+k), err)
+			}
+			t.Enums[k] = e
+		}
+	}
+	if seq, ok := m["seq"]; ok {
+		list, ok := seq.([]any)
+		if !ok {
+			return nil, fmt.Errorf("schema: %s: seq is not a sequence", t.path("seq"))
+		}
+		ids := map[string]bool{}
+		for i, v := range list {
+			fm, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("schema: %s: field is not a mapping", t.path(fmt.Sprintf("seq[%d]", i)))
+			}
+			f, err := loadField(fm)
+			if nil == err && (f.Pos != nil || f.Value != nil) {
+				err = fmt.Errorf("pos and value are only allowed in instances")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("schema: %s: %w", t.path(fmt.Sprintf("seq[%d]", i)), err)
+			}
+			if ids[f.ID] {
+				return nil, fmt.Errorf("schema: %s: duplicate id %q", t.path(fmt.Sprintf("seq[%d]", i)), f.ID)
+			}
+			ids[f.ID] = true
+			t.Seq = append(t.Seq, f)
+		}
+	}
+	if instances, ok := m["instances"]; ok {
+		im, ok := instances.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("schema: %s: instances is not a mapping", t.path("instances"))
+		}
+		for _, k := range sortedKeys(im) {
+			fm, ok := im[k].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("schema: %s: instance is not a mapping", t.path("instances."+k))
+			}
+			fm = maps.Clone(fm)
+			fm["id"] = k
+			f, err := loadField(fm)
+			if nil == err && nil == f.Pos && nil == f.Value {
+				err = fmt.Errorf("an instance needs pos or value")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("schema: %s: %w", t.path("instances."+k), err)
+			}
+			for _, sf := range t.Seq {
+				if sf.ID == k {
+					return nil, fmt.Errorf("schema: %s: duplicate id %q", t.path("instances."+k), k)
+				}
+			}
+			t.Instances = append(t.Instances, f)
+		}
+	}
+	return t, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func loadEnum(name string, v any) (*Enum, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("enum is not a mapping")
+	}
+	e := &Enum{Name: name, Values: map[int64]string{}}
+	ids := map[string]bool{}
+	for k, v := range m {
+		n, err := strconv.ParseInt(k, 0, 64)
+		if err != nil {
+			u, uerr := strconv.ParseUint(k, 0, 64)
+			if uerr != nil {
+				return nil, fmt.Errorf("enum key %q is not an integer", k)
+			}
+			n = int64(u)
+		}
+		if vm, ok := v.(map[string]any); ok {
+			v = vm["id"]
+		}
+		id, ok := v.(string)
+		if !ok || "" == id {
+			return nil, fmt.Errorf("enum value %s has no id", k)
+		}
+		if ids[id] {
+			return nil, fmt.Errorf("duplicate enum id %q", id)
+		}
+		ids[id] = true
+		e.Values[n] = id
+	}
+	return e, nil
+}
+
+func checkEncoding(v any) error {
+	switch strings.ToUpper(fmt.Sprint(v)) {
+	case "UTF-8", "UTF8", "ASCII":
+		return nil
+	}
+	return fmt.Errorf("unsupported encoding %v", v)
+}
+
+var fieldKeys = map[string]bool{"id": true, "type": true, "size": true, "size-eos": true, "terminator": true,
+	"contents": true, "repeat": true, "repeat-expr": true, "repeat-until": true, "if": true, "encoding": true,
+	"enum": true, "process": true, "pos": true, "value": true, "doc": true, "doc-ref": true}
+
+func loadField(m map[string]any) (*Field, error) {
+	for k := range m {
+		if !fieldKeys[k] {
+			return nil, fmt.Errorf("unknown key %q", k)
+		}
+	}
+	f := &Field{Terminator: -1}
+	id, ok := m["id"].(string)
+	if !ok || "" == id {
+		return nil, fmt.Errorf("id is missing")
+	}
+	f.ID = id
+	var err error
+	expr := func(key string) *Expr {
+		v, ok := m[key]
+		if !ok || err != nil {
+			return nil
+		}
+		var e *Expr
+		e, err = ParseExpr(fmt.Sprint(v))
+		return e
+	}
+	f.Size = expr("size")
+	f.If = expr("if")
+	f.RepeatExpr = expr("repeat-expr")
+	f.RepeatUntil = expr("repeat-until")
+	f.Pos = expr("pos")
+	f.Value = expr("value")
+	if err != nil {
+		return nil, err
+	}
+	switch t := m["type"].(type) {
+	case nil:
+	case string:
+		f.Type = t
+	case map[string]any:
+		if f.Switch, err = loadSwitch(t); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("type must be a name or a switch")
+	}
+	if v, ok := m["size-eos"]; ok {
+		if f.SizeEOS, ok = v.(bool); !ok {
+			return nil, fmt.Errorf("size-eos must be a boolean")
+		}
+	}
+	if v, ok := m["terminator"]; ok {
+		i, ok := v.(int64)
+		if !ok || i < 0 || i > 0xff {
+			return nil, fmt.Errorf("terminator must be a byte")
+		}
+		f.Terminator = int(i)
+	}
+	if v, ok := m["contents"]; ok {
+		if f.Contents, err = loadContents(v); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := m["encoding"]; ok {
+		if err := checkEncoding(v); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := m["enum"]; ok {
+		f.Enum = fmt.Sprint(v)
+	}
+	if v, ok := m["process"]; ok {
+		f.Process = fmt.Sprint(v)
+	}
+	if v, ok := m["repeat"]; ok {
+		f.Repeat = fmt.Sprint(v)
+	}
+	if "strz" == f.Type && f.Terminator < 0 {
+		f.Terminator = 0
+	}
+	return f, f.validate()
+}
+
+func (f *Field) validate() error {
+	switch f.Repeat {
+	case "":
+	case "expr":
+		if nil == f.RepeatExpr {
+			return fmt.Errorf("repeat: expr needs repeat-expr")
+		}
+	case "eos":
+	case "until":
+		if nil == f.RepeatUntil {
+			return fmt.Errorf("repeat: until needs repeat-until")
+		}
+	default:
+		return fmt.Errorf("repeat must be expr, eos or until, not %q", f.Repeat)
+	}
+	sized := f.Size != nil || f.SizeEOS || f.Terminator >= 0
+	if f.Value != nil {
+		if f.Type != "" || f.Switch != nil || sized || f.Contents != nil || f.Repeat != "" || f.Pos != nil || f.Process != "" {
+			return fmt.Errorf("a value instance can only have if and enum")
+		}
+		return nil
+	}
+	if f.Enum != "" {
+		if k := primitive(f.Type).kind; k != kindUint && k != kindSint && k != kindBits {
+			return fmt.Errorf("enum needs an integer type")
+		}
+	}
+	if f.Process != "" && !sized {
+		return fmt.Errorf("process needs size, size-eos or terminator")
+	}
+	if f.Contents != nil {
+		if f.Type != "" || f.Switch != nil || sized {
+			return fmt.Errorf("contents can not have a type or a size")
+		}
+		return nil
+	}
+	switch p := primitive(f.Type); {
+	case f.Switch != nil:
+	case "" == f.Type || "str" == f.Type || "strz" == f.Type:
+		if !sized {
+			return fmt.Errorf("%s needs size, size-eos or terminator", f.describe())
+		}
+	case p.kind != 0:
+		if sized {
+			return fmt.Errorf("%s can not have a size", f.Type)
+		}
+	}
+	if f.Size != nil && f.SizeEOS {
+		return fmt.Errorf("size and size-eos are exclusive")
+	}
+	return nil
+}
+
+func (f *Field) describe() string {
+	if "" == f.Type {
+		return "raw bytes"
+	}
+	return f.Type
+}
+
+func loadSwitch(m map[string]any) (*Switch, error) {
+	for k := range m {
+		if k != "switch-on" && k != "cases" {
+			return nil, fmt.Errorf("unknown key %q in switch", k)
+		}
+	}
+	on, ok := m["switch-on"]
+	if !ok {
+		return nil, fmt.Errorf("switch-on is missing")
+	}
+	e, err := ParseExpr(fmt.Sprint(on))
+	if err != nil {
+		return nil, err
+	}
+	cases, ok := m["cases"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("cases is not a mapping")
+	}
+	sw := &Switch{On: e}
+	keys := make([]string, 0, len(cases))
+	for k := range cases {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name, ok := cases[k].(string)
+		if !ok {
+			return nil, fmt.Errorf("case %s is not a type name", k)
+		}
+		c := Case{Type: name}
+		if k != "_" {
+			if c.Match, err = ParseExpr(k); err != nil {
+				return nil, err
+			}
+		}
+		sw.Cases = append(sw.Cases, c)
+	}
+	return sw, nil
+}
+
+func loadContents(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), nil
+	case []any:
+		var buf []byte
+		for _, e := range v {
+			switch e := e.(type) {
+			case int64:
+				if e < 0 || e > 0xff {
+					return nil, fmt.Errorf("contents byte %d is out of range", e)
+				}
+				buf = append(buf, byte(e))
+			case string:
+				buf = append(buf, e...)
+			default:
+				return nil, fmt.Errorf("contents must be bytes or strings")
+			}
+		}
+		return buf, nil
+	}
+	return nil, fmt.Errorf("contents must be a string or a sequence")
+}
+
+// kinds of primitive types
+const (
+	kindUint = iota + 1
+	kindSint
+	kindFloat
+	kindBits
+	kindStr
+	kindLP
+)
+
+type prim struct {
+	kind   int
+	size   int
+	endian binary.ByteOrder
+}
+
+// primitive parse a primitive type name. kind is 0 for user types, raw bytes and str with a size.
+func primitive(name string) prim {
+	if "lpstr" == name || "lpbytes" == name {
+		return prim{kind: kindLP}
+	}
+	if len(name) < 2 {
+		return prim{}
+	}
+	var p prim
+	switch name[0] {
+	case 'u':
+		p.kind = kindUint
+	case 's':
+		p.kind = kindSint
+	case 'f':
+		p.kind = kindFloat
+	case 'b':
+		n, err := strconv.Atoi(name[1:])
+		if err != nil || n < 1 || n > 64 || name[1] == '0' {
+			return prim{}
+		}
+		return prim{kind: kindBits, size: n}
+	default:
+		return prim{}
+	}
+	rest := name[1:]
+	if strings.HasSuffix(rest, "le") {
+		p.endian, rest = binary.LittleEndian, strings.TrimSuffix(rest, "le")
+	} else if strings.HasSuffix(rest, "be") {
+		p.endian, rest = binary.BigEndian, strings.TrimSuffix(rest, "be")
+	}
+	switch rest {
+	case "1", "2", "4", "8":
+		p.size = int(rest[0] - '0')
+	default:
+		return prim{}
+	}
+	if kindFloat == p.kind && p.size < 4 {
+		return prim{}
+	}
+	return p
+}
+
+func isPrimitive(name string) bool {
+	return "str" == name || "strz" == name || primitive(name).kind != 0
+}
@@ -0,0 +1,76 @@
+package schema
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      string
+		wantError bool
+	}{
+		{name: "TestLoad_YAML", args: "meta:\n  endian: le\nseq:\n  - id: a\n    type: u2\n"},
+		{name: "TestLoad_JSON", args: `{"meta": {"endian": "be"}, "seq": [{"id": "a", "type": "u4"}]}`},
+		{name: "TestLoad_UnknownKey", args: "seq:\n  - id: a\n    type: u1\n    sise: 2\n", wantError: true},
+		{name: "TestLoad_UnknownType", args: "seq:\n  - id: a\n    type: header\n", wantError: true},
+		{name: "TestLoad_BadEndian", args: "meta:\n  endian: middle\n", wantError: true},
+		{name: "TestLoad_MissingId", args: "seq:\n  - type: u1\n", wantError: true},
+		{name: "TestLoad_DuplicateId", args: "seq:\n  - id: a\n    type: u1\n  - id: a\n    type: u1\n", wantError: true},
+		{name: "TestLoad_UnsizedStr", args: "seq:\n  - id: a\n    type: str\n", wantError: true},
+		{name: "TestLoad_SizedInt", args: "seq:\n  - id: a\n    type: u4\n    size: 2\n", wantError: true},
+		{name: "TestLoad_BadRepeat", args: "seq:\n  - id: a\n    type: u1\n    repeat: expr\n", wantError: true},
+		{name: "TestLoad_BadExpr", args: "seq:\n  - id: a\n    size: (1\n", wantError: true},
+		{name: "TestLoad_SwitchUnknown", args: "seq:\n  - id: a\n    type:\n      switch-on: 1\n      cases:\n        1: nope\n", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load([]byte(tt.args))
+			if (err != nil) != tt.wantError {
+				t.Errorf("Load() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestLoad_Model(t *testing.T) {
+	s, err := Load([]byte(`
+meta:
+  endian: be
+seq:
+  - id: magic
+    contents: [0x7f, "ELF"]
+  - id: body
+    type:
+      switch-on: magic.size
+      cases:
+        4: entry
+        _: u1
+types:
+  entry:
+    meta:
+      endian: le
+    seq:
+      - id: name
+        type: strz
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Root.Endian != binary.BigEndian || len(s.Root.Seq) != 2 {
+		t.Fatalf("Root = %+v", s.Root)
+	}
+	if got := s.Root.Seq[0].Contents; !reflect.DeepEqual(got, []byte{0x7f, 'E', 'L', 'F'}) {
+		t.Errorf("Contents = %x", got)
+	}
+	sw := s.Root.Seq[1].Switch
+	if nil == sw || len(sw.Cases) != 2 || sw.Cases[0].Type != "entry" || sw.Cases[1].Match != nil {
+		t.Errorf("Switch = %+v", sw)
+	}
+	entry := s.Root.lookupType("entry")
+	if nil == entry || entry.Endian != binary.LittleEndian || entry.Parent != s.Root || entry.Seq[0].Terminator != 0 {
+		t.Errorf("entry = %+v", entry)
+	}
+}
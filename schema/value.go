@@ -0,0 +1,108 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// Struct is a decoded user type. It maps field ids to values and keeps the order of the fields.
+//
+// Values are uint64 for unsigned integers, int64 for signed integers, float64, bool for b1 fields, string,
+// []byte for raw bytes, EnumValue for enums, *Struct for user types and []any for repeated fields.
+type Struct struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewStruct returns an empty Struct.
+func NewStruct() *Struct {
+	return &Struct{values: make(map[string]any)}
+}
+
+// Get returns the value of field key.
+func (s *Struct) Get(key string) (any, bool) {
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Set set the value of field key, appending key to the field order if it is new.
+func (s *Struct) Set(key string, v any) {
+	if _, ok := s.values[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.values[key] = v
+}
+
+// Keys returns the field ids in order.
+func (s *Struct) Keys() []string {
+	return s.keys
+}
+
+// Map returns the fields as a map, with nested structs converted too.
+func (s *Struct) Map() map[string]any {
+	m := make(map[string]any, len(s.keys))
+	for _, k := range s.keys {
+		m[k] = toMap(s.values[k])
+	}
+	return m
+}
+
+func toMap(v any) any {
+	switch v := v.(type) {
+	case *Struct:
+		return v.Map()
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = toMap(e)
+		}
+		return out
+	}
+	return v
+}
+
+// MarshalJSON encode the fields as a JSON object in field order. Raw bytes are encoded as base64 strings.
+func (s *Struct) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, k := range s.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(s.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// EnumValue is a decoded enum field. Name is empty when Value is not listed in the enum.
+type EnumValue struct {
+	Name  string
+	Value int64
+}
+
+func (v EnumValue) String() string {
+	if "" == v.Name {
+		return strconv.FormatInt(v.Value, 10)
+	}
+	return v.Name
+}
+
+// MarshalJSON encode the name of the value, or its number when it has no name.
+func (v EnumValue) MarshalJSON() ([]byte, error) {
+	if "" == v.Name {
+		return json.Marshal(v.Value)
+	}
+	return json.Marshal(v.Name)
+}
@@ -0,0 +1,427 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML parse the YAML subset used by format descriptions: block mappings and sequences, flow
+// sequences and mappings, plain and quoted scalars, literal and folded block scalars, and comments.
+// Mappings are returned as map[string]any, sequences as []any, and scalars as int64, float64, bool,
+// nil or string.
+func parseYAML(data []byte) (any, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	p := &yamlParser{lines: strings.Split(text, "\n")}
+	p.skipBlank()
+	if p.i < len(p.lines) && strings.HasPrefix(p.lines[p.i], "---") {
+		p.i++
+		p.skipBlank()
+	}
+	if p.i >= len(p.lines) {
+		return nil, nil
+	}
+	v, err := p.parseBlock(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if p.i < len(p.lines) && !strings.HasPrefix(p.lines[p.i], "...") {
+		return nil, p.errorf("unexpected content")
+	}
+	return v, nil
+}
+
+type yamlParser struct {
+	lines []string
+	i     int
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("schema: yaml line %d: %s", p.i+1, fmt.Sprintf(format, args...))
+}
+
+// skipBlank skip empty and comment only lines.
+func (p *yamlParser) skipBlank() {
+	for p.i < len(p.lines) {
+		s := strings.TrimSpace(p.lines[p.i])
+		if s != "" && !strings.HasPrefix(s, "#") {
+			return
+		}
+		p.i++
+	}
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// current returns the indentation and the content of the current line without its comment.
+func (p *yamlParser) current() (int, string) {
+	line := p.lines[p.i]
+	return indentOf(line), strings.TrimSpace(stripComment(line))
+}
+
+func isSeqItem(s string) bool {
+	return "-" == s || strings.HasPrefix(s, "- ")
+}
+
+func (p *yamlParser) parseBlock(minIndent int) (any, error) {
+	p.skipBlank()
+	if p.i >= len(p.lines) {
+		return nil, nil
+	}
+	indent, s := p.current()
+	if indent < minIndent {
+		return nil, nil
+	}
+	if strings.ContainsRune(p.lines[p.i][:indent], '\t') {
+		return nil, p.errorf("tabs are not allowed for indentation")
+	}
+	if isSeqItem(s) {
+		return p.parseSeq(indent)
+	}
+	if _, _, ok := splitKey(s); ok {
+		return p.parseMap(indent)
+	}
+	p.i++
+	return parseFlow(s)
+}
+
+func (p *yamlParser) parseSeq(indent int) (any, error) {
+	seq := []any{}
+	for {
+		p.skipBlank()
+		if p.i >= len(p.lines) {
+			return seq, nil
+		}
+		n, s := p.current()
+		if n != indent || !isSeqItem(s) {
+			if n > indent {
+				return nil, p.errorf("bad indentation")
+			}
+			return seq, nil
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(s, "-"), " ")
+		if "" == rest {
+			p.i++
+			v, err := p.parseBlock(indent + 1)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+		// reparse the item content as a block starting at its own column, past the dash and its spaces
+		start, col := p.i, indent+1+(len(s)-1-len(rest))
+		p.lines[p.i] = strings.Repeat(" ", col) + p.lines[p.i][col:]
+		v, err := p.parseBlock(col)
+		if err != nil {
+			return nil, err
+		}
+		if p.i == start {
+			return nil, p.errorf("bad sequence item")
+		}
+		seq = append(seq, v)
+	}
+}
+
+func (p *yamlParser) parseMap(indent int) (any, error) {
+	m := map[string]any{}
+	for {
+		p.skipBlank()
+		if p.i >= len(p.lines) {
+			return m, nil
+		}
+		n, s := p.current()
+		if n != indent {
+			if n > indent {
+				return nil, p.errorf("bad indentation")
+			}
+			return m, nil
+		}
+		key, rest, ok := splitKey(s)
+		if !ok {
+			return nil, p.errorf("expected a mapping key")
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		p.i++
+		var (
+			v   any
+			err error
+		)
+		switch {
+		case "" == rest:
+			p.skipBlank()
+			if p.i < len(p.lines) {
+				next, ns := p.current()
+				if next > indent || (next == indent && isSeqItem(ns)) {
+					v, err = p.parseBlock(next)
+				}
+			}
+		case strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">"):
+			v = p.parseBlockScalar(indent, rest)
+		default:
+			v, err = parseFlow(rest)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+}
+
+// parseBlockScalar read the lines of a literal (|) or folded (>) scalar.
+func (p *yamlParser) parseBlockScalar(indent int, header string) string {
+	var lines []string
+	blockIndent := -1
+	for p.i < len(p.lines) {
+		line := p.lines[p.i]
+		if "" == strings.TrimSpace(line) {
+			lines = append(lines, "")
+			p.i++
+			continue
+		}
+		n := indentOf(line)
+		if n <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = n
+		}
+		lines = append(lines, line[min(n, blockIndent):])
+		p.i++
+	}
+	for len(lines) > 0 && "" == lines[len(lines)-1] {
+		lines = lines[:len(lines)-1]
+	}
+	sep := "\n"
+	if strings.HasPrefix(header, ">") {
+		sep = " "
+	}
+	s := strings.Join(lines, sep)
+	if !strings.HasSuffix(header, "-") && s != "" {
+		s += "\n"
+	}
+	return s
+}
+
+// stripComment remove a # comment that is not inside quotes.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if '\\' == c && '"' == quote {
+				i++
+			}
+		case '"' == c || '\'' == c:
+			if 0 == i || ' ' == line[i-1] || '[' == line[i-1] || '{' == line[i-1] || ',' == line[i-1] || ':' == line[i-1] {
+				quote = c
+			}
+		case '#' == c && (0 == i || ' ' == line[i-1]):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitKey split "key: value" outside of quotes and brackets.
+func splitKey(s string) (string, string, bool) {
+	var quote byte
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if '\\' == c && '"' == quote {
+				i++
+			}
+		case ('"' == c || '\'' == c) && 0 == i:
+			quote = c
+		case '[' == c || '{' == c:
+			if 0 == i {
+				return "", "", false
+			}
+			depth++
+		case ']' == c || '}' == c:
+			depth--
+		case ':' == c && 0 == depth && (i+1 == len(s) || ' ' == s[i+1]):
+			key := strings.TrimSpace(s[:i])
+			if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') {
+				k, err := unquote(key)
+				if err != nil {
+					return "", "", false
+				}
+				key = k
+			}
+			return key, strings.TrimSpace(s[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+func unquote(s string) (string, error) {
+	if '\'' == s[0] {
+		if len(s) < 2 || s[len(s)-1] != '\'' {
+			return "", fmt.Errorf("schema: unterminated string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return strconv.Unquote(s)
+}
+
+// parseFlow parse a flow collection or a scalar.
+func parseFlow(s string) (any, error) {
+	f := &flowParser{s: s}
+	v, err := f.value()
+	if err != nil {
+		return nil, err
+	}
+	f.space()
+	if f.i != len(f.s) {
+		return nil, fmt.Errorf("schema: yaml: unexpected %q", f.s[f.i:])
+	}
+	return v, nil
+}
+
+type flowParser struct {
+	s     string
+	i     int
+	depth int
+}
+
+func (f *flowParser) space() {
+	for f.i < len(f.s) && ' ' == f.s[f.i] {
+		f.i++
+	}
+}
+
+func (f *flowParser) value() (any, error) {
+	f.space()
+	if f.i >= len(f.s) {
+		return nil, nil
+	}
+	switch f.s[f.i] {
+	case '[':
+		f.i++
+		f.depth++
+		seq := []any{}
+		for {
+			f.space()
+			if f.i < len(f.s) && ']' == f.s[f.i] {
+				f.i++
+				f.depth--
+				return seq, nil
+			}
+			v, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			if err := f.separator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		f.i++
+		f.depth++
+		m := map[string]any{}
+		for {
+			f.space()
+			if f.i < len(f.s) && '}' == f.s[f.i] {
+				f.i++
+				f.depth--
+				return m, nil
+			}
+			k, err := f.scalar(true)
+			if err != nil {
+				return nil, err
+			}
+			f.space()
+			if f.i >= len(f.s) || f.s[f.i] != ':' {
+				return nil, fmt.Errorf("schema: yaml: expected ':' in %q", f.s)
+			}
+			f.i++
+			v, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = v
+			if err := f.separator('}'); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return f.scalar(false)
+}
+
+func (f *flowParser) separator(end byte) error {
+	f.space()
+	if f.i < len(f.s) && ',' == f.s[f.i] {
+		f.i++
+		return nil
+	}
+	if f.i < len(f.s) && end == f.s[f.i] {
+		return nil
+	}
+	return fmt.Errorf("schema: yaml: expected ',' or %q in %q", end, f.s)
+}
+
+func (f *flowParser) scalar(key bool) (any, error) {
+	f.space()
+	start := f.i
+	if f.i < len(f.s) && ('"' == f.s[f.i] || '\'' == f.s[f.i]) {
+		quote := f.s[f.i]
+		for f.i++; f.i < len(f.s) && f.s[f.i] != quote; f.i++ {
+			if '\\' == f.s[f.i] && '"' == quote {
+				f.i++
+			}
+		}
+		if f.i >= len(f.s) {
+			return nil, fmt.Errorf("schema: yaml: unterminated string %s", f.s[start:])
+		}
+		f.i++
+		return unquote(f.s[start:f.i])
+	}
+	for f.i < len(f.s) {
+		c := f.s[f.i]
+		if f.depth > 0 && (',' == c || ']' == c || '}' == c) {
+			break
+		}
+		if key && ':' == c {
+			break
+		}
+		f.i++
+	}
+	return plainScalar(strings.TrimSpace(f.s[start:f.i])), nil
+}
+
+// plainScalar resolve the type of an unquoted scalar.
+func plainScalar(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if i, err := strconv.ParseInt(s, 0, 64); nil == err {
+		return i
+	}
+	if u, err := strconv.ParseUint(s, 0, 64); nil == err {
+		return int64(u)
+	}
+	if f, err := strconv.ParseFloat(s, 64); nil == err && strings.ContainsAny(s, ".eE") && !strings.HasPrefix(s, "0x") {
+		return f
+	}
+	return s
+}
@@ -0,0 +1,39 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseYAML(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      string
+		want      any
+		wantError bool
+	}{
+		{name: "Test_parseYAML_Map", args: "a: 1\nb: two\nc: 0x10\n", want: map[string]any{"a": int64(1), "b": "two", "c": int64(16)}},
+		{name: "Test_parseYAML_Nested", args: "meta:\n  endian: le\n  id: x # comment\n", want: map[string]any{"meta": map[string]any{"endian": "le", "id": "x"}}},
+		{name: "Test_parseYAML_Seq", args: "seq:\n  - id: a\n    type: u1\n  - id: b\n", want: map[string]any{"seq": []any{map[string]any{"id": "a", "type": "u1"}, map[string]any{"id": "b"}}}},
+		{name: "Test_parseYAML_SeqSameIndent", args: "seq:\n- 1\n- 2\n", want: map[string]any{"seq": []any{int64(1), int64(2)}}},
+		{name: "Test_parseYAML_Flow", args: "a: [1, 'x, y', {k: v}]\n", want: map[string]any{"a": []any{int64(1), "x, y", map[string]any{"k": "v"}}}},
+		{name: "Test_parseYAML_Scalars", args: "a: true\nb: ~\nc: 1.5\nd: \"q\\n\"\ne: a: b\n", want: map[string]any{"a": true, "b": nil, "c": 1.5, "d": "q\n", "e": "a: b"}},
+		{name: "Test_parseYAML_Literal", args: "doc: |\n  line 1\n  line 2\nnext: 1\n", want: map[string]any{"doc": "line 1\nline 2\n", "next": int64(1)}},
+		{name: "Test_parseYAML_Folded", args: "doc: >-\n  a\n  b\n", want: map[string]any{"doc": "a b"}},
+		{name: "Test_parseYAML_NestedSeq", args: "- - a\n  - b\n- c\n", want: []any{[]any{"a", "b"}, "c"}},
+		{name: "Test_parseYAML_NestedSeqEmpty", args: "- -\n", want: []any{[]any{nil}}},
+		{name: "Test_parseYAML_Duplicate", args: "a: 1\na: 2\n", wantError: true},
+		{name: "Test_parseYAML_BadIndent", args: "a:\n    b: 1\n  c: 2\n", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(tt.args))
+			if (err != nil) != tt.wantError {
+				t.Fatalf("parseYAML() error = %v, wantError %v", err, tt.wantError)
+			}
+			if !tt.wantError && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package bitstream
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+)
+
+// A sealed section is laid out as
+//
+//	nonce                 aead.NonceSize() bytes
+//	ciphertext length     same length prefix as WriteBytesWithLengthPrefix
+//	ciphertext || tag     aead.Seal output, aead.Overhead() bytes longer than the plaintext
+//
+// No additional data is authenticated.
+
+// BeginSealed start a sealed section. The bytes written until the matching EndSealed are encrypted with aead,
+// for example cipher.NewGCM, using a nonce read from nonceSource. A nil nonceSource means crypto/rand.Reader.
+func (p *BOStream) BeginSealed(aead cipher.AEAD, nonceSource io.Reader) *BOStream {
+	return p.catchError(func() {
+		if nil == nonceSource {
+			nonceSource = rand.Reader
+		}
+		buf := new(bytes.Buffer)
+		p.pushRegion(region{kind: regionSealed, buf: buf, aead: aead, nonce: nonceSource}, buf)
+	})
+}
+
+// EndSealed end the sealed section and write the nonce, the length prefix and the ciphertext.
+func (p *BOStream) EndSealed() *BOStream {
+	r, ok := p.popRegion(regionSealed)
+	if !ok {
+		return p
+	}
+	return p.catchError(func() {
+		nonce := make([]byte, r.aead.NonceSize())
+		if _, p.err = io.ReadFull(r.nonce, nonce); p.err != nil {
+			return
+		}
+		p.WriteBytes(nonce).WriteBytesWithLengthPrefix(r.aead.Seal(nil, nonce, r.buf.Bytes(), nil))
+	})
+}
+
+// OpenSealed read a section written by BOStream.EndSealed and decrypt it with aead. Returns a BIStream over
+// the plaintext and an error if exists. A tampered section is reported as ErrMACMismatch.
+func (b *BIStream) OpenSealed(aead cipher.AEAD) (*BIStream, error) {
+	nonce, err := b.ReadBytes(uint64(aead.NonceSize()))
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := b.ReadBytesWithLengthPrefix()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(ciphertext[:0], nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrMACMismatch
+	}
+	return NewBIStream(b.endian, bytes.NewReader(plaintext)), nil
+}
+
+// FetchSealed fetch a sealed section into value, see OpenSealed.
+func (b *BIStream) FetchSealed(value **BIStream, aead cipher.AEAD) *BIStream {
+	return b.catchError(func() {
+		*value, b.err = b.OpenSealed(aead)
+	})
+}
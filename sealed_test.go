@@ -0,0 +1,82 @@
+package bitstream
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"testing"
+)
+
+func newTestGCM(t *testing.T, key string) cipher.AEAD {
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aead
+}
+
+func TestBOStream_BeginSealed(t *testing.T) {
+	aead := newTestGCM(t, "0123456789abcdef")
+	nonce := bytes.Repeat([]byte{7}, aead.NonceSize())
+	buf := new(bytes.Buffer)
+	w := NewBOStream(binary.BigEndian, buf)
+	w.BeginSealed(aead, bytes.NewReader(nonce)).WriteString("secret").EndSealed()
+	if w.Error() != nil {
+		t.Fatalf("EndSealed() has error %v", w.Error())
+	}
+	want := append(append(append([]byte{}, nonce...), byte(7+aead.Overhead())), aead.Seal(nil, nonce, []byte("\x06secret"), nil)...)
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("EndSealed() = %v, want %v", buf.Bytes(), want)
+	}
+}
+
+func TestBIStream_OpenSealed(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		tamper    int
+		want      string
+		wantError error
+	}{
+		{name: "TestBIStream_OpenSealed", key: "0123456789abcdef", tamper: -1, want: "secret"},
+		{name: "TestBIStream_OpenSealed_TamperedNonce", key: "0123456789abcdef", tamper: 0, wantError: ErrMACMismatch},
+		{name: "TestBIStream_OpenSealed_TamperedCiphertext", key: "0123456789abcdef", tamper: 14, wantError: ErrMACMismatch},
+		{name: "TestBIStream_OpenSealed_TamperedTag", key: "0123456789abcdef", tamper: 30, wantError: ErrMACMismatch},
+		{name: "TestBIStream_OpenSealed_WrongKey", key: "fedcba9876543210", tamper: -1, wantError: ErrMACMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			w := NewBOStream(binary.BigEndian, buf)
+			w.BeginSealed(newTestGCM(t, "0123456789abcdef"), nil).WriteString("secret").WriteUint16(1).EndSealed().WriteUint16(2)
+			if w.Error() != nil {
+				t.Fatalf("EndSealed() has error %v", w.Error())
+			}
+			data := buf.Bytes()
+			if tt.tamper >= 0 {
+				data[tt.tamper] ^= 0x80
+			}
+			var plain *BIStream
+			r := NewBIStream(binary.BigEndian, bytes.NewReader(data))
+			r.FetchSealed(&plain, newTestGCM(t, tt.key))
+			if r.Error() != tt.wantError {
+				t.Fatalf("OpenSealed() error = %v, want %v", r.Error(), tt.wantError)
+			}
+			if tt.wantError != nil {
+				return
+			}
+			s, err := plain.ReadString()
+			if err != nil || s != tt.want {
+				t.Errorf("OpenSealed() = %v, want %v", s, tt.want)
+			}
+			if tail, _ := r.ReadUint16(); tail != 2 {
+				t.Errorf("ReadUint16() after OpenSealed() = %v, want 2", tail)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package bitstream
+
+import "encoding/binary"
+
+// Marshaler is implemented by types that write themselves to a BOStream.
+type Marshaler interface {
+	MarshalBitstream(p *BOStream) error
+}
+
+// Unmarshaler is implemented by types that read themselves from a BIStream, as written by their Marshaler.
+type Unmarshaler interface {
+	UnmarshalBitstream(b *BIStream) error
+}
+
+// counter is an io.Writer discarding the bytes and counting them.
+type counter struct {
+	n int64
+}
+
+func (c *counter) Write(buf []byte) (int, error) {
+	c.n += int64(len(buf))
+	return len(buf), nil
+}
+
+// NewSizer returns a BOStream discarding its output, to compute the encoded size of a message before writing
+// it. Len reports the bytes written so far. Regions are encoded for real, so compressed and sealed sizes are
+// exact.
+func NewSizer(endian binary.ByteOrder) *BOStream {
+	return NewBOStream(endian, &counter{})
+}
+
+// Len returns the number of bytes written by a stream created by NewSizer, without the bytes of regions not
+// yet ended. It returns 0 for other streams.
+func (p *BOStream) Len() int64 {
+	if c, ok := p.sink().(*counter); ok {
+		return c.n
+	}
+	return 0
+}
+
+// Size returns the number of bytes v writes, and an error if exists. The byte order is big endian, which
+// does not change the size of any write of BOStream.
+//
+// The types generated by schema.GenerateGo only have readers and do not implement Marshaler. Size a value
+// decoded by a schema with schema.Schema.Size instead.
+func Size(v Marshaler) (int64, error) {
+	p := NewSizer(binary.BigEndian)
+	if err := v.MarshalBitstream(p); err != nil {
+		return 0, err
+	}
+	if err := p.Error(); err != nil {
+		return 0, err
+	}
+	return p.Len(), nil
+}
@@ -0,0 +1,91 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestNewSizer(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(p *BOStream)
+	}{
+		{name: "TestNewSizer_Numbers", write: func(p *BOStream) { p.WriteBool(true).WriteUint16(1).WriteInt64(-1).WriteFloat32(1.5) }},
+		{name: "TestNewSizer_Prefix0", write: func(p *BOStream) { p.WriteBytesWithLengthPrefix(nil) }},
+		{name: "TestNewSizer_Prefix254", write: func(p *BOStream) { p.WriteBytesWithLengthPrefix(make([]byte, 0xfe)) }},
+		{name: "TestNewSizer_Prefix255", write: func(p *BOStream) { p.WriteBytesWithLengthPrefix(make([]byte, 0xff)) }},
+		{name: "TestNewSizer_Prefix65533", write: func(p *BOStream) { p.WriteBytesWithLengthPrefix(make([]byte, 0xfffd)) }},
+		{name: "TestNewSizer_Prefix65534", write: func(p *BOStream) { p.WriteString(string(make([]byte, 0xfffe))) }},
+		{name: "TestNewSizer_Varint", write: func(p *BOStream) { p.WriteUvarint(300).WriteVarint(-1 << 40) }},
+		{name: "TestNewSizer_Pack", write: func(p *BOStream) { p.Pack("<hI3s", 1, 2, "abc") }},
+		{
+			name: "TestNewSizer_Compressed",
+			write: func(p *BOStream) {
+				p.WriteUint8(1).BeginCompressed(Zlib).WriteBytes(bytes.Repeat([]byte("bitstream"), 100)).EndCompressed()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			p := NewBOStream(binary.BigEndian, buf)
+			tt.write(p)
+			sizer := NewSizer(binary.BigEndian)
+			tt.write(sizer)
+			if err := sizer.Error(); err != nil {
+				t.Fatalf("Error() = %v", err)
+			}
+			if sizer.Len() != int64(buf.Len()) {
+				t.Errorf("Len() = %v, want %v", sizer.Len(), buf.Len())
+			}
+		})
+	}
+}
+
+func TestBOStream_Len(t *testing.T) {
+	p := NewSizer(binary.LittleEndian).WriteUint32(1).BeginCompressed(Zlib).WriteUint32(2)
+	if p.Len() != 4 {
+		t.Errorf("Len() = %v, want 4 before the region ends", p.Len())
+	}
+	if n := NewBOStream(binary.LittleEndian, new(bytes.Buffer)).WriteUint32(1).Len(); n != 0 {
+		t.Errorf("Len() = %v, want 0 for a stream not created by NewSizer", n)
+	}
+}
+
+type header struct {
+	version uint16
+	name    string
+}
+
+func (h *header) MarshalBitstream(p *BOStream) error {
+	if "" == h.name {
+		return errors.New("missing name")
+	}
+	return p.WriteUint16(h.version).WriteString(h.name).Error()
+}
+
+func TestSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      Marshaler
+		want      int64
+		wantError bool
+	}{
+		{name: "TestSize", args: &header{version: 1, name: "go"}, want: 5},
+		{name: "TestSize_Long", args: &header{version: 1, name: string(make([]byte, 300))}, want: 2 + 3 + 300},
+		{name: "TestSize_Error", args: &header{}, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Size(tt.args)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Size() error = %v, wantError %v", err, tt.wantError)
+			}
+			if got != tt.want {
+				t.Errorf("Size() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
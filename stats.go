@@ -0,0 +1,124 @@
+package bitstream
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+)
+
+// BytesRead returns the number of bytes consumed from io.Reader, without the bytes pushed back by ScanFor or
+// AtEOF.
+func (b *BIStream) BytesRead() int64 {
+	return b.nread
+}
+
+// BytesWritten returns the number of bytes written to io.Writer. The bytes of a verified, compressed or sealed
+// region are counted as written when it ends.
+func (p *BOStream) BytesWritten() int64 {
+	return p.written
+}
+
+// Stats tallies the operations of the streams it is attached to. Like a Trace, only the outermost Read* or
+// Write* is counted. One Stats may be shared by several streams, in several goroutines, to aggregate them,
+// for instance per message type. A Stats is an expvar.Var.
+type Stats struct {
+	mu       sync.Mutex
+	snapshot StatsSnapshot
+}
+
+// StatsSnapshot is the state of a Stats at a point in time.
+type StatsSnapshot struct {
+	// Ops counts the operations by kind, as the Kind of an Op.
+	Ops map[string]int64 `json:"ops"`
+	// Errors counts the operations that failed.
+	Errors       int64 `json:"errors"`
+	BytesRead    int64 `json:"bytes_read"`
+	BytesWritten int64 `json:"bytes_written"`
+	// MaxLengthPrefix is the largest length prefix read by ReadBytesWithLengthPrefix.
+	MaxLengthPrefix uint64 `json:"max_length_prefix"`
+}
+
+// NewStats returns an empty Stats.
+func NewStats() *Stats {
+	return &Stats{snapshot: StatsSnapshot{Ops: map[string]int64{}}}
+}
+
+// WithStats attach s to the stream, nil detach it.
+func (b *BIStream) WithStats(s *Stats) *BIStream {
+	b.stats = s
+	return b.WithInterceptor()
+}
+
+// Stats returns the stats attached to the stream, or nil.
+func (b *BIStream) Stats() *Stats {
+	return b.stats
+}
+
+// WithStats attach s to the stream, nil detach it.
+func (p *BOStream) WithStats(s *Stats) *BOStream {
+	p.stats = s
+	return p.WithInterceptor()
+}
+
+// Stats returns the stats attached to the stream, or nil.
+func (p *BOStream) Stats() *Stats {
+	return p.stats
+}
+
+// Snapshot returns a copy of the current counts.
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := s.snapshot
+	snapshot.Ops = make(map[string]int64, len(s.snapshot.Ops))
+	for k, v := range s.snapshot.Ops {
+		snapshot.Ops[k] = v
+	}
+	return snapshot
+}
+
+// Reset clear the counts.
+func (s *Stats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot = StatsSnapshot{Ops: map[string]int64{}}
+}
+
+// String returns the snapshot as JSON, so that s can be given to expvar.Publish.
+func (s *Stats) String() string {
+	js, _ := json.Marshal(s.Snapshot())
+	return string(js)
+}
+
+// Publish publish s in expvar under name. Like expvar.Publish, it panics if name is already registered.
+func (s *Stats) Publish(name string) {
+	expvar.Publish(name, s)
+}
+
+// statsInterceptor counts the operations of a stream in a Stats.
+type statsInterceptor struct {
+	s *Stats
+}
+
+func (i statsInterceptor) Intercept(c *Call, next func() error) error {
+	err := next()
+	s := i.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot.Ops[c.Kind]++
+	if err != nil {
+		s.snapshot.Errors++
+	}
+	if c.Write {
+		s.snapshot.BytesWritten += c.Size
+	} else {
+		s.snapshot.BytesRead += c.Size
+	}
+	return err
+}
+
+func (s *Stats) allocate(n uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot.MaxLengthPrefix = max(s.snapshot.MaxLengthPrefix, n)
+}
@@ -0,0 +1,110 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBIStream_BytesRead(t *testing.T) {
+	b := NewBIStream(binary.BigEndian, bytes.NewReader([]byte{9, 'B', 'S', 0, 1, 2, 3}))
+	b.ScanFor([]byte("BS"))
+	if b.BytesRead() != 1 {
+		t.Errorf("BytesRead() = %v after ScanFor, want 1", b.BytesRead())
+	}
+	b.ReadUint16()
+	b.AtEOF()
+	if b.BytesRead() != 3 {
+		t.Errorf("BytesRead() = %v after AtEOF, want 3", b.BytesRead())
+	}
+	b.ReadAll()
+	if b.BytesRead() != 7 {
+		t.Errorf("BytesRead() = %v after ReadAll, want 7", b.BytesRead())
+	}
+	short := NewBIStream(binary.BigEndian, bytes.NewReader([]byte{1, 2, 3}))
+	if _, err := short.ReadUint32(); err != io.ErrUnexpectedEOF || short.BytesRead() != 3 {
+		t.Errorf("BytesRead() = %v, %v after a short read, want 3, %v", short.BytesRead(), err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestBOStream_BytesWritten(t *testing.T) {
+	buf := new(bytes.Buffer)
+	p := NewBOStream(binary.BigEndian, buf)
+	p.WriteUint32(1).BeginCompressed(Zlib).WriteString("bitstream")
+	if p.BytesWritten() != 4 {
+		t.Errorf("BytesWritten() = %v in a compressed region, want 4", p.BytesWritten())
+	}
+	p.EndCompressed()
+	if p.BytesWritten() != int64(buf.Len()) {
+		t.Errorf("BytesWritten() = %v, want %v", p.BytesWritten(), buf.Len())
+	}
+}
+
+func TestStats(t *testing.T) {
+	stats := NewStats()
+	buf := new(bytes.Buffer)
+	NewBOStream(binary.BigEndian, buf).WithStats(stats).
+		WriteUint16(1).WriteString("go").WriteBytesWithLengthPrefix(make([]byte, 300)).WriteUint16(2)
+	b := NewBIStream(binary.BigEndian, bytes.NewReader(buf.Bytes())).WithStats(stats)
+	var s string
+	b.FetchUShort(new(uint16)).FetchString(&s)
+	b.ReadBytesWithLengthPrefix()
+	b.ReadUint32()
+	want := StatsSnapshot{
+		Ops:             map[string]int64{"uint16": 3, "string": 2, "lpbytes": 2, "uint32": 1},
+		Errors:          1,
+		BytesRead:       2 + 3 + 303 + 2, // the uint32 fails after the last 2 bytes
+		BytesWritten:    int64(buf.Len()),
+		MaxLengthPrefix: 300,
+	}
+	if got := stats.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot() = %+v, want %+v", got, want)
+	}
+	stats.Reset()
+	if got := stats.Snapshot(); len(got.Ops) != 0 || got.BytesWritten != 0 {
+		t.Errorf("Snapshot() = %+v after Reset", got)
+	}
+}
+
+func TestStats_Concurrent(t *testing.T) {
+	stats := NewStats()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := NewSizer(binary.BigEndian).WithStats(stats)
+			for k := 0; k < 100; k++ {
+				p.WriteUint32(uint32(k))
+			}
+		}()
+	}
+	wg.Wait()
+	if got := stats.Snapshot(); got.Ops["uint32"] != 800 || got.BytesWritten != 3200 {
+		t.Errorf("Snapshot() = %+v, want 800 uint32 ops", got)
+	}
+}
+
+// published counts the runs of TestStats_Publish, expvar names can not be published twice.
+var published atomic.Int64
+
+func TestStats_Publish(t *testing.T) {
+	name := fmt.Sprintf("bitstream_test_stats_%d", published.Add(1))
+	stats := NewStats()
+	stats.Publish(name)
+	NewSizer(binary.BigEndian).WithStats(stats).WriteBool(true)
+	var got StatsSnapshot
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.Ops["bool"] != 1 || got.BytesWritten != 1 {
+		t.Errorf("expvar = %+v, want one bool", got)
+	}
+}
@@ -0,0 +1,306 @@
+package bitstream
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Op is a primitive operation recorded by a Trace. Kind is the type of the value, such as uint16, bytes,
+// lpbytes, string or pack, or section for a span opened by BeginSection.
+type Op struct {
+	Kind   string `json:"kind"`
+	Offset int    `json:"offset"`
+	Size   int    `json:"size"`
+	Value  any    `json:"value,omitempty"`
+	Label  string `json:"label,omitempty"`
+	Depth  int    `json:"depth"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Trace records the primitive operations of a stream with the bytes they read or wrote. When a Read* or Write*
+// is built on others, like WriteString, only the outermost one is recorded. Offsets count the bytes seen by
+// the stream since the trace was attached. The writes inside a verified, compressed or sealed region are not
+// recorded, the region is recorded as the bytes written when it ends.
+//
+// A Trace is attached to one stream at a time and is not safe for concurrent use.
+type Trace struct {
+	Ops      []Op
+	data     []byte
+	sections []int
+}
+
+// NewTrace returns an empty Trace.
+func NewTrace() *Trace {
+	return &Trace{}
+}
+
+// Bytes returns the bytes read or written while the trace was attached.
+func (t *Trace) Bytes() []byte {
+	return t.data
+}
+
+// WithTrace attach t to the stream, nil detach it.
+func (b *BIStream) WithTrace(t *Trace) *BIStream {
+	b.trace = t
+	return b.WithInterceptor()
+}
+
+// Trace returns the trace attached to the stream, or nil.
+func (b *BIStream) Trace() *Trace {
+	return b.trace
+}
+
+// Label name the next operation, for its Call and the Op recording it.
+func (b *BIStream) Label(name string) *BIStream {
+	b.label = name
+	return b
+}
+
+// BeginSection open a named section. Operations until the matching EndSection are nested in it.
+func (b *BIStream) BeginSection(name string) *BIStream {
+	if b.trace != nil {
+		b.trace.beginSection(name)
+	}
+	return b
+}
+
+// EndSection close the innermost section.
+func (b *BIStream) EndSection() *BIStream {
+	if b.trace != nil {
+		b.trace.endSection()
+	}
+	return b
+}
+
+// WithTrace attach t to the stream, nil detach it.
+func (p *BOStream) WithTrace(t *Trace) *BOStream {
+	p.trace = t
+	return p.WithInterceptor()
+}
+
+// Trace returns the trace attached to the stream, or nil.
+func (p *BOStream) Trace() *Trace {
+	return p.trace
+}
+
+// Label name the next operation, for its Call and the Op recording it.
+func (p *BOStream) Label(name string) *BOStream {
+	p.label = name
+	return p
+}
+
+// BeginSection open a named section. Operations until the matching EndSection are nested in it.
+func (p *BOStream) BeginSection(name string) *BOStream {
+	if t := p.tracing(); t != nil {
+		t.beginSection(name)
+	}
+	return p
+}
+
+// EndSection close the innermost section.
+func (p *BOStream) EndSection() *BOStream {
+	if t := p.tracing(); t != nil {
+		t.endSection()
+	}
+	return p
+}
+
+// tracing returns the trace of the stream, or nil when there is none or the bytes are held by a region.
+func (p *BOStream) tracing() *Trace {
+	if nil == p.trace || p.buffered() {
+		return nil
+	}
+	return p.trace
+}
+
+// traceInterceptor records the operations of a stream in a Trace, unless paused.
+type traceInterceptor struct {
+	t      *Trace
+	paused func() bool
+}
+
+func (i traceInterceptor) Intercept(c *Call, next func() error) error {
+	if i.paused != nil && i.paused() {
+		return next()
+	}
+	t := i.t
+	offset := len(t.data)
+	err := next()
+	v := c.Value
+	if buf, ok := v.([]byte); ok {
+		v = append([]byte{}, buf...)
+	}
+	op := Op{Kind: c.Kind, Offset: offset, Size: len(t.data) - offset, Value: v, Label: c.Label, Depth: len(t.sections)}
+	if err != nil {
+		op.Error = err.Error()
+	}
+	t.Ops = append(t.Ops, op)
+	return err
+}
+
+func (t *Trace) beginSection(name string) {
+	t.sections = append(t.sections, len(t.Ops))
+	t.Ops = append(t.Ops, Op{Kind: "section", Offset: len(t.data), Label: name, Depth: len(t.sections) - 1})
+}
+
+func (t *Trace) endSection() {
+	n := len(t.sections)
+	if 0 == n {
+		return
+	}
+	op := &t.Ops[t.sections[n-1]]
+	op.Size = len(t.data) - op.Offset
+	t.sections = t.sections[:n-1]
+}
+
+// Annotate set the kind and value of the last operation, for a decoder that reads a value as bytes and converts
+// it itself.
+func (t *Trace) Annotate(kind string, v any) {
+	if n := len(t.Ops); n > 0 && t.Ops[n-1].Kind != "section" {
+		t.Ops[n-1].Kind, t.Ops[n-1].Value = kind, v
+	}
+}
+
+// Nest turn the last operation into a section holding the operations of sub, the trace of a stream over the
+// bytes of that operation, so that a value decoded from a buffer shows up at its place in the outer stream.
+func (t *Trace) Nest(sub *Trace) {
+	n := len(t.Ops)
+	if 0 == n || "section" == t.Ops[n-1].Kind {
+		return
+	}
+	last := t.Ops[n-1]
+	t.Ops[n-1] = Op{Kind: "section", Offset: last.Offset, Size: last.Size, Label: last.Label, Depth: last.Depth, Error: last.Error}
+	for _, op := range sub.Ops {
+		op.Offset += last.Offset
+		op.Depth += last.Depth + 1
+		t.Ops = append(t.Ops, op)
+	}
+}
+
+func (t *Trace) read(buf []byte) {
+	t.data = append(t.data, buf...)
+}
+
+func (t *Trace) unread(n int) {
+	t.data = t.data[:max(0, len(t.data)-n)]
+}
+
+// WriteJSON write the operations and the bytes, in hex, as a JSON object.
+func (t *Trace) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	ops := t.Ops
+	if nil == ops {
+		ops = []Op{}
+	}
+	return enc.Encode(struct {
+		Data string `json:"data"`
+		Ops  []Op   `json:"ops"`
+	}{hex.EncodeToString(t.data), ops})
+}
+
+// WriteHexdump write an annotated hexdump, 16 bytes per line. Each operation starts a line annotated with its
+// label, kind and value, sections are headers that indent the operations in them, and bytes no operation
+// covers are annotated with -.
+func (t *Trace) WriteHexdump(w io.Writer) error {
+	var sb strings.Builder
+	at := 0
+	for _, op := range t.Ops {
+		if op.Offset > at && op.Offset <= len(t.data) {
+			hexLines(&sb, t.data, at, op.Offset, 0, "-")
+			at = op.Offset
+		}
+		indent := strings.Repeat("  ", op.Depth)
+		if "section" == op.Kind {
+			fmt.Fprintf(&sb, "%08x  %-47s  %s%s (%d bytes)\n", op.Offset, "", indent, op.Label, op.Size)
+			continue
+		}
+		end := min(op.Offset+op.Size, len(t.data))
+		hexLines(&sb, t.data, op.Offset, end, op.Depth, annotation(op))
+		at = max(at, end)
+	}
+	if at < len(t.data) {
+		hexLines(&sb, t.data, at, len(t.data), 0, "-")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteTree write the operations as an indented tree, one per line after its offset and size.
+func (t *Trace) WriteTree(w io.Writer) error {
+	var sb strings.Builder
+	for _, op := range t.Ops {
+		note := op.Label
+		if op.Kind != "section" {
+			note = annotation(op)
+		}
+		fmt.Fprintf(&sb, "%08x %6d  %s%s\n", op.Offset, op.Size, strings.Repeat("  ", op.Depth), note)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func hexLines(sb *strings.Builder, data []byte, from, to, depth int, note string) {
+	indent := strings.Repeat("  ", depth)
+	if from == to {
+		fmt.Fprintf(sb, "%08x  %-47s  %s%s\n", from, "", indent, note)
+		return
+	}
+	for i := from; i < to; i += 16 {
+		line := fmt.Sprintf("% x", data[i:min(i+16, to)])
+		if i == from {
+			fmt.Fprintf(sb, "%08x  %-47s  %s%s\n", i, line, indent, note)
+		} else {
+			fmt.Fprintf(sb, "%08x  %s\n", i, line)
+		}
+	}
+}
+
+// annotation returns the label, kind and value of op.
+func annotation(op Op) string {
+	var sb strings.Builder
+	if op.Label != "" {
+		sb.WriteString(op.Label)
+		sb.WriteString(": ")
+	}
+	sb.WriteString(op.Kind)
+	if op.Value != nil {
+		sb.WriteString(" ")
+		sb.WriteString(formatValue(op.Value, 32))
+	}
+	if op.Error != "" {
+		sb.WriteString(" error: ")
+		sb.WriteString(op.Error)
+	}
+	return sb.String()
+}
+
+// formatValue format a traced value in at most about limit characters. Bytes are printed in hex, strings
+// quoted.
+func formatValue(v any, limit int) string {
+	switch v := v.(type) {
+	case []byte:
+		if 2*len(v) > limit {
+			return hex.EncodeToString(v[:limit/2]) + "..."
+		}
+		return hex.EncodeToString(v)
+	case string:
+		if len(v) > limit {
+			cut := limit
+			for cut > 0 && !utf8.RuneStart(v[cut]) {
+				cut--
+			}
+			return fmt.Sprintf("%q...", v[:cut])
+		}
+		return fmt.Sprintf("%q", v)
+	}
+	s := fmt.Sprint(v)
+	if len(s) > limit {
+		return s[:limit] + "..."
+	}
+	return s
+}
@@ -0,0 +1,209 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBOStream_WithTrace(t *testing.T) {
+	trace := NewTrace()
+	var buf bytes.Buffer
+	p := NewBOStream(binary.BigEndian, &buf).WithTrace(trace)
+	p.Label("magic").WriteMagic([]byte("BS")).
+		BeginSection("header").
+		Label("version").WriteUint16(2).
+		WriteString("go").
+		EndSection().
+		WriteInt8(-1)
+	if err := p.Error(); err != nil {
+		t.Fatalf("Error() = %v", err)
+	}
+	want := []Op{
+		{Kind: "bytes", Offset: 0, Size: 2, Value: []byte("BS"), Label: "magic"},
+		{Kind: "section", Offset: 2, Size: 5, Label: "header"},
+		{Kind: "uint16", Offset: 2, Size: 2, Value: uint16(2), Label: "version", Depth: 1},
+		{Kind: "string", Offset: 4, Size: 3, Value: "go", Depth: 1},
+		{Kind: "int8", Offset: 7, Size: 1, Value: int8(-1)},
+	}
+	if !reflect.DeepEqual(trace.Ops, want) {
+		t.Errorf("Ops = %+v, want %+v", trace.Ops, want)
+	}
+	if !bytes.Equal(trace.Bytes(), buf.Bytes()) {
+		t.Errorf("Bytes() = %v, want %v", trace.Bytes(), buf.Bytes())
+	}
+}
+
+func TestBIStream_WithTrace(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []byte
+		read  func(b *BIStream)
+		want  []Op
+		error bool
+	}{
+		{
+			name: "TestBIStream_WithTrace_Fetch",
+			args: []byte{0, 2, 2, 'g', 'o', 1},
+			read: func(b *BIStream) {
+				var v uint16
+				var s string
+				var ok bool
+				b.Label("version").FetchUShort(&v).FetchString(&s).FetchBool(&ok)
+			},
+			want: []Op{
+				{Kind: "uint16", Offset: 0, Size: 2, Value: uint16(2), Label: "version"},
+				{Kind: "string", Offset: 2, Size: 3, Value: "go"},
+				{Kind: "bool", Offset: 5, Size: 1, Value: true},
+			},
+		},
+		{
+			name: "TestBIStream_WithTrace_ScanFor",
+			args: []byte{9, 9, 'B', 'S', 7},
+			read: func(b *BIStream) {
+				b.ScanFor([]byte("BS"))
+				b.ExpectBytes([]byte("BS")).ExpectUint8(7)
+			},
+			want: []Op{
+				{Kind: "bytes", Offset: 2, Size: 2, Value: []byte("BS")},
+				{Kind: "uint8", Offset: 4, Size: 1, Value: byte(7)},
+			},
+		},
+		{
+			name: "TestBIStream_WithTrace_Unpack",
+			args: []byte{0, 1, 'a'},
+			read: func(b *BIStream) {
+				var v uint16
+				var c byte
+				b.Unpack(">Hc", &v, &c)
+			},
+			want: []Op{{Kind: "pack", Offset: 0, Size: 3, Value: []any{uint16(1), byte('a')}}},
+		},
+		{
+			name: "TestBIStream_WithTrace_Error",
+			args: []byte{0},
+			read: func(b *BIStream) {
+				var v uint32
+				b.FetchUint32(&v)
+			},
+			want:  []Op{{Kind: "uint32", Offset: 0, Size: 1, Value: uint32(0), Error: io.ErrUnexpectedEOF.Error()}},
+			error: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace := NewTrace()
+			b := NewBIStream(binary.BigEndian, bytes.NewReader(tt.args)).WithTrace(trace)
+			tt.read(b)
+			if (b.Error() != nil) != tt.error {
+				t.Fatalf("Error() = %v, want error %v", b.Error(), tt.error)
+			}
+			if !reflect.DeepEqual(trace.Ops, tt.want) {
+				t.Errorf("Ops = %+v, want %+v", trace.Ops, tt.want)
+			}
+		})
+	}
+}
+
+func TestBOStream_WithTrace_Compressed(t *testing.T) {
+	trace := NewTrace()
+	var buf bytes.Buffer
+	p := NewBOStream(binary.BigEndian, &buf).WithTrace(trace)
+	p.WriteUint8(1).BeginCompressed(Zlib).WriteString("hidden").EndCompressed()
+	if err := p.Error(); err != nil {
+		t.Fatalf("Error() = %v", err)
+	}
+	if 2 != len(trace.Ops) || "lpbytes" != trace.Ops[1].Kind || trace.Ops[1].Offset != 1 {
+		t.Errorf("Ops = %+v, want uint8 then lpbytes at 1", trace.Ops)
+	}
+	if !bytes.Equal(trace.Bytes(), buf.Bytes()) {
+		t.Errorf("Bytes() = %v, want %v", trace.Bytes(), buf.Bytes())
+	}
+}
+
+func TestTrace_WriteHexdump(t *testing.T) {
+	trace := NewTrace()
+	b := NewBIStream(binary.BigEndian, bytes.NewReader(append([]byte{0xff, 'B', 'S', 0, 2}, bytes.Repeat([]byte{0xaa}, 18)...))).WithTrace(trace)
+	var data []byte
+	b.ScanFor([]byte("BS"))
+	b.Label("magic").ExpectBytes([]byte("BS")).
+		BeginSection("body").
+		FetchBytes(&data, 20).
+		EndSection()
+	if err := b.Error(); err != nil {
+		t.Fatalf("Error() = %v", err)
+	}
+	var out strings.Builder
+	if err := trace.WriteHexdump(&out); err != nil {
+		t.Fatalf("WriteHexdump() error = %v", err)
+	}
+	want := "" +
+		"00000000  ff                                               -\n" +
+		"00000001  42 53                                            magic: bytes 4253\n" +
+		"00000003                                                   body (20 bytes)\n" +
+		"00000003  00 02 aa aa aa aa aa aa aa aa aa aa aa aa aa aa    bytes 0002aaaaaaaaaaaaaaaaaaaaaaaaaaaa...\n" +
+		"00000013  aa aa aa aa\n"
+	if out.String() != want {
+		t.Errorf("WriteHexdump() = \n%s, want \n%s", out.String(), want)
+	}
+}
+
+func TestTrace_WriteJSON(t *testing.T) {
+	trace := NewTrace()
+	NewBOStream(binary.LittleEndian, io.Discard).WithTrace(trace).Label("n").WriteUint16(0x102)
+	var out bytes.Buffer
+	if err := trace.WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var got struct {
+		Data string           `json:"data"`
+		Ops  []map[string]any `json:"ops"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := []map[string]any{{"kind": "uint16", "offset": 0.0, "size": 2.0, "value": 258.0, "label": "n", "depth": 0.0}}
+	if got.Data != "0201" || !reflect.DeepEqual(got.Ops, want) {
+		t.Errorf("WriteJSON() = %s", out.String())
+	}
+}
+
+func TestTrace_Nest(t *testing.T) {
+	trace := NewTrace()
+	b := NewBIStream(binary.BigEndian, bytes.NewReader([]byte{7, 0, 1, 2})).WithTrace(trace)
+	b.ReadUint8()
+	buf, _ := b.Label("body").ReadBytes(3)
+	sub := NewTrace()
+	NewBIStream(binary.BigEndian, bytes.NewReader(buf)).WithTrace(sub).Label("n").ReadUint16()
+	trace.Nest(sub)
+	want := []Op{
+		{Kind: "uint8", Offset: 0, Size: 1, Value: byte(7)},
+		{Kind: "section", Offset: 1, Size: 3, Label: "body"},
+		{Kind: "uint16", Offset: 1, Size: 2, Value: uint16(1), Label: "n", Depth: 1},
+	}
+	if !reflect.DeepEqual(trace.Ops, want) {
+		t.Errorf("Ops = %+v, want %+v", trace.Ops, want)
+	}
+}
+
+func TestTrace_WriteTree(t *testing.T) {
+	trace := NewTrace()
+	NewBOStream(binary.BigEndian, io.Discard).WithTrace(trace).
+		BeginSection("header").Label("version").WriteUint16(2).EndSection().
+		WriteString("go")
+	var out strings.Builder
+	if err := trace.WriteTree(&out); err != nil {
+		t.Fatalf("WriteTree() error = %v", err)
+	}
+	want := "" +
+		"00000000      2  header\n" +
+		"00000000      2    version: uint16 2\n" +
+		"00000002      3  string \"go\"\n"
+	if out.String() != want {
+		t.Errorf("WriteTree() = \n%s, want \n%s", out.String(), want)
+	}
+}
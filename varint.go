@@ -0,0 +1,53 @@
+package bitstream
+
+import "encoding/binary"
+
+// ReadUvarint read an unsigned varint in the format of encoding/binary. Returns an uint64 and an error if exists
+func (b *BIStream) ReadUvarint() (uint64, error) {
+	return interceptRead(b, "uvarint", func() (uint64, error) {
+		n, err := binary.ReadUvarint(b)
+		if err != nil {
+			return 0, err
+		}
+		return n, nil
+	})
+}
+
+// FetchUvarint fetch an unsigned varint in io.Reader.
+func (b *BIStream) FetchUvarint(value *uint64) *BIStream {
+	return b.catchError(func() {
+		*value, b.err = b.ReadUvarint()
+	})
+}
+
+// ReadVarint read a zigzag encoded varint in the format of encoding/binary. Returns an int64 and an error if exists
+func (b *BIStream) ReadVarint() (int64, error) {
+	return interceptRead(b, "varint", func() (int64, error) {
+		n, err := binary.ReadVarint(b)
+		if err != nil {
+			return 0, err
+		}
+		return n, nil
+	})
+}
+
+// FetchVarint fetch a zigzag encoded varint in io.Reader.
+func (b *BIStream) FetchVarint(value *int64) *BIStream {
+	return b.catchError(func() {
+		*value, b.err = b.ReadVarint()
+	})
+}
+
+// WriteUvarint write an unsigned varint in the format of encoding/binary in io.Writer
+func (p *BOStream) WriteUvarint(n uint64) *BOStream {
+	return interceptWrite(p, "uvarint", n, func() *BOStream {
+		return p.WriteBytes(binary.AppendUvarint(nil, n))
+	})
+}
+
+// WriteVarint write a zigzag encoded varint in the format of encoding/binary in io.Writer
+func (p *BOStream) WriteVarint(n int64) *BOStream {
+	return interceptWrite(p, "varint", n, func() *BOStream {
+		return p.WriteBytes(binary.AppendVarint(nil, n))
+	})
+}
@@ -0,0 +1,69 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestBOStream_WriteUvarint(t *testing.T) {
+	tests := []struct {
+		name string
+		args uint64
+		want []byte
+	}{
+		{name: "TestBOStream_WriteUvarint_Zero", args: 0, want: []byte{0}},
+		{name: "TestBOStream_WriteUvarint_300", args: 300, want: []byte{0xac, 0x02}},
+		{name: "TestBOStream_WriteUvarint_Max", args: math.MaxUint64, want: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := NewBOStream(binary.BigEndian, buf).WriteUvarint(tt.args).Error(); err != nil {
+				t.Fatalf("WriteUvarint() error = %v", err)
+			}
+			if !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("WriteUvarint() = %x, want %x", buf.Bytes(), tt.want)
+			}
+			got, err := NewBIStream(binary.BigEndian, buf).ReadUvarint()
+			if err != nil || got != tt.args {
+				t.Errorf("ReadUvarint() = %v, %v, want %v", got, err, tt.args)
+			}
+		})
+	}
+}
+
+func TestBIStream_ReadVarint(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []byte
+		want      int64
+		wantError error
+	}{
+		{name: "TestBIStream_ReadVarint_Negative", args: []byte{0x03}, want: -2},
+		{name: "TestBIStream_ReadVarint_Positive", args: []byte{0xd8, 0x04}, want: 300},
+		{name: "TestBIStream_ReadVarint_Truncated", args: []byte{0xd8}, wantError: io.ErrUnexpectedEOF},
+		{name: "TestBIStream_ReadVarint_Empty", args: nil, wantError: io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v int64
+			p := NewBIStream(binary.BigEndian, bytes.NewReader(tt.args)).FetchVarint(&v)
+			if p.Error() != tt.wantError {
+				t.Fatalf("FetchVarint() error = %v, want %v", p.Error(), tt.wantError)
+			}
+			if v != tt.want {
+				t.Errorf("FetchVarint() = %v, want %v", v, tt.want)
+			}
+			if nil == tt.wantError {
+				buf := new(bytes.Buffer)
+				NewBOStream(binary.BigEndian, buf).WriteVarint(tt.want)
+				if !bytes.Equal(buf.Bytes(), tt.args) {
+					t.Errorf("WriteVarint() = %x, want %x", buf.Bytes(), tt.args)
+				}
+			}
+		})
+	}
+}